@@ -2,26 +2,73 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
 
 	pb "taskflow/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
-	addr = "localhost:8080"
+	defaultAddr = "localhost:8080"
 )
 
+var (
+	addr       = flag.String("addr", defaultAddr, "gRPC 服务地址")
+	useTLS     = flag.Bool("tls", false, "使用 TLS 连接（指定 -ca/-cert 时自动启用）")
+	caFile     = flag.String("ca", "", "校验服务端证书的 CA（PEM），为空使用系统根证书")
+	certFile   = flag.String("cert", "", "双向 TLS 客户端证书（PEM）")
+	keyFile    = flag.String("key", "", "双向 TLS 客户端私钥（PEM）")
+	serverName = flag.String("server-name", "", "覆盖校验服务端证书时使用的主机名")
+)
+
+// transportCredentials 根据命令行参数构造连接凭证
+func transportCredentials() (credentials.TransportCredentials, error) {
+	if !*useTLS && *caFile == "" && *certFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: *serverName}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 失败: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 文件中没有证书: %s", *caFile)
+		}
+	}
+	if *certFile != "" || *keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
 func main() {
+	flag.Parse()
 	fmt.Println("========== TaskFlow gRPC 客户端测试 ==========")
 	
+	creds, err := transportCredentials()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// 连接 gRPC 服务
-	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(creds), grpc.WithBlock())
 	if err != nil {
 		log.Fatalf("连接失败: %v", err)
 	}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,6 +48,37 @@ const (
 	DefaultDBMaxOpenConns = 25
 	DefaultDBMaxIdleConns = 5
 	DefaultDBConnMaxLifetime = 300 // seconds
+	DefaultDBJournalMode     = "WAL"
+	DefaultDBSynchronous     = "NORMAL"
+	DefaultDBBusyTimeout     = 5000 // milliseconds
+
+	// Retention defaults
+	DefaultRetentionInterval    = 3600 // seconds
+	DefaultRetentionRules       = "SUCCEEDED=7d,CANCELLED=7d,FAILED=30d,TIMEOUT=30d"
+	DefaultRetentionArchiveMode = "file"
+	DefaultRetentionArchiveDir  = "~/.taskflow/archive"
+	DefaultRetentionBatchSize   = 500
+
+	// Backup defaults
+	DefaultBackupDir      = "~/.taskflow/backups"
+	DefaultBackupInterval = 86400 // seconds
+	DefaultBackupKeep     = 7
+
+	// Outbox defaults
+	DefaultOutboxPollInterval   = 1000  // milliseconds
+	DefaultOutboxRetention      = 86400 // seconds
+	DefaultOutboxWebhookTimeout = 5     // seconds
+
+	// Auth defaults
+	DefaultAuthClockSkew        = 30 // seconds
+	DefaultAuthTokenExpireHours = 24
+	DefaultAuthRefreshExpireHours = 168
+	MinAuthSecretLength         = 32 // bytes
+	DefaultAuthDefaultRole      = "viewer"
+
+	// TLS defaults
+	DefaultTLSClientAuth     = "require"
+	DefaultTLSReloadInterval = 60 // seconds
 )
 
 // ServerConfig 服务配置
@@ -115,16 +148,99 @@ type DatabaseConfig struct {
 	TablePrefix     string `yaml:"table_prefix" env:"DB_TABLE_PREFIX"`        // 表前缀，默认空
 	PoolSize        int    `yaml:"pool_size" env:"DB_POOL_SIZE"`              // 连接池大小
 	MinIdleConns    int    `yaml:"min_idle_conns" env:"DB_MIN_IDLE_CONNS"`    // 最小空闲连接数
+	JournalMode     string `yaml:"journal_mode" env:"DB_JOURNAL_MODE"`        // SQLite 日志模式，默认WAL
+	Synchronous     string `yaml:"synchronous" env:"DB_SYNCHRONOUS"`          // SQLite 同步级别：OFF, NORMAL, FULL, EXTRA，默认NORMAL
+	BusyTimeout     int    `yaml:"busy_timeout" env:"DB_BUSY_TIMEOUT"`        // SQLite 锁等待超时（毫秒），默认5000
+}
+
+// RetentionConfig 任务保留与归档配置
+type RetentionConfig struct {
+	Enabled     bool   `yaml:"enabled" env:"RETENTION_ENABLED"`           // 是否启用后台清理
+	Interval    int    `yaml:"interval" env:"RETENTION_INTERVAL"`         // 清理间隔（秒），默认3600
+	Rules       string `yaml:"rules" env:"RETENTION_RULES"`               // 保留规则，如 SUCCEEDED=7d,FAILED=30d,etl:SUCCEEDED=1d
+	ArchiveMode string `yaml:"archive_mode" env:"RETENTION_ARCHIVE_MODE"` // 归档方式：file, table, none
+	ArchiveDir  string `yaml:"archive_dir" env:"RETENTION_ARCHIVE_DIR"`   // 归档文件目录（file 模式）
+	BatchSize   int    `yaml:"batch_size" env:"RETENTION_BATCH_SIZE"`     // 每批处理任务数，默认500
+}
+
+// BackupConfig 数据库在线备份配置
+type BackupConfig struct {
+	Enabled  bool   `yaml:"enabled" env:"BACKUP_ENABLED"`   // 是否启用定时备份
+	Dir      string `yaml:"dir" env:"BACKUP_DIR"`           // 备份目录
+	Interval int    `yaml:"interval" env:"BACKUP_INTERVAL"` // 备份间隔（秒），默认86400
+	Keep     int    `yaml:"keep" env:"BACKUP_KEEP"`         // 保留的备份数量，默认7
+}
+
+// OutboxConfig 任务变更 outbox 中继配置
+type OutboxConfig struct {
+	PollInterval   int    `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`     // 轮询间隔（毫秒），默认1000
+	Retention      int    `yaml:"retention" env:"OUTBOX_RETENTION"`             // 已发布记录保留时长（秒），默认86400
+	WebhookURL     string `yaml:"webhook_url" env:"OUTBOX_WEBHOOK_URL"`         // 外部 Webhook 地址，为空不推送
+	WebhookTimeout int    `yaml:"webhook_timeout" env:"OUTBOX_WEBHOOK_TIMEOUT"` // Webhook 请求超时（秒），默认5
+}
+
+// AuthConfig JWT 认证配置
+type AuthConfig struct {
+	Enabled          bool   `yaml:"enabled" env:"AUTH_ENABLED"`                       // 是否启用认证
+	Secret           string `yaml:"secret" env:"AUTH_SECRET"`                         // HS256 密钥（至少32字节）
+	JWKSFile         string `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`                   // RS256/ES256 公钥 JWKS 文件
+	Issuer           string `yaml:"issuer" env:"AUTH_ISSUER"`                         // 校验 iss，为空不校验
+	Audience         string `yaml:"audience" env:"AUTH_AUDIENCE"`                     // 校验 aud，为空不校验
+	ClockSkew        int    `yaml:"clock_skew" env:"AUTH_CLOCK_SKEW"`                 // exp/nbf 允许的时钟偏差（秒），默认30
+	TokenExpireHours int    `yaml:"token_expire_hours" env:"AUTH_TOKEN_EXPIRE_HOURS"` // 签发令牌有效期（小时），默认24
+
+	RefreshExpireHours int    `yaml:"refresh_expire_hours" env:"AUTH_REFRESH_EXPIRE_HOURS"` // 刷新令牌有效期（小时），默认168
+	UsersFile          string `yaml:"users_file" env:"AUTH_USERS_FILE"`                     // 本地用户文件（username:bcrypt_hash[:roles[:namespace]]）
+	SigningKeyFile     string `yaml:"signing_key_file" env:"AUTH_SIGNING_KEY_FILE"`         // 未配置 secret 时用于签发 RS256/ES256 令牌的 PEM 私钥
+	SigningKeyID       string `yaml:"signing_key_id" env:"AUTH_SIGNING_KEY_ID"`             // 签发令牌的 kid，需与 JWKS 中的公钥一致
+	DefaultRole        string `yaml:"default_role" env:"AUTH_DEFAULT_ROLE"`                 // 令牌或 API Key 未携带角色时的角色，默认 viewer
+}
+
+// TLSConfig gRPC 与 HTTP 监听的 TLS 配置，未配置证书时使用明文
+type TLSConfig struct {
+	CertFile       string `yaml:"cert_file" env:"TLS_CERT_FILE"`             // 服务端证书（PEM，可包含中间证书）
+	KeyFile        string `yaml:"key_file" env:"TLS_KEY_FILE"`               // 服务端私钥（PEM）
+	ClientCAFile   string `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`   // 客户端证书 CA，配置后启用双向 TLS
+	ClientAuth     string `yaml:"client_auth" env:"TLS_CLIENT_AUTH"`         // 双向 TLS 模式：require, optional，默认require
+	ReloadInterval int    `yaml:"reload_interval" env:"TLS_RELOAD_INTERVAL"` // 检查证书文件变化的间隔（秒），0表示不重新加载，默认60
+}
+
+// GRPCConfig gRPC 拦截器配置，认证、审计、授权与命名空间拦截器由各自的配置决定
+type GRPCConfig struct {
+	Recovery  bool    `yaml:"recovery" env:"GRPC_RECOVERY"`     // 将处理器 panic 转为 Internal 错误，默认true
+	Logging   bool    `yaml:"logging" env:"GRPC_LOGGING"`       // 记录每个调用的开始、结束、耗时与结果
+	RateLimit float64 `yaml:"rate_limit" env:"GRPC_RATE_LIMIT"` // 每个调用者每秒请求数，0表示不限流
+	RateBurst int     `yaml:"rate_burst" env:"GRPC_RATE_BURST"` // 允许的突发请求数，0表示 rate_limit 的2倍
+}
+
+// SecretsConfig 任务机密参数加密配置，未配置密钥文件时不接受机密参数
+type SecretsConfig struct {
+	KeyFile      string `yaml:"key_file" env:"SECRETS_KEY_FILE"`             // 密钥文件，每行 key_id:base64(16/24/32 字节 AES 密钥)
+	PrimaryKeyID string `yaml:"primary_key_id" env:"SECRETS_PRIMARY_KEY_ID"` // 加密新数据使用的密钥 ID，默认为文件中第一个密钥
+}
+
+// RetentionRule 保留规则：指定状态（可限定任务类型）的任务完成后保留 MaxAge
+type RetentionRule struct {
+	TaskType string        // 为空表示所有类型
+	Status   string        // 终态名称：SUCCEEDED, FAILED, CANCELLED, TIMEOUT
+	MaxAge   time.Duration // 保留时长
 }
 
 // Config 配置
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Features FeatureFlags   `yaml:"features"`
-	Worker   WorkerConfig   `yaml:"worker"`
-	Queue    QueueConfig    `yaml:"queue"`
-	Database DatabaseConfig `yaml:"database"`
-	mu       sync.RWMutex   // 用于配置热加载
+	Server    ServerConfig    `yaml:"server"`
+	Features  FeatureFlags    `yaml:"features"`
+	Worker    WorkerConfig    `yaml:"worker"`
+	Queue     QueueConfig     `yaml:"queue"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Retention RetentionConfig `yaml:"retention"`
+	Backup    BackupConfig    `yaml:"backup"`
+	Outbox    OutboxConfig    `yaml:"outbox"`
+	Auth      AuthConfig      `yaml:"auth"`
+	TLS       TLSConfig       `yaml:"tls"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Secrets   SecretsConfig   `yaml:"secrets"`
+	mu        sync.RWMutex    // 用于配置热加载
 }
 
 // LoadConfig 加载配置（支持环境变量覆盖）
@@ -254,6 +370,61 @@ func LoadConfig() *Config {
 			TablePrefix:      getEnv("DB_TABLE_PREFIX", ""),
 			PoolSize:         getEnvInt("DB_POOL_SIZE", DefaultDBMaxOpenConns),
 			MinIdleConns:     getEnvInt("DB_MIN_IDLE_CONNS", DefaultDBMaxIdleConns),
+			JournalMode:      strings.ToUpper(getEnv("DB_JOURNAL_MODE", DefaultDBJournalMode)),
+			Synchronous:      strings.ToUpper(getEnv("DB_SYNCHRONOUS", DefaultDBSynchronous)),
+			BusyTimeout:      getEnvInt("DB_BUSY_TIMEOUT", DefaultDBBusyTimeout),
+		},
+		Retention: RetentionConfig{
+			Enabled:     getEnvBool("RETENTION_ENABLED"),
+			Interval:    getEnvInt("RETENTION_INTERVAL", DefaultRetentionInterval),
+			Rules:       getEnv("RETENTION_RULES", DefaultRetentionRules),
+			ArchiveMode: getEnv("RETENTION_ARCHIVE_MODE", DefaultRetentionArchiveMode),
+			ArchiveDir:  getEnv("RETENTION_ARCHIVE_DIR", DefaultRetentionArchiveDir),
+			BatchSize:   getEnvInt("RETENTION_BATCH_SIZE", DefaultRetentionBatchSize),
+		},
+		Backup: BackupConfig{
+			Enabled:  getEnvBool("BACKUP_ENABLED"),
+			Dir:      getEnv("BACKUP_DIR", DefaultBackupDir),
+			Interval: getEnvInt("BACKUP_INTERVAL", DefaultBackupInterval),
+			Keep:     getEnvInt("BACKUP_KEEP", DefaultBackupKeep),
+		},
+		Outbox: OutboxConfig{
+			PollInterval:   getEnvInt("OUTBOX_POLL_INTERVAL", DefaultOutboxPollInterval),
+			Retention:      getEnvInt("OUTBOX_RETENTION", DefaultOutboxRetention),
+			WebhookURL:     getEnv("OUTBOX_WEBHOOK_URL", ""),
+			WebhookTimeout: getEnvInt("OUTBOX_WEBHOOK_TIMEOUT", DefaultOutboxWebhookTimeout),
+		},
+		Auth: AuthConfig{
+			Enabled:          getEnvBool("AUTH_ENABLED"),
+			Secret:           getEnv("AUTH_SECRET", ""),
+			JWKSFile:         getEnv("AUTH_JWKS_FILE", ""),
+			Issuer:           getEnv("AUTH_ISSUER", ""),
+			Audience:         getEnv("AUTH_AUDIENCE", ""),
+			ClockSkew:        getEnvInt("AUTH_CLOCK_SKEW", DefaultAuthClockSkew),
+			TokenExpireHours: getEnvInt("AUTH_TOKEN_EXPIRE_HOURS", DefaultAuthTokenExpireHours),
+
+			RefreshExpireHours: getEnvInt("AUTH_REFRESH_EXPIRE_HOURS", DefaultAuthRefreshExpireHours),
+			UsersFile:          getEnv("AUTH_USERS_FILE", ""),
+			SigningKeyFile:     getEnv("AUTH_SIGNING_KEY_FILE", ""),
+			SigningKeyID:       getEnv("AUTH_SIGNING_KEY_ID", ""),
+			DefaultRole:        getEnv("AUTH_DEFAULT_ROLE", DefaultAuthDefaultRole),
+		},
+		TLS: TLSConfig{
+			CertFile:       getEnv("TLS_CERT_FILE", ""),
+			KeyFile:        getEnv("TLS_KEY_FILE", ""),
+			ClientCAFile:   getEnv("TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     getEnv("TLS_CLIENT_AUTH", DefaultTLSClientAuth),
+			ReloadInterval: getEnvInt("TLS_RELOAD_INTERVAL", DefaultTLSReloadInterval),
+		},
+		GRPC: GRPCConfig{
+			Recovery:  getEnvBoolDefault("GRPC_RECOVERY", true),
+			Logging:   getEnvBool("GRPC_LOGGING"),
+			RateLimit: getEnvFloat("GRPC_RATE_LIMIT", 0),
+			RateBurst: getEnvInt("GRPC_RATE_BURST", 0),
+		},
+		Secrets: SecretsConfig{
+			KeyFile:      getEnv("SECRETS_KEY_FILE", ""),
+			PrimaryKeyID: getEnv("SECRETS_PRIMARY_KEY_ID", ""),
 		},
 	}
 	return cfg
@@ -378,6 +549,45 @@ func (c *Config) Validate() error {
 	if c.Database.MaxRetries < 0 {
 		errs = append(errs, fmt.Sprintf("DB_MAX_RETRIES must be non-negative, got %d", c.Database.MaxRetries))
 	}
+	errs = append(errs, c.Database.validateSQLite()...)
+
+	// 验证Retention配置
+	if err := c.Retention.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// 验证Backup配置
+	if err := c.Backup.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// 验证Outbox配置
+	if err := c.Outbox.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// 验证Auth配置
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// 验证TLS配置
+	if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// 验证gRPC拦截器配置
+	if c.GRPC.RateLimit < 0 {
+		errs = append(errs, fmt.Sprintf("GRPC_RATE_LIMIT must be non-negative, got %v", c.GRPC.RateLimit))
+	}
+	if c.GRPC.RateBurst < 0 {
+		errs = append(errs, fmt.Sprintf("GRPC_RATE_BURST must be non-negative, got %d", c.GRPC.RateBurst))
+	}
+
+	// 验证Secrets配置
+	if c.Secrets.PrimaryKeyID != "" && c.Secrets.KeyFile == "" {
+		errs = append(errs, "SECRETS_PRIMARY_KEY_ID requires SECRETS_KEY_FILE")
+	}
 
 	if len(errs) > 0 {
 		return fmt.Errorf("configuration validation failed: %s", strings.Join(errs, "; "))
@@ -446,6 +656,23 @@ func getEnvBool(key string) bool {
 	}
 }
 
+func getEnvBoolDefault(key string, defaultValue bool) bool {
+	if os.Getenv(key) == "" {
+		return defaultValue
+	}
+	return getEnvBool(key)
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	value := os.Getenv(key)
 	if value != "" {
@@ -704,12 +931,254 @@ func (d *DatabaseConfig) Validate() error {
 		errs = append(errs, fmt.Sprintf("DB_MIN_IDLE_CONNS (%d) cannot exceed DB_POOL_SIZE (%d)", d.MinIdleConns, d.PoolSize))
 	}
 
+	errs = append(errs, d.validateSQLite()...)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateSQLite 验证SQLite连接参数
+func (d *DatabaseConfig) validateSQLite() []string {
+	var errs []string
+
+	validJournalModes := map[string]bool{"WAL": true, "DELETE": true, "TRUNCATE": true, "PERSIST": true, "MEMORY": true, "OFF": true}
+	if !validJournalModes[d.JournalMode] {
+		errs = append(errs, fmt.Sprintf("DB_JOURNAL_MODE must be one of [WAL, DELETE, TRUNCATE, PERSIST, MEMORY, OFF], got %s", d.JournalMode))
+	}
+
+	validSynchronous := map[string]bool{"OFF": true, "NORMAL": true, "FULL": true, "EXTRA": true}
+	if !validSynchronous[d.Synchronous] {
+		errs = append(errs, fmt.Sprintf("DB_SYNCHRONOUS must be one of [OFF, NORMAL, FULL, EXTRA], got %s", d.Synchronous))
+	}
+
+	if d.BusyTimeout < 0 {
+		errs = append(errs, fmt.Sprintf("DB_BUSY_TIMEOUT must be non-negative, got %d", d.BusyTimeout))
+	}
+
+	return errs
+}
+
+// Validate 验证Retention配置
+func (r *RetentionConfig) Validate() error {
+	var errs []string
+
+	if r.Interval <= 0 {
+		errs = append(errs, fmt.Sprintf("RETENTION_INTERVAL must be greater than 0, got %d", r.Interval))
+	}
+	if r.BatchSize <= 0 || r.BatchSize > 10000 {
+		errs = append(errs, fmt.Sprintf("RETENTION_BATCH_SIZE must be between 1 and 10000, got %d", r.BatchSize))
+	}
+
+	validArchiveModes := map[string]bool{"file": true, "table": true, "none": true}
+	if !validArchiveModes[r.ArchiveMode] {
+		errs = append(errs, fmt.Sprintf("RETENTION_ARCHIVE_MODE must be one of [file, table, none], got %s", r.ArchiveMode))
+	}
+	if r.ArchiveMode == "file" && r.ArchiveDir == "" {
+		errs = append(errs, "RETENTION_ARCHIVE_DIR cannot be empty when archive mode is file")
+	}
+
+	if _, err := ParseRetentionRules(r.Rules); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Validate 验证Backup配置
+func (b *BackupConfig) Validate() error {
+	var errs []string
+
+	if b.Dir == "" {
+		errs = append(errs, "BACKUP_DIR cannot be empty")
+	}
+	if b.Interval <= 0 {
+		errs = append(errs, fmt.Sprintf("BACKUP_INTERVAL must be greater than 0, got %d", b.Interval))
+	}
+	if b.Keep <= 0 {
+		errs = append(errs, fmt.Sprintf("BACKUP_KEEP must be greater than 0, got %d", b.Keep))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Validate 验证Outbox配置
+func (o *OutboxConfig) Validate() error {
+	var errs []string
+
+	if o.PollInterval <= 0 {
+		errs = append(errs, fmt.Sprintf("OUTBOX_POLL_INTERVAL must be greater than 0, got %d", o.PollInterval))
+	}
+	if o.Retention <= 0 {
+		errs = append(errs, fmt.Sprintf("OUTBOX_RETENTION must be greater than 0, got %d", o.Retention))
+	}
+	if o.WebhookURL != "" && !strings.HasPrefix(o.WebhookURL, "http://") && !strings.HasPrefix(o.WebhookURL, "https://") {
+		errs = append(errs, fmt.Sprintf("OUTBOX_WEBHOOK_URL must be an http(s) URL, got %s", o.WebhookURL))
+	}
+	if o.WebhookTimeout <= 0 {
+		errs = append(errs, fmt.Sprintf("OUTBOX_WEBHOOK_TIMEOUT must be greater than 0, got %d", o.WebhookTimeout))
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 	return nil
 }
 
+// Validate 验证Auth配置
+// 启用认证时必须配置 HS256 密钥或 JWKS 文件，不再提供内置默认密钥
+func (a *AuthConfig) Validate() error {
+	var errs []string
+
+	if a.Enabled && a.Secret == "" && a.JWKSFile == "" {
+		errs = append(errs, "AUTH_SECRET or AUTH_JWKS_FILE is required when AUTH_ENABLED is set")
+	}
+	if a.Secret != "" && len(a.Secret) < MinAuthSecretLength {
+		errs = append(errs, fmt.Sprintf("AUTH_SECRET must be at least %d bytes, got %d", MinAuthSecretLength, len(a.Secret)))
+	}
+	if a.ClockSkew < 0 || a.ClockSkew > 300 {
+		errs = append(errs, fmt.Sprintf("AUTH_CLOCK_SKEW must be between 0 and 300, got %d", a.ClockSkew))
+	}
+	if a.TokenExpireHours <= 0 {
+		errs = append(errs, fmt.Sprintf("AUTH_TOKEN_EXPIRE_HOURS must be greater than 0, got %d", a.TokenExpireHours))
+	}
+	if a.RefreshExpireHours < a.TokenExpireHours {
+		errs = append(errs, fmt.Sprintf("AUTH_REFRESH_EXPIRE_HOURS must be at least AUTH_TOKEN_EXPIRE_HOURS, got %d", a.RefreshExpireHours))
+	}
+	// 用私钥签发的令牌需要通过 JWKS 中的公钥校验
+	if a.Secret == "" && a.SigningKeyFile != "" && a.JWKSFile == "" {
+		errs = append(errs, "AUTH_JWKS_FILE is required to verify tokens signed with AUTH_SIGNING_KEY_FILE")
+	}
+	switch a.DefaultRole {
+	case "", "viewer", "submitter", "operator", "admin":
+	default:
+		errs = append(errs, fmt.Sprintf("AUTH_DEFAULT_ROLE must be one of viewer, submitter, operator, admin, got %q", a.DefaultRole))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// GetAuthClockSkew 获取令牌时间校验允许的时钟偏差
+func (c *Config) GetAuthClockSkew() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Auth.ClockSkew) * time.Second
+}
+
+// GetAuthDefaultRole 获取未携带角色的调用者使用的默认角色
+func (c *Config) GetAuthDefaultRole() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Auth.DefaultRole == "" {
+		return DefaultAuthDefaultRole
+	}
+	return c.Auth.DefaultRole
+}
+
+// GetOutboxPollInterval 获取 outbox 轮询间隔
+func (c *Config) GetOutboxPollInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Outbox.PollInterval) * time.Millisecond
+}
+
+// GetOutboxRetention 获取已发布 outbox 记录的保留时长
+func (c *Config) GetOutboxRetention() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Outbox.Retention) * time.Second
+}
+
+// GetBackupInterval 获取备份间隔
+func (c *Config) GetBackupInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Backup.Interval) * time.Second
+}
+
+// GetRetentionRules 获取解析后的保留规则
+func (c *Config) GetRetentionRules() ([]RetentionRule, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return ParseRetentionRules(c.Retention.Rules)
+}
+
+// GetRetentionInterval 获取清理间隔
+func (c *Config) GetRetentionInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Retention.Interval) * time.Second
+}
+
+// ParseRetentionRules 解析保留规则
+// 格式：[task_type:]STATUS=duration，逗号分隔；duration 支持 Go 时长格式及天（7d）
+func ParseRetentionRules(s string) ([]RetentionRule, error) {
+	validStatuses := map[string]bool{"SUCCEEDED": true, "FAILED": true, "CANCELLED": true, "TIMEOUT": true}
+
+	var rules []RetentionRule
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		target, age, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("RETENTION_RULES: invalid rule %q, expected [type:]STATUS=duration", item)
+		}
+
+		var rule RetentionRule
+		status := target
+		if taskType, st, ok := strings.Cut(target, ":"); ok {
+			rule.TaskType = strings.TrimSpace(taskType)
+			status = st
+		}
+		rule.Status = strings.ToUpper(strings.TrimSpace(status))
+		if !validStatuses[rule.Status] {
+			return nil, fmt.Errorf("RETENTION_RULES: status in %q must be one of [SUCCEEDED, FAILED, CANCELLED, TIMEOUT]", item)
+		}
+
+		maxAge, err := parseDays(strings.TrimSpace(age))
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("RETENTION_RULES: invalid duration in %q", item)
+		}
+		rule.MaxAge = maxAge
+
+		key := rule.TaskType + ":" + rule.Status
+		if seen[key] {
+			return nil, fmt.Errorf("RETENTION_RULES: duplicate rule for %q", target)
+		}
+		seen[key] = true
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parseDays 解析时长，在 time.ParseDuration 基础上支持天（7d）
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
 // GetWorkerTimeout 获取Worker超时时间
 func (c *Config) GetWorkerTimeout() time.Duration {
 	c.mu.RLock()
@@ -738,6 +1207,13 @@ func (c *Config) GetQueueTTL() time.Duration {
 	return time.Duration(c.Queue.TTL) * time.Millisecond
 }
 
+// GetDBBusyTimeout 获取SQLite锁等待超时
+func (c *Config) GetDBBusyTimeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.Database.BusyTimeout) * time.Millisecond
+}
+
 // GetDBConnMaxLifetime 获取数据库连接最大生命周期
 func (c *Config) GetDBConnMaxLifetime() time.Duration {
 	c.mu.RLock()
@@ -759,6 +1235,25 @@ func (c *Config) GetDBRetryDelay() time.Duration {
 	return time.Duration(c.Database.RetryDelay) * time.Millisecond
 }
 
+// GetDBPath 获取数据库文件路径（已展开 ~）
+func (c *Config) GetDBPath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return ExpandHome(c.Server.DBPath)
+}
+
+// ExpandHome 展开路径中的用户主目录（~）
+func ExpandHome(p string) string {
+	if !strings.HasPrefix(p, "~") {
+		return p
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return path.Join(homeDir, strings.TrimPrefix(p, "~/"))
+}
+
 // GetDSN 获取数据库连接字符串
 func (c *Config) GetDSN() string {
 	c.mu.RLock()
@@ -773,3 +1268,56 @@ func (c *Config) GetDSN() string {
 		c.Database.SSLMode,
 	)
 }
+
+// Validate 验证TLS配置
+// 证书与私钥必须同时配置，双向 TLS 需要先启用服务端 TLS
+func (t *TLSConfig) Validate() error {
+	var errs []string
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		errs = append(errs, "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	switch t.ClientAuth {
+	case "", "require", "optional":
+	default:
+		errs = append(errs, fmt.Sprintf("TLS_CLIENT_AUTH must be one of require, optional, got %q", t.ClientAuth))
+	}
+	if t.ReloadInterval < 0 {
+		errs = append(errs, fmt.Sprintf("TLS_RELOAD_INTERVAL must be non-negative, got %d", t.ReloadInterval))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// TLSEnabled 是否为 gRPC 与 HTTP 监听启用 TLS
+func (c *Config) TLSEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.TLS.CertFile != ""
+}
+
+// GetGRPCRateBurst 获取 gRPC 限流的突发请求数，未配置时为每秒请求数的 2 倍（至少为 1）
+func (c *Config) GetGRPCRateBurst() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.GRPC.RateBurst > 0 {
+		return c.GRPC.RateBurst
+	}
+	if burst := int(math.Ceil(c.GRPC.RateLimit * 2)); burst > 1 {
+		return burst
+	}
+	return 1
+}
+
+// GetTLSReloadInterval 获取证书文件检查间隔，0 表示不重新加载
+func (c *Config) GetTLSReloadInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Duration(c.TLS.ReloadInterval) * time.Second
+}
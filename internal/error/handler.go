@@ -94,6 +94,9 @@ func (e *TaskError) ToGRPCStatus() *status.Status {
 		return status.New(codes.NotFound, e.Message)
 	case ErrCodeAlreadyExists:
 		return status.New(codes.AlreadyExists, e.Message)
+	case ErrCodeInvalidState, ErrCodeTaskAlreadyRunning, ErrCodeTaskTerminated, ErrCodeTaskCancelled,
+		ErrCodeTaskDependency, ErrCodeTaskRetryExhausted:
+		return status.New(codes.FailedPrecondition, e.Message)
 	case ErrCodeTimeout, ErrCodeTaskTimeout:
 		return status.New(codes.DeadlineExceeded, e.Message)
 	case ErrCodeRateLimit:
@@ -131,6 +134,9 @@ func FromGRPCStatus(s *status.Status) *TaskError {
 	case codes.AlreadyExists:
 		code = ErrCodeAlreadyExists
 		httpStatus = http.StatusConflict
+	case codes.FailedPrecondition:
+		code = ErrCodeInvalidState
+		httpStatus = http.StatusBadRequest
 	case codes.DeadlineExceeded:
 		code = ErrCodeTimeout
 		httpStatus = http.StatusGatewayTimeout
@@ -0,0 +1,151 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"taskflow/internal/model"
+)
+
+// AuditRecorder persists audit events. Implementations handle their own errors:
+// a failure to record must not change the outcome of a call that already ran.
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, event *model.AuditEvent)
+}
+
+// ReadOnlyMethods methods that don't modify state and are not audited.
+// Every other method, including ones added later, is audited by default.
+var ReadOnlyMethods = map[string]bool{
+	"/taskflow.TaskService/GetTask":             true,
+	"/taskflow.TaskService/ListTasks":           true,
+	"/taskflow.TaskService/ListTaskEvents":      true,
+	"/taskflow.TaskService/WaitTask":            true,
+	"/taskflow.TaskService/WatchTask":           true,
+	"/taskflow.TaskService/GetBulkOperation":    true,
+	"/taskflow.APIKeyService/GetAPIKey":         true,
+	"/taskflow.APIKeyService/ListAPIKeys":       true,
+	"/taskflow.NamespaceService/GetNamespace":   true,
+	"/taskflow.NamespaceService/ListNamespaces": true,
+	"/taskflow.AuditService/ListAuditEvents":    true,
+}
+
+// audited reports whether calls to method are recorded. Public methods carry no
+// caller identity and are not recorded.
+func audited(method string) bool {
+	return !ReadOnlyMethods[method] && !PublicMethods[method]
+}
+
+// UnaryAuditInterceptor records every mutating call with its result code.
+// It runs after the auth interceptor, so the caller is known, and before authz,
+// so denied attempts are recorded as well.
+func UnaryAuditInterceptor(recorder AuditRecorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		digest := sha256.New()
+		writeMessage(digest, req)
+
+		resp, err := handler(ctx, req)
+		recorder.RecordAudit(ctx, NewAuditEvent(ctx, model.AuditTransportGRPC, info.FullMethod,
+			auditResource(req, resp), hex.EncodeToString(digest.Sum(nil)), status.Code(err)))
+		return resp, err
+	}
+}
+
+// StreamAuditInterceptor records mutating streaming calls once the stream ends.
+// The digest covers every message the client sent.
+func StreamAuditInterceptor(recorder AuditRecorder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !audited(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		stream := &digestStream{ServerStream: ss, digest: sha256.New()}
+		err := handler(srv, stream)
+		recorder.RecordAudit(ss.Context(), NewAuditEvent(ss.Context(), model.AuditTransportGRPC, info.FullMethod,
+			"", hex.EncodeToString(stream.digest.Sum(nil)), status.Code(err)))
+		return err
+	}
+}
+
+// digestStream hashes every received message
+type digestStream struct {
+	grpc.ServerStream
+	digest hash.Hash
+}
+
+func (s *digestStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	writeMessage(s.digest, m)
+	return nil
+}
+
+// writeMessage writes the deterministic encoding of a protobuf message
+func writeMessage(h hash.Hash, m interface{}) {
+	if msg, ok := m.(proto.Message); ok {
+		data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		h.Write(data)
+	}
+}
+
+// auditResource picks the target resource: the id in the request, else the id of
+// the created resource in the response, else a name
+func auditResource(req, resp interface{}) string {
+	for _, m := range []interface{}{req, resp} {
+		if v, ok := m.(interface{ GetId() string }); ok && v.GetId() != "" {
+			return v.GetId()
+		}
+	}
+	for _, m := range []interface{}{req, resp} {
+		if v, ok := m.(interface{ GetName() string }); ok && v.GetName() != "" {
+			return v.GetName()
+		}
+	}
+	return ""
+}
+
+// NewAuditEvent builds an audit event for the caller in ctx. It is shared by the
+// gRPC interceptors and the HTTP middleware.
+func NewAuditEvent(ctx context.Context, transport, method, resource, digest string, code codes.Code) *model.AuditEvent {
+	event := &model.AuditEvent{
+		Timestamp:     time.Now(),
+		UserID:        GetUserID(ctx),
+		Transport:     transport,
+		Method:        method,
+		Resource:      resource,
+		RequestDigest: digest,
+		Code:          code.String(),
+	}
+	if claims := GetClaims(ctx); claims != nil {
+		event.AuthMethod = claims.TokenUse
+		if event.AuthMethod == "" {
+			event.AuthMethod = TokenUseAccess
+		}
+	}
+
+	// Over gRPC the namespace interceptor runs later; a namespace the caller may
+	// not use is recorded as requested
+	if namespace, ok := ctx.Value(namespaceKey).(string); ok {
+		event.Namespace = namespace
+		return event
+	}
+	requested := requestedNamespace(ctx)
+	if nsCtx, err := ResolveNamespace(ctx, requested); err == nil {
+		event.Namespace = GetNamespace(nsCtx)
+	} else {
+		event.Namespace = requested
+	}
+	return event
+}
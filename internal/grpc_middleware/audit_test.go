@@ -0,0 +1,74 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"taskflow/internal/model"
+)
+
+type auditRecorderFunc func(ctx context.Context, event *model.AuditEvent)
+
+func (f auditRecorderFunc) RecordAudit(ctx context.Context, event *model.AuditEvent) {
+	f(ctx, event)
+}
+
+type createdResource struct{ id string }
+
+func (r *createdResource) GetId() string { return r.id }
+
+func TestUnaryAuditInterceptor(t *testing.T) {
+	var events []*model.AuditEvent
+	interceptor := UnaryAuditInterceptor(auditRecorderFunc(func(ctx context.Context, event *model.AuditEvent) {
+		events = append(events, event)
+	}))
+
+	claims := &Claims{Namespace: "team-a", TokenUse: TokenUseAPIKey}
+	claims.Subject = "alice"
+	ctx := WithClaims(context.Background(), claims, "")
+	req := wrapperspb.String("payload")
+
+	created := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &createdResource{id: "t-1"}, nil
+	}
+	denied := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.PermissionDenied, "denied")
+	}
+
+	if _, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/CreateTask"}, created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/PurgeTasks"}, denied); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected the handler error to pass through, got %v", err)
+	}
+	if _, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"}, created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/taskflow.AuthService/Login"}, created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected read-only and public calls to be skipped, got %d events", len(events))
+	}
+
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	sum := sha256.Sum256(data)
+	first := events[0]
+	if first.UserID != "alice" || first.AuthMethod != TokenUseAPIKey || first.Namespace != "team-a" ||
+		first.Transport != model.AuditTransportGRPC || first.Resource != "t-1" || first.Code != "OK" ||
+		first.RequestDigest != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected event %+v", first)
+	}
+	if events[1].Method != "/taskflow.TaskService/PurgeTasks" || events[1].Code != codes.PermissionDenied.String() {
+		t.Errorf("expected denied call recorded, got %+v", events[1])
+	}
+}
@@ -3,6 +3,7 @@ package grpc_middleware
 import (
 	"context"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -10,147 +11,160 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// ContextKeys context keys
-type ContextKeys struct {
-	UserID   string
-	UserName string
-	Token    string
-}
+// contextKey context key type, unexported to avoid collisions with other packages
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	userNameKey
+	tokenKey
+	claimsKey
+	roleKey
+	namespaceKey
+	peerCertKey
+)
 
-var contextKeys = &ContextKeys{}
+// APIKeyHeader metadata key (and HTTP header) carrying an API key
+const APIKeyHeader = "x-api-key"
 
 // PublicMethods public methods that don't require authentication
 var PublicMethods = map[string]bool{
-	"/taskflow.TaskService/HealthCheck": true,
-	"/taskflow.TaskService/Login":       true,
+	"/grpc.health.v1.Health/Check":       true,
+	"/grpc.health.v1.Health/Watch":       true,
+	"/taskflow.AuthService/Login":        true,
+	"/taskflow.AuthService/RefreshToken": true,
 }
 
 // AuthConfig authentication config
 type AuthConfig struct {
-	Secret          string
+	Secret           string        // HS256 shared secret
+	JWKSFile         string        // local JWKS file with RS256/ES256 public keys
+	Issuer           string        // expected iss, empty to skip the check
+	Audience         string        // expected aud, empty to skip the check
+	ClockSkew        time.Duration // tolerance for exp/nbf/iat
+	SigningKeyFile   string        // PEM private key used to issue RS256/ES256 tokens when Secret is empty
+	SigningKeyID     string        // kid header of issued tokens, must match the JWKS entry
 	TokenExpireHours int
 }
 
-// DefaultAuthConfig default auth config
+// DefaultAuthConfig default auth config; a secret or JWKS file must be configured
 var DefaultAuthConfig = &AuthConfig{
-	Secret:          "taskflow-secret-key",
+	ClockSkew:        30 * time.Second,
 	TokenExpireHours: 24,
 }
 
 // UnaryAuthInterceptor creates unary auth interceptor
-func UnaryAuthInterceptor(cfg *AuthConfig) grpc.UnaryServerInterceptor {
-	if cfg == nil {
-		cfg = DefaultAuthConfig
-	}
-	
+func UnaryAuthInterceptor(verifier *TokenVerifier) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Skip auth for public methods
 		if PublicMethods[info.FullMethod] {
 			return handler(ctx, req)
 		}
-		
-		// Extract token from metadata
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
-		}
-		
-		// Get authorization header
-		authHeader := md.Get("authorization")
-		if len(authHeader) == 0 {
-			return nil, status.Errorf(codes.Unauthenticated, "missing authorization header")
-		}
-		
-		// Parse Bearer token
-		token := strings.TrimPrefix(authHeader[0], "Bearer ")
-		if token == authHeader[0] {
-			return nil, status.Errorf(codes.Unauthenticated, "invalid authorization format")
-		}
-		
-		// In production, validate JWT token here
-		// For now, extract user info from token (simplified)
-		userID, userName, err := validateToken(token, cfg.Secret)
+
+		ctx, err := authenticate(ctx, verifier)
 		if err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+			return nil, err
 		}
-		
-		// Add user info to context
-		ctx = context.WithValue(ctx, contextKeys.UserID, userID)
-		ctx = context.WithValue(ctx, contextKeys.UserName, userName)
-		ctx = context.WithValue(ctx, contextKeys.Token, token)
-		
+
 		return handler(ctx, req)
 	}
 }
 
 // StreamAuthInterceptor creates stream auth interceptor
-func StreamAuthInterceptor(cfg *AuthConfig) grpc.StreamServerInterceptor {
-	if cfg == nil {
-		cfg = DefaultAuthConfig
-	}
-	
+func StreamAuthInterceptor(verifier *TokenVerifier) grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		// Skip auth for public methods
 		if PublicMethods[info.FullMethod] {
 			return handler(srv, ss)
 		}
-		
-		// Extract token from metadata
-		md, ok := metadata.FromIncomingContext(ss.Context())
-		if !ok {
-			return status.Errorf(codes.Unauthenticated, "missing metadata")
-		}
-		
-		authHeader := md.Get("authorization")
-		if len(authHeader) == 0 {
-			return status.Errorf(codes.Unauthenticated, "missing authorization header")
-		}
-		
-		token := strings.TrimPrefix(authHeader[0], "Bearer ")
-		if token == authHeader[0] {
-			return status.Errorf(codes.Unauthenticated, "invalid authorization format")
-		}
-		
-		userID, userName, err := validateToken(token, cfg.Secret)
+
+		ctx, err := authenticate(ss.Context(), verifier)
 		if err != nil {
-			return status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+			return err
 		}
-		
-		// Add user info to context
-		ctx := ss.Context()
-		ctx = context.WithValue(ctx, contextKeys.UserID, userID)
-		ctx = context.WithValue(ctx, contextKeys.UserName, userName)
-		ctx = context.WithValue(ctx, contextKeys.Token, token)
-		
+
 		wrappedStream := &serverStream{
 			ServerStream: ss,
-			ctx:         ctx,
+			ctx:          ctx,
 		}
-		
+
 		return handler(srv, wrappedStream)
 	}
 }
 
-// validateToken validates token and returns user info
-// In production, implement proper JWT validation
-func validateToken(token, secret string) (userID, userName string, err error) {
-	// Simplified validation - in production use proper JWT library
-	// For now, accept any non-empty token and extract user info
-	if len(token) == 0 {
-		return "", "", status.Errorf(codes.InvalidArgument, "empty token")
+// authenticate reads credentials from metadata and stores the claims in context
+func authenticate(ctx context.Context, verifier *TokenVerifier) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
+	}
+
+	var authorization, apiKey string
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+	if values := md.Get(APIKeyHeader); len(values) > 0 {
+		apiKey = values[0]
 	}
-	
-	// Extract user info from token (simplified)
-	// In production: verify JWT signature, check expiration, etc.
-	userID = "user-" + token[:min(8, len(token))]
-	userName = "User"
-	
-	return userID, userName, nil
+	return Authenticate(ctx, verifier, authorization, apiKey)
+}
+
+// Authenticate verifies an "authorization: Bearer" token or an x-api-key credential
+// and returns a context carrying the claims. Without either, a verified mutual TLS
+// client certificate (see PeerCertificate) authenticates the caller. It is shared
+// by the gRPC interceptors and the HTTP middleware so both transports accept the
+// same credentials. Errors are gRPC status errors with code Unauthenticated.
+func Authenticate(ctx context.Context, verifier *TokenVerifier, authorization, apiKey string) (context.Context, error) {
+	if apiKey != "" {
+		if verifier.apiKeys == nil {
+			return nil, status.Errorf(codes.Unauthenticated, "API keys are not enabled")
+		}
+		claims, err := verifier.apiKeys.AuthenticateAPIKey(ctx, apiKey)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid API key: %v", err)
+		}
+		return WithClaims(ctx, claims, ""), nil
+	}
+
+	if authorization == "" {
+		if cert := PeerCertificate(ctx); cert != nil {
+			claims := CertificateClaims(cert)
+			if claims.Subject == "" {
+				return nil, status.Errorf(codes.Unauthenticated, "client certificate has no usable identity")
+			}
+			return WithClaims(ctx, claims, ""), nil
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "missing authorization header")
+	}
+
+	// Parse Bearer token
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if token == authorization || token == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid authorization format")
+	}
+
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if claims.TokenUse == TokenUseRefresh {
+		return nil, status.Errorf(codes.Unauthenticated, "refresh token cannot be used for API calls")
+	}
+
+	return WithClaims(ctx, claims, token), nil
+}
+
+// WithClaims returns a context carrying the verified claims and raw token
+func WithClaims(ctx context.Context, claims *Claims, token string) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, claims.Subject)
+	ctx = context.WithValue(ctx, userNameKey, claims.Name)
+	ctx = context.WithValue(ctx, tokenKey, token)
+	return context.WithValue(ctx, claimsKey, claims)
 }
 
 // GetUserID extracts user ID from context
 func GetUserID(ctx context.Context) string {
-	if userID, ok := ctx.Value(contextKeys.UserID).(string); ok {
+	if userID, ok := ctx.Value(userIDKey).(string); ok {
 		return userID
 	}
 	return ""
@@ -158,7 +172,7 @@ func GetUserID(ctx context.Context) string {
 
 // GetUserName extracts user name from context
 func GetUserName(ctx context.Context) string {
-	if userName, ok := ctx.Value(contextKeys.UserName).(string); ok {
+	if userName, ok := ctx.Value(userNameKey).(string); ok {
 		return userName
 	}
 	return ""
@@ -166,17 +180,18 @@ func GetUserName(ctx context.Context) string {
 
 // GetToken extracts token from context
 func GetToken(ctx context.Context) string {
-	if token, ok := ctx.Value(contextKeys.Token).(string); ok {
+	if token, ok := ctx.Value(tokenKey).(string); ok {
 		return token
 	}
 	return ""
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// GetClaims extracts the verified JWT claims from context
+func GetClaims(ctx context.Context) *Claims {
+	if claims, ok := ctx.Value(claimsKey).(*Claims); ok {
+		return claims
 	}
-	return b
+	return nil
 }
 
 // serverStream wraps grpc.ServerStream to override context
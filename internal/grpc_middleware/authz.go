@@ -0,0 +1,169 @@
+package grpc_middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Role caller role; each role includes the permissions of the roles below it
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleSubmitter
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:    "viewer",
+	RoleSubmitter: "submitter",
+	RoleOperator:  "operator",
+	RoleAdmin:     "admin",
+}
+
+// String returns the role name used in tokens, API key scopes and config
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, bool) {
+	for role, n := range roleNames {
+		if n == name {
+			return role, true
+		}
+	}
+	return RoleNone, false
+}
+
+// MethodRoles minimum role required per gRPC method.
+// Methods not listed here require RoleAdmin; PublicMethods skip authorization.
+// Submitters may only modify their own tasks, which the task handler enforces.
+var MethodRoles = map[string]Role{
+	"/taskflow.TaskService/GetTask":          RoleViewer,
+	"/taskflow.TaskService/ListTasks":        RoleViewer,
+	"/taskflow.TaskService/ListTaskEvents":   RoleViewer,
+	"/taskflow.TaskService/WaitTask":         RoleViewer,
+	"/taskflow.TaskService/WatchTask":        RoleViewer,
+	"/taskflow.TaskService/CreateTask":       RoleSubmitter,
+	"/taskflow.TaskService/BatchCreateTasks": RoleSubmitter,
+	"/taskflow.TaskService/UpdateTask":       RoleSubmitter,
+	"/taskflow.TaskService/TaskUpdates":      RoleSubmitter,
+	"/taskflow.TaskService/CancelTask":       RoleSubmitter,
+	"/taskflow.TaskService/RetryTask":        RoleSubmitter,
+	"/taskflow.TaskService/DeleteTask":       RoleSubmitter,
+	"/taskflow.TaskService/BulkCancel":       RoleOperator,
+	"/taskflow.TaskService/BulkRetry":        RoleOperator,
+	"/taskflow.TaskService/BulkSetPriority":  RoleOperator,
+	"/taskflow.TaskService/GetBulkOperation": RoleOperator,
+	"/taskflow.TaskService/PurgeTasks":       RoleAdmin,
+	"/taskflow.TaskService/BackupDatabase":   RoleAdmin,
+	"/taskflow.AuthService/RevokeToken":      RoleViewer,
+	"/taskflow.APIKeyService/CreateAPIKey":   RoleViewer,
+	"/taskflow.APIKeyService/GetAPIKey":      RoleViewer,
+	"/taskflow.APIKeyService/ListAPIKeys":    RoleViewer,
+	"/taskflow.APIKeyService/UpdateAPIKey":   RoleViewer,
+	"/taskflow.APIKeyService/DeleteAPIKey":   RoleViewer,
+
+	// Namespaces and their quotas are managed by admins only
+	"/taskflow.NamespaceService/CreateNamespace": RoleAdmin,
+	"/taskflow.NamespaceService/GetNamespace":    RoleAdmin,
+	"/taskflow.NamespaceService/ListNamespaces":  RoleAdmin,
+	"/taskflow.NamespaceService/UpdateNamespace": RoleAdmin,
+	"/taskflow.NamespaceService/DeleteNamespace": RoleAdmin,
+
+	// The audit log records every caller and is readable by admins only
+	"/taskflow.AuditService/ListAuditEvents": RoleAdmin,
+}
+
+// RoleFromClaims returns the highest known role in the claims, or defaultRole if there is none
+func RoleFromClaims(claims *Claims, defaultRole Role) Role {
+	role := RoleNone
+	if claims != nil {
+		for _, name := range claims.Roles {
+			if r, ok := ParseRole(name); ok && r > role {
+				role = r
+			}
+		}
+	}
+	if role == RoleNone {
+		return defaultRole
+	}
+	return role
+}
+
+// UnaryAuthzInterceptor checks the caller's role against MethodRoles.
+// It must run after UnaryAuthInterceptor, which stores the claims in context.
+func UnaryAuthzInterceptor(defaultRole Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if PublicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := Authorize(ctx, info.FullMethod, defaultRole)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthzInterceptor checks the caller's role against MethodRoles for streaming methods
+func StreamAuthzInterceptor(defaultRole Role) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if PublicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx, err := Authorize(ss.Context(), info.FullMethod, defaultRole)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Authorize checks that the authenticated caller may invoke fullMethod and
+// returns a context carrying the caller's role. It is shared by the gRPC
+// interceptors and the HTTP middleware. Errors are gRPC status errors.
+func Authorize(ctx context.Context, fullMethod string, defaultRole Role) (context.Context, error) {
+	claims := GetClaims(ctx)
+	if claims == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication required")
+	}
+
+	required, ok := MethodRoles[fullMethod]
+	if !ok {
+		required = RoleAdmin
+	}
+
+	role := RoleFromClaims(claims, defaultRole)
+	if role < required {
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires role %s, caller has %s", fullMethod, required, role)
+	}
+
+	return WithRole(ctx, role), nil
+}
+
+// WithRole returns a context carrying the caller's role
+func WithRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// GetRole extracts the caller's role from context; RoleNone when authorization is disabled
+func GetRole(ctx context.Context) Role {
+	if role, ok := ctx.Value(roleKey).(Role); ok {
+		return role
+	}
+	return RoleNone
+}
@@ -0,0 +1,65 @@
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleFromClaims(t *testing.T) {
+	if role := RoleFromClaims(&Claims{Roles: []string{"viewer", "operator", "unknown"}}, RoleViewer); role != RoleOperator {
+		t.Errorf("expected highest role operator, got %s", role)
+	}
+	if role := RoleFromClaims(&Claims{Roles: []string{"unknown"}}, RoleSubmitter); role != RoleSubmitter {
+		t.Errorf("expected default role submitter, got %s", role)
+	}
+	if role, ok := ParseRole("admin"); !ok || role != RoleAdmin || role.String() != "admin" {
+		t.Errorf("unexpected ParseRole result %v %v", role, ok)
+	}
+}
+
+func TestUnaryAuthzInterceptor(t *testing.T) {
+	interceptor := UnaryAuthzInterceptor(RoleViewer)
+
+	var role Role
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		role = GetRole(ctx)
+		return nil, nil
+	}
+	call := func(method string, roles ...string) error {
+		claims := &Claims{Roles: roles}
+		claims.Subject = "alice"
+		ctx := WithClaims(context.Background(), claims, "")
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	if err := call("/taskflow.TaskService/GetTask"); err != nil || role != RoleViewer {
+		t.Errorf("viewer GetTask: err=%v role=%s", err, role)
+	}
+	if err := call("/taskflow.TaskService/CreateTask"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for viewer CreateTask, got %v", err)
+	}
+	if err := call("/taskflow.TaskService/CreateTask", "submitter"); err != nil {
+		t.Errorf("submitter CreateTask rejected: %v", err)
+	}
+	if err := call("/taskflow.TaskService/BulkCancel", "submitter"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for submitter BulkCancel, got %v", err)
+	}
+	// 未登记的方法只允许 admin
+	if err := call("/taskflow.TaskService/Unlisted", "operator"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for unlisted method, got %v", err)
+	}
+	if err := call("/taskflow.TaskService/Unlisted", "admin"); err != nil {
+		t.Errorf("admin rejected on unlisted method: %v", err)
+	}
+
+	// 没有经过认证拦截器
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"}, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without claims, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// TokenUseCertificate token_use of claims derived from a verified client certificate
+const TokenUseCertificate = "certificate"
+
+// WithPeerCertificate returns a context carrying a verified client certificate.
+// The HTTP middleware uses it to pass the certificate of a mutual TLS connection
+// to Authenticate; gRPC requests read it from the peer info instead.
+func WithPeerCertificate(ctx context.Context, cert *x509.Certificate) context.Context {
+	return context.WithValue(ctx, peerCertKey, cert)
+}
+
+// PeerCertificate returns the verified client certificate of the request, nil if
+// the connection isn't mutual TLS or the client didn't present a certificate
+func PeerCertificate(ctx context.Context) *x509.Certificate {
+	if cert, ok := ctx.Value(peerCertKey).(*x509.Certificate); ok {
+		return cert
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// CertificateClaims maps a verified client certificate to claims. The subject is
+// the first URI SAN (e.g. a SPIFFE ID), else the Common Name, else the first DNS
+// or email SAN. Organizational Units naming a role become the caller's roles, so
+// the CA that signs client certificates decides what they may do.
+func CertificateClaims(cert *x509.Certificate) *Claims {
+	claims := &Claims{
+		Name:     cert.Subject.CommonName,
+		TokenUse: TokenUseCertificate,
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if _, ok := ParseRole(ou); ok {
+			claims.Roles = append(claims.Roles, ou)
+		}
+	}
+
+	switch {
+	case len(cert.URIs) > 0:
+		claims.Subject = cert.URIs[0].String()
+	case cert.Subject.CommonName != "":
+		claims.Subject = cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		claims.Subject = cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		claims.Subject = cert.EmailAddresses[0]
+	}
+	claims.ID = cert.SerialNumber.String()
+	return claims
+}
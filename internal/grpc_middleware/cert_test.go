@@ -0,0 +1,68 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCertificateClaims(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/ci")
+
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		subject string
+		roles   []string
+	}{
+		{"URI SAN wins", &x509.Certificate{URIs: []*url.URL{spiffe}, Subject: pkix.Name{CommonName: "ci"}}, "spiffe://example.org/ci", nil},
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "ci-bot"}, DNSNames: []string{"ci.example.org"}}, "ci-bot", nil},
+		{"DNS SAN", &x509.Certificate{DNSNames: []string{"ci.example.org"}}, "ci.example.org", nil},
+		{"email SAN", &x509.Certificate{EmailAddresses: []string{"ci@example.org"}}, "ci@example.org", nil},
+		{"roles from OU", &x509.Certificate{Subject: pkix.Name{CommonName: "ops", OrganizationalUnit: []string{"platform", "operator"}}}, "ops", []string{"operator"}},
+		{"no identity", &x509.Certificate{}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cert.SerialNumber = big.NewInt(7)
+			claims := CertificateClaims(tt.cert)
+			if claims.Subject != tt.subject {
+				t.Errorf("expected subject %q, got %q", tt.subject, claims.Subject)
+			}
+			if !reflect.DeepEqual(claims.Roles, tt.roles) {
+				t.Errorf("expected roles %v, got %v", tt.roles, claims.Roles)
+			}
+			if claims.TokenUse != TokenUseCertificate {
+				t.Errorf("expected token_use %q, got %q", TokenUseCertificate, claims.TokenUse)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_PeerCertificate(t *testing.T) {
+	verifier := &TokenVerifier{}
+
+	cert := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ci-bot"}}
+	ctx, err := Authenticate(WithPeerCertificate(context.Background(), cert), verifier, "", "")
+	if err != nil {
+		t.Fatalf("expected the client certificate to authenticate, got %v", err)
+	}
+	if GetUserID(ctx) != "ci-bot" {
+		t.Errorf("expected user ci-bot, got %q", GetUserID(ctx))
+	}
+
+	anonymous := &x509.Certificate{SerialNumber: big.NewInt(2)}
+	if _, err := Authenticate(WithPeerCertificate(context.Background(), anonymous), verifier, "", ""); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated for a certificate without identity, got %v", err)
+	}
+	if _, err := Authenticate(context.Background(), verifier, "", ""); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without credentials, got %v", err)
+	}
+}
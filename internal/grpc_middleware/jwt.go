@@ -0,0 +1,316 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token use values; refresh tokens are only accepted by AuthService.RefreshToken.
+// TokenUseAPIKey marks claims built from an x-api-key credential rather than a JWT.
+const (
+	TokenUseAccess  = "access"
+	TokenUseRefresh = "refresh"
+	TokenUseAPIKey  = "api_key"
+)
+
+// ErrTokenRevoked the token ID is on the revocation list
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Claims JWT claims carried by TaskFlow tokens; the subject is the user ID
+type Claims struct {
+	Name      string   `json:"name,omitempty"`
+	TokenUse  string   `json:"token_use,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// RevocationChecker reports whether a token ID (jti) has been revoked
+type RevocationChecker interface {
+	IsRevoked(jti string) bool
+}
+
+// APIKeyAuthenticator resolves an x-api-key credential into claims
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*Claims, error)
+}
+
+// TokenVerifier verifies JWT signatures and registered claims
+type TokenVerifier struct {
+	secret  []byte
+	keys    map[string]interface{} // JWKS public keys by kid
+	parser  *jwt.Parser
+	revoked RevocationChecker
+	apiKeys APIKeyAuthenticator
+}
+
+// NewTokenVerifier creates a verifier from the auth config.
+// HS256 is accepted when Secret is set, RS256/ES256 when JWKSFile is set.
+func NewTokenVerifier(cfg *AuthConfig) (*TokenVerifier, error) {
+	if cfg == nil {
+		cfg = DefaultAuthConfig
+	}
+
+	v := &TokenVerifier{secret: []byte(cfg.Secret)}
+	var methods []string
+	if cfg.Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth: either a secret or a JWKS file is required")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.ClockSkew),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// SetRevocationChecker enables revocation checks for tokens carrying a jti
+func (v *TokenVerifier) SetRevocationChecker(checker RevocationChecker) {
+	v.revoked = checker
+}
+
+// SetAPIKeyAuthenticator enables x-api-key authentication alongside bearer tokens
+func (v *TokenVerifier) SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	v.apiKeys = authenticator
+}
+
+// Verify parses the token, checks signature, exp/nbf/iss/aud, revocation and returns its claims
+func (v *TokenVerifier) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if claims.ID != "" && v.revoked != nil && v.revoked.IsRevoked(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// keyFunc selects the verification key for the token's algorithm
+func (v *TokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return v.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid != "" {
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	}
+
+	// Tokens without kid are accepted only when the JWKS holds a single key
+	if len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	return nil, errors.New("token has no key id")
+}
+
+// jsonWebKey the subset of RFC 7517 fields used for RSA and P-256 public keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads public verification keys from a local JWKS file
+func loadJWKS(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: JWKS key %d (%s): %w", i, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("auth: JWKS contains no signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key into *rsa.PublicKey or *ecdsa.PublicKey
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := decodeBase64URL(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBase64URL(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 coordinates")
+		}
+		// Reject points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeBase64URL decodes unpadded base64url as used by JWK
+func decodeBase64URL(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// TokenIssuer signs TaskFlow access and refresh tokens.
+// HS256 is used when Secret is set, otherwise RS256/ES256 with the PEM key in SigningKeyFile.
+type TokenIssuer struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	kid      string
+	issuer   string
+	audience string
+}
+
+// NewTokenIssuer creates an issuer from the auth config
+func NewTokenIssuer(cfg *AuthConfig) (*TokenIssuer, error) {
+	if cfg == nil {
+		cfg = DefaultAuthConfig
+	}
+
+	i := &TokenIssuer{kid: cfg.SigningKeyID, issuer: cfg.Issuer, audience: cfg.Audience}
+	switch {
+	case cfg.Secret != "":
+		i.method, i.key = jwt.SigningMethodHS256, []byte(cfg.Secret)
+	case cfg.SigningKeyFile != "":
+		data, err := os.ReadFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: read signing key: %w", err)
+		}
+		if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			i.method, i.key = jwt.SigningMethodRS256, key
+		} else if key, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil && key.Curve == elliptic.P256() {
+			i.method, i.key = jwt.SigningMethodES256, key
+		} else {
+			return nil, errors.New("auth: signing key must be an RSA or P-256 EC private key in PEM format")
+		}
+	default:
+		return nil, errors.New("auth: either a secret or a signing key file is required to issue tokens")
+	}
+	return i, nil
+}
+
+// Issue signs a token for the subject; use is TokenUseAccess or TokenUseRefresh
+func (i *TokenIssuer) Issue(subject, name, use string, roles []string, namespace string, ttl time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		Name:      name,
+		TokenUse:  use,
+		Roles:     roles,
+		Namespace: namespace,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   subject,
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token := jwt.NewWithClaims(i.method, claims)
+	if i.kid != "" {
+		token.Header["kid"] = i.kid
+	}
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
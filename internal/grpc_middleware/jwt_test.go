@@ -0,0 +1,237 @@
+package grpc_middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func signHS256(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func testClaims(offset time.Duration) *Claims {
+	now := time.Now().Add(offset)
+	return &Claims{
+		Name: "Alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "taskflow",
+			Audience:  jwt.ClaimStrings{"taskflow-api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func TestTokenVerifier_HS256(t *testing.T) {
+	if _, err := NewTokenVerifier(&AuthConfig{}); err == nil {
+		t.Fatal("expected error without secret or JWKS")
+	}
+
+	v, err := NewTokenVerifier(&AuthConfig{
+		Secret: testSecret, Issuer: "taskflow", Audience: "taskflow-api", ClockSkew: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenVerifier failed: %v", err)
+	}
+
+	claims, err := v.Verify(signHS256(t, testClaims(0)))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.Subject != "alice" || claims.Name != "Alice" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// 过期 10 秒仍在时钟偏差范围内
+	skewed := testClaims(0)
+	skewed.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	if _, err := v.Verify(signHS256(t, skewed)); err != nil {
+		t.Errorf("token within clock skew rejected: %v", err)
+	}
+
+	cases := map[string]func(c *Claims){
+		"expired":    func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+		"no exp":     func(c *Claims) { c.ExpiresAt = nil },
+		"not yet":    func(c *Claims) { c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute)) },
+		"wrong iss":  func(c *Claims) { c.Issuer = "other" },
+		"wrong aud":  func(c *Claims) { c.Audience = jwt.ClaimStrings{"other"} },
+		"no subject": func(c *Claims) { c.Subject = "" },
+	}
+	for name, mutate := range cases {
+		c := testClaims(0)
+		mutate(c)
+		if _, err := v.Verify(signHS256(t, c)); err == nil {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(0)).SignedString([]byte("another-secret-another-secret-xx"))
+	if _, err := v.Verify(forged); err == nil {
+		t.Error("expected token with wrong signature to be rejected")
+	}
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims(0)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := v.Verify(unsigned); err == nil {
+		t.Error("expected alg=none token to be rejected")
+	}
+}
+
+func TestTokenVerifier_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		},
+	}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	v, err := NewTokenVerifier(&AuthConfig{JWKSFile: path})
+	if err != nil {
+		t.Fatalf("NewTokenVerifier failed: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims(0))
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return s
+	}
+
+	if _, err := v.Verify(sign(jwt.SigningMethodRS256, "rsa-1", rsaKey)); err != nil {
+		t.Errorf("RS256 token rejected: %v", err)
+	}
+	if _, err := v.Verify(sign(jwt.SigningMethodES256, "ec-1", ecKey)); err != nil {
+		t.Errorf("ES256 token rejected: %v", err)
+	}
+	if _, err := v.Verify(sign(jwt.SigningMethodRS256, "ec-1", rsaKey)); err == nil {
+		t.Error("expected RS256 token with EC kid to be rejected")
+	}
+	if _, err := v.Verify(sign(jwt.SigningMethodRS256, "unknown", rsaKey)); err == nil {
+		t.Error("expected unknown kid to be rejected")
+	}
+	if _, err := v.Verify(sign(jwt.SigningMethodRS256, "", rsaKey)); err == nil {
+		t.Error("expected token without kid to be rejected when JWKS has several keys")
+	}
+	// 只配置 JWKS 时不接受 HS256
+	if _, err := v.Verify(signHS256(t, testClaims(0))); err == nil {
+		t.Error("expected HS256 token to be rejected without a secret")
+	}
+}
+
+func TestUnaryAuthInterceptor_Claims(t *testing.T) {
+	v, err := NewTokenVerifier(&AuthConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatalf("NewTokenVerifier failed: %v", err)
+	}
+	interceptor := UnaryAuthInterceptor(v)
+	info := &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"}
+
+	var userID, userName string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		userID, userName = GetUserID(ctx), GetUserName(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("authorization", "Bearer "+signHS256(t, testClaims(0))))
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("interceptor rejected valid token: %v", err)
+	}
+	if userID != "alice" || userName != "Alice" {
+		t.Errorf("expected alice/Alice in context, got %q/%q", userID, userName)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer not-a-jwt"))
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+}
+
+// fakeAPIKeys 只接受一个固定的 API Key
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (*Claims, error) {
+	if key != "tfk_valid" {
+		return nil, errors.New("unknown key")
+	}
+	claims := &Claims{Name: "pipeline", TokenUse: TokenUseAPIKey}
+	claims.Subject = "ci"
+	return claims, nil
+}
+
+func TestUnaryAuthInterceptor_APIKey(t *testing.T) {
+	v, err := NewTokenVerifier(&AuthConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatalf("NewTokenVerifier failed: %v", err)
+	}
+	interceptor := UnaryAuthInterceptor(v)
+	info := &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"}
+
+	var userID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		userID = GetUserID(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyHeader, "tfk_valid"))
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated when API keys are disabled, got %v", err)
+	}
+
+	v.SetAPIKeyAuthenticator(fakeAPIKeys{})
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("interceptor rejected valid API key: %v", err)
+	}
+	if userID != "ci" {
+		t.Errorf("expected ci in context, got %q", userID)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyHeader, "tfk_other"))
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated, got %v", err)
+	}
+}
@@ -45,7 +45,7 @@ func UnaryLoggerInterceptor(cfg *LoggerConfig) grpc.UnaryServerInterceptor {
 		
 		// Get metadata
 		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			cfg.InfoLogger.Printf("[%s] Metadata: %v", requestID, md)
+			cfg.InfoLogger.Printf("[%s] Metadata: %v", requestID, redactMetadata(md))
 		}
 		
 		// Call handler
@@ -198,6 +198,17 @@ func generateRequestID(ctx context.Context) string {
 	return generateID()
 }
 
+// redactMetadata returns a copy of md with credentials replaced, safe to log
+func redactMetadata(md metadata.MD) metadata.MD {
+	redacted := md.Copy()
+	for _, key := range []string{"authorization", APIKeyHeader} {
+		if len(redacted.Get(key)) > 0 {
+			redacted.Set(key, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
 // GetRequestID extracts request ID from context
 func GetRequestID(ctx context.Context) string {
 	if rid, ok := ctx.Value("request_id").(string); ok {
@@ -0,0 +1,103 @@
+package grpc_middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/model"
+)
+
+// NamespaceHeader metadata key (and HTTP header) selecting the namespace of a request
+const NamespaceHeader = "x-namespace"
+
+// DefaultNamespace namespace of callers whose identity doesn't name one
+const DefaultNamespace = model.DefaultNamespace
+
+// UnaryNamespaceInterceptor resolves the caller's namespace and stores it in context.
+// It must run after the auth and authz interceptors when authentication is enabled.
+func UnaryNamespaceInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if PublicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := ResolveNamespace(ctx, requestedNamespace(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamNamespaceInterceptor resolves the caller's namespace for streaming methods
+func StreamNamespaceInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if PublicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx, err := ResolveNamespace(ss.Context(), requestedNamespace(ss.Context()))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// requestedNamespace reads the x-namespace metadata
+func requestedNamespace(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(NamespaceHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ResolveNamespace returns a context carrying the namespace the request operates in.
+// Authenticated callers are bound to the namespace in their claims (DefaultNamespace
+// if none); only admins may select another one with x-namespace. Without
+// authentication the requested namespace is used as is. It is shared by the gRPC
+// interceptors and the HTTP middleware. Errors are gRPC status errors.
+func ResolveNamespace(ctx context.Context, requested string) (context.Context, error) {
+	claims := GetClaims(ctx)
+	if claims == nil {
+		if requested == "" {
+			requested = DefaultNamespace
+		}
+		return WithNamespace(ctx, requested), nil
+	}
+
+	own := claims.Namespace
+	if own == "" {
+		own = DefaultNamespace
+	}
+	if requested == "" || requested == own {
+		return WithNamespace(ctx, own), nil
+	}
+	if GetRole(ctx) != RoleAdmin {
+		return nil, status.Errorf(codes.PermissionDenied, "caller is bound to namespace %s", own)
+	}
+	return WithNamespace(ctx, requested), nil
+}
+
+// WithNamespace returns a context carrying the request namespace
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey, namespace)
+}
+
+// GetNamespace extracts the request namespace from context, DefaultNamespace if unset
+func GetNamespace(ctx context.Context) string {
+	if namespace, ok := ctx.Value(namespaceKey).(string); ok && namespace != "" {
+		return namespace
+	}
+	return DefaultNamespace
+}
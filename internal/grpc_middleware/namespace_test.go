@@ -0,0 +1,64 @@
+package grpc_middleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestResolveNamespace(t *testing.T) {
+	caller := func(namespace string, role Role) context.Context {
+		claims := &Claims{Namespace: namespace}
+		claims.Subject = "alice"
+		return WithRole(WithClaims(context.Background(), claims, ""), role)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		want      string
+		code      codes.Code
+	}{
+		{"auth disabled", context.Background(), "", DefaultNamespace, codes.OK},
+		{"auth disabled selects", context.Background(), "team-a", "team-a", codes.OK},
+		{"bound to claim", caller("team-a", RoleSubmitter), "", "team-a", codes.OK},
+		{"no claim is default", caller("", RoleSubmitter), "", DefaultNamespace, codes.OK},
+		{"own namespace requested", caller("team-a", RoleViewer), "team-a", "team-a", codes.OK},
+		{"other namespace denied", caller("team-a", RoleOperator), "team-b", "", codes.PermissionDenied},
+		{"admin switches", caller("team-a", RoleAdmin), "team-b", "team-b", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := ResolveNamespace(tt.ctx, tt.requested)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %v, got %v", tt.code, err)
+			}
+			if err == nil && GetNamespace(ctx) != tt.want {
+				t.Errorf("expected namespace %s, got %s", tt.want, GetNamespace(ctx))
+			}
+		})
+	}
+}
+
+func TestUnaryNamespaceInterceptor(t *testing.T) {
+	interceptor := UnaryNamespaceInterceptor()
+
+	var namespace string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		namespace = GetNamespace(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(NamespaceHeader, "team-a"))
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"}, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if namespace != "team-a" {
+		t.Errorf("expected namespace from metadata, got %s", namespace)
+	}
+}
@@ -3,11 +3,13 @@ package grpc_middleware
 import (
 	"context"
 	"math"
+	"net"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -56,10 +58,18 @@ func NewTokenBucketLimiter(cfg *RateLimiterConfig) *TokenBucketLimiter {
 
 // defaultClientKeyFunc default client key function
 func defaultClientKeyFunc(ctx context.Context) string {
-	// Use user ID if available, otherwise use "anonymous"
+	// Use user ID if available, otherwise the peer IP (without the port, so
+	// reconnecting does not reset the bucket), otherwise "anonymous"
 	if userID := GetUserID(ctx); userID != "" {
 		return userID
 	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		return "peer:" + addr
+	}
 	return "anonymous"
 }
 
@@ -72,9 +82,9 @@ func (r *TokenBucketLimiter) allow(key string) bool {
 	b, exists := r.tokens[key]
 	
 	if !exists {
-		// Create new bucket
+		// Create new bucket, this request takes the first token
 		r.tokens[key] = &bucket{
-			tokens:     float64(r.config.BurstSize),
+			tokens:     float64(r.config.BurstSize) - 1,
 			maxTokens:  float64(r.config.BurstSize),
 			lastUpdate: now,
 			refillRate: r.config.RequestsPerSecond,
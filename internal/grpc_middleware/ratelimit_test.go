@@ -0,0 +1,47 @@
+package grpc_middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryRateLimiter_ClientKey(t *testing.T) {
+	limiter := NewTokenBucketLimiter(&RateLimiterConfig{RequestsPerSecond: 0.001, BurstSize: 2})
+	interceptor := UnaryRateLimiter(limiter)
+	info := &grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/ListTasks"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	fromPeer := func(addr string) context.Context {
+		tcp, _ := net.ResolveTCPAddr("tcp", addr)
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: tcp})
+	}
+	call := func(ctx context.Context) codes.Code {
+		_, err := interceptor(ctx, nil, info, ok)
+		return status.Code(err)
+	}
+
+	// Without auth each client IP gets its own bucket, regardless of the source port
+	first, reconnected, other := fromPeer("10.0.0.1:5000"), fromPeer("10.0.0.1:5001"), fromPeer("10.0.0.2:5000")
+	if call(first) != codes.OK || call(reconnected) != codes.OK {
+		t.Fatal("expected requests within the burst to pass")
+	}
+	if code := call(first); code != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted after the burst, got %v", code)
+	}
+	if code := call(other); code != codes.OK {
+		t.Errorf("expected another client to be unaffected, got %v", code)
+	}
+
+	// The authenticated user ID takes precedence over the address
+	claims := &Claims{}
+	claims.Subject = "alice"
+	if code := call(WithClaims(first, claims, "")); code != codes.OK {
+		t.Errorf("expected a separate bucket for an authenticated caller, got %v", code)
+	}
+}
@@ -14,10 +14,15 @@ type serverOptions struct {
 	rateLimitEnabled bool
 	loggerEnabled    bool
 	recoveryEnabled  bool
+	authzEnabled     bool
+	namespaceEnabled bool
 	authConfig       *AuthConfig
+	authVerifier     *TokenVerifier
 	tokenLimiter     *TokenBucketLimiter
 	slidingLimiter   *SlidingWindowLimiter
 	loggerConfig     *LoggerConfig
+	auditRecorder    AuditRecorder
+	defaultRole      Role
 }
 
 // WithAuth enables authentication
@@ -28,6 +33,14 @@ func WithAuth(cfg *AuthConfig) ServerOption {
 	}
 }
 
+// WithAuthVerifier enables authentication with a shared token verifier
+func WithAuthVerifier(verifier *TokenVerifier) ServerOption {
+	return func(o *serverOptions) {
+		o.authEnabled = true
+		o.authVerifier = verifier
+	}
+}
+
 // WithRateLimit enables rate limiting with token bucket
 func WithRateLimit(limiter *TokenBucketLimiter) ServerOption {
 	return func(o *serverOptions) {
@@ -48,6 +61,15 @@ func WithSlidingWindowRateLimit(limiter *SlidingWindowLimiter) ServerOption {
 func WithLogger(cfg *LoggerConfig) ServerOption {
 	return func(o *serverOptions) {
 		o.loggerEnabled = true
+		if cfg != nil {
+			o.loggerConfig = cfg
+		}
+	}
+}
+
+// WithLogOutput sets the logger config without enabling logging
+func WithLogOutput(cfg *LoggerConfig) ServerOption {
+	return func(o *serverOptions) {
 		o.loggerConfig = cfg
 	}
 }
@@ -59,6 +81,28 @@ func WithRecovery() ServerOption {
 	}
 }
 
+// WithAudit enables audit logging of mutating calls
+func WithAudit(recorder AuditRecorder) ServerOption {
+	return func(o *serverOptions) {
+		o.auditRecorder = recorder
+	}
+}
+
+// WithAuthorization enables role-based authorization
+func WithAuthorization(defaultRole Role) ServerOption {
+	return func(o *serverOptions) {
+		o.authzEnabled = true
+		o.defaultRole = defaultRole
+	}
+}
+
+// WithNamespaceResolution enables namespace resolution
+func WithNamespaceResolution() ServerOption {
+	return func(o *serverOptions) {
+		o.namespaceEnabled = true
+	}
+}
+
 // DefaultServerOptions returns default server options
 func DefaultServerOptions() *serverOptions {
 	return &serverOptions{
@@ -69,7 +113,8 @@ func DefaultServerOptions() *serverOptions {
 	}
 }
 
-// GetUnaryServerOptions returns unary server options
+// GetUnaryServerOptions returns server options chaining the enabled interceptors
+// in order: recovery, logger, auth, rate limit, audit, authz, namespace
 func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error) {
 	opts := DefaultServerOptions()
 	for _, opt := range options {
@@ -79,27 +124,33 @@ func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error)
 	var unaryInterceptors []grpc.UnaryServerInterceptor
 	var streamInterceptors []grpc.StreamServerInterceptor
 
-	// Add recovery interceptor first (outermost)
+	loggerCfg := opts.loggerConfig
+	if loggerCfg == nil {
+		loggerCfg = defaultLoggerConfig
+	}
+
 	if opts.recoveryEnabled {
-		recoveryCfg := opts.loggerConfig
-		if recoveryCfg == nil {
-			recoveryCfg = defaultLoggerConfig
-		}
-		unaryInterceptors = append(unaryInterceptors, UnaryRecoveryInterceptor(recoveryCfg))
-		streamInterceptors = append(streamInterceptors, StreamRecoveryInterceptor(recoveryCfg))
+		unaryInterceptors = append(unaryInterceptors, UnaryRecoveryInterceptor(loggerCfg))
+		streamInterceptors = append(streamInterceptors, StreamRecoveryInterceptor(loggerCfg))
 	}
 
-	// Add logger interceptor
 	if opts.loggerEnabled {
-		loggerCfg := opts.loggerConfig
-		if loggerCfg == nil {
-			loggerCfg = defaultLoggerConfig
-		}
 		unaryInterceptors = append(unaryInterceptors, UnaryLoggerInterceptor(loggerCfg))
 		streamInterceptors = append(streamInterceptors, StreamLoggerInterceptor(loggerCfg))
 	}
 
-	// Add rate limiter
+	if opts.authEnabled {
+		verifier := opts.authVerifier
+		if verifier == nil {
+			var err error
+			if verifier, err = NewTokenVerifier(opts.authConfig); err != nil {
+				return nil, err
+			}
+		}
+		unaryInterceptors = append(unaryInterceptors, UnaryAuthInterceptor(verifier))
+		streamInterceptors = append(streamInterceptors, StreamAuthInterceptor(verifier))
+	}
+
 	if opts.rateLimitEnabled {
 		if opts.tokenLimiter != nil {
 			unaryInterceptors = append(unaryInterceptors, UnaryRateLimiter(opts.tokenLimiter))
@@ -110,29 +161,27 @@ func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error)
 		}
 	}
 
-	// Add auth interceptor (innermost)
-	if opts.authEnabled {
-		authCfg := opts.authConfig
-		if authCfg == nil {
-			authCfg = DefaultAuthConfig
-		}
-		unaryInterceptors = append(unaryInterceptors, UnaryAuthInterceptor(authCfg))
-		streamInterceptors = append(streamInterceptors, StreamAuthInterceptor(authCfg))
+	if opts.auditRecorder != nil {
+		unaryInterceptors = append(unaryInterceptors, UnaryAuditInterceptor(opts.auditRecorder))
+		streamInterceptors = append(streamInterceptors, StreamAuditInterceptor(opts.auditRecorder))
 	}
 
-	var serverOpts []grpc.ServerOption
+	if opts.authzEnabled {
+		unaryInterceptors = append(unaryInterceptors, UnaryAuthzInterceptor(opts.defaultRole))
+		streamInterceptors = append(streamInterceptors, StreamAuthzInterceptor(opts.defaultRole))
+	}
 
-	if len(unaryInterceptors) == 1 {
-		serverOpts = append(serverOpts, grpc.UnaryInterceptor(unaryInterceptors[0]))
-	} else if len(unaryInterceptors) > 1 {
-		// Use the last one for simplicity
-		serverOpts = append(serverOpts, grpc.UnaryInterceptor(unaryInterceptors[len(unaryInterceptors)-1]))
+	if opts.namespaceEnabled {
+		unaryInterceptors = append(unaryInterceptors, UnaryNamespaceInterceptor())
+		streamInterceptors = append(streamInterceptors, StreamNamespaceInterceptor())
 	}
 
-	if len(streamInterceptors) == 1 {
-		serverOpts = append(serverOpts, grpc.StreamInterceptor(streamInterceptors[0]))
-	} else if len(streamInterceptors) > 1 {
-		serverOpts = append(serverOpts, grpc.StreamInterceptor(streamInterceptors[len(streamInterceptors)-1]))
+	var serverOpts []grpc.ServerOption
+	if len(unaryInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unaryInterceptors...))
+	}
+	if len(streamInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(streamInterceptors...))
 	}
 
 	return serverOpts, nil
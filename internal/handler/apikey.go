@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// APIKeyHandler API Key 管理处理器，调用者只能管理自己名下的 API Key，admin 可管理所有人的
+type APIKeyHandler struct {
+	keys *service.APIKeyService
+	pb.UnimplementedAPIKeyServiceServer
+}
+
+// NewAPIKeyHandler 创建 API Key 管理处理器
+func NewAPIKeyHandler(keys *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey 创建 API Key
+func (h *APIKeyHandler) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	owner, err := apiKeyOwner(ctx, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := apiKeyScopes(ctx, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t := req.ExpiresAt.AsTime()
+		expiresAt = &t
+	}
+
+	key, plaintext, err := h.keys.Create(ctx, req.Name, owner, grpc_middleware.GetNamespace(ctx), scopes, expiresAt)
+	if err != nil {
+		return nil, apiKeyError(err)
+	}
+	return &pb.CreateAPIKeyResponse{ApiKey: toPBAPIKey(key), Key: plaintext}, nil
+}
+
+// GetAPIKey 获取 API Key
+func (h *APIKeyHandler) GetAPIKey(ctx context.Context, req *pb.GetAPIKeyRequest) (*pb.APIKey, error) {
+	key, err := h.ownedKey(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toPBAPIKey(key), nil
+}
+
+// ListAPIKeys 列出 API Key
+func (h *APIKeyHandler) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	owner, err := apiKeyOwner(ctx, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := h.keys.List(ctx, owner)
+	if err != nil {
+		return nil, apiKeyError(err)
+	}
+
+	resp := &pb.ListAPIKeysResponse{ApiKeys: make([]*pb.APIKey, 0, len(keys))}
+	for _, key := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, toPBAPIKey(key))
+	}
+	return resp, nil
+}
+
+// UpdateAPIKey 修改 API Key
+func (h *APIKeyHandler) UpdateAPIKey(ctx context.Context, req *pb.UpdateAPIKeyRequest) (*pb.APIKey, error) {
+	if _, err := h.ownedKey(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	scopes := req.Scopes
+	if req.UpdateScopes {
+		var err error
+		if scopes, err = apiKeyScopes(ctx, req.Scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	update := service.APIKeyUpdate{
+		Name:        req.Name,
+		Scopes:      scopes,
+		ScopesSet:   req.UpdateScopes,
+		ClearExpiry: req.ClearExpiry,
+	}
+	if req.ExpiresAt != nil {
+		t := req.ExpiresAt.AsTime()
+		update.ExpiresAt = &t
+	}
+
+	key, err := h.keys.Update(ctx, req.Id, update)
+	if err != nil {
+		return nil, apiKeyError(err)
+	}
+	return toPBAPIKey(key), nil
+}
+
+// DeleteAPIKey 删除 API Key
+func (h *APIKeyHandler) DeleteAPIKey(ctx context.Context, req *pb.DeleteAPIKeyRequest) (*pb.DeleteAPIKeyResponse, error) {
+	if _, err := h.ownedKey(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	if err := h.keys.Delete(ctx, req.Id); err != nil {
+		return nil, apiKeyError(err)
+	}
+	return &pb.DeleteAPIKeyResponse{Deleted: true}, nil
+}
+
+// ownedKey 获取调用者名下的 API Key，admin 可获取任意 Key
+func (h *APIKeyHandler) ownedKey(ctx context.Context, id string) (*repository.APIKey, error) {
+	caller, err := apiKeyOwner(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	key, err := h.keys.Get(ctx, id)
+	if err != nil {
+		return nil, apiKeyError(err)
+	}
+	if key.Owner != caller && grpc_middleware.GetRole(ctx) != grpc_middleware.RoleAdmin {
+		// 与不存在返回相同错误，不暴露其他用户的 API Key
+		return nil, apiKeyError(service.ErrAPIKeyNotFound)
+	}
+	return key, nil
+}
+
+// apiKeyOwner 解析操作的所有者：为空时为调用者本人，只有 admin 可以指定其他用户
+func apiKeyOwner(ctx context.Context, owner string) (string, error) {
+	caller := grpc_middleware.GetUserID(ctx)
+	if caller == "" {
+		return "", errorcode.NewTaskError(errorcode.ErrCodeUnauthorized, "authentication required").ToGRPCStatus().Err()
+	}
+	if owner == "" || owner == caller {
+		return caller, nil
+	}
+	if grpc_middleware.GetRole(ctx) != grpc_middleware.RoleAdmin {
+		return "", errorcode.NewTaskError(errorcode.ErrCodeForbidden, "cannot manage API keys of another user").ToGRPCStatus().Err()
+	}
+	return owner, nil
+}
+
+// apiKeyScopes 检查并返回 API Key 的权限范围：其中的角色不能高于调用者自身的角色；
+// 未指定角色时写入调用者的角色，避免 Key 按 AUTH_DEFAULT_ROLE 获得高于创建者的权限
+func apiKeyScopes(ctx context.Context, scopes []string) ([]string, error) {
+	callerRole := grpc_middleware.GetRole(ctx)
+	if callerRole == grpc_middleware.RoleNone {
+		return scopes, nil // 未启用授权
+	}
+	hasRole := false
+	for _, scope := range scopes {
+		role, ok := grpc_middleware.ParseRole(scope)
+		if !ok {
+			continue
+		}
+		if role > callerRole {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeForbidden,
+				"cannot grant role "+scope+" above your own role "+callerRole.String()).ToGRPCStatus().Err()
+		}
+		hasRole = true
+	}
+	if hasRole {
+		return scopes, nil
+	}
+	return append(append([]string(nil), scopes...), callerRole.String()), nil
+}
+
+// apiKeyError 将 API Key 服务错误转换为 gRPC 错误
+func apiKeyError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return errorcode.NewTaskError(errorcode.ErrCodeNotFound, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrAPIKeyNameRequired), errors.Is(err, service.ErrAPIKeyExpiryInPast):
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Errorf("API key handler error: %v", err)
+	return errorcode.NewTaskError(errorcode.ErrCodeDBError, "failed to access api keys").ToGRPCStatus().Err()
+}
+
+// toPBAPIKey 转换 API Key，不包含摘要
+func toPBAPIKey(key *repository.APIKey) *pb.APIKey {
+	pbKey := &pb.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		Owner:     key.Owner,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		Namespace: key.Namespace,
+		CreatedAt: timestamppb.New(key.CreatedAt),
+	}
+	if key.ExpiresAt != nil {
+		pbKey.ExpiresAt = timestamppb.New(*key.ExpiresAt)
+	}
+	if key.LastUsedAt != nil {
+		pbKey.LastUsedAt = timestamppb.New(*key.LastUsedAt)
+	}
+	return pbKey
+}
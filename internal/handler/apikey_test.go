@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// TestAPIKeyHandler_RoleCeiling tests that a key never resolves to a role above its creator's,
+// even when AUTH_DEFAULT_ROLE is higher than the creator's role
+func TestAPIKeyHandler_RoleCeiling(t *testing.T) {
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "apikey.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+	keys := service.NewAPIKeyService(repository.NewAuthRepository(db))
+	handler := NewAPIKeyHandler(keys)
+
+	claims := &grpc_middleware.Claims{Name: "reader"}
+	claims.Subject = "reader"
+	ctx := grpc_middleware.WithRole(grpc_middleware.WithClaims(context.Background(), claims, ""), grpc_middleware.RoleViewer)
+	defaultRole := grpc_middleware.RoleOperator
+
+	resolve := func(plaintext string) grpc_middleware.Role {
+		t.Helper()
+		keyClaims, err := keys.AuthenticateAPIKey(context.Background(), plaintext)
+		if err != nil {
+			t.Fatalf("failed to authenticate key: %v", err)
+		}
+		return grpc_middleware.RoleFromClaims(keyClaims, defaultRole)
+	}
+
+	if _, err := handler.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"operator"}}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a role above the caller's, got %v", err)
+	}
+
+	for _, scopes := range [][]string{nil, {"tasks:read"}} {
+		created, err := handler.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "ci", Scopes: scopes})
+		if err != nil {
+			t.Fatalf("CreateAPIKey with scopes %v failed: %v", scopes, err)
+		}
+		if role := resolve(created.Key); role != grpc_middleware.RoleViewer {
+			t.Errorf("scopes %v: expected key to act as viewer, got %v", scopes, role)
+		}
+	}
+
+	created, err := handler.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "scoped", Scopes: []string{"viewer"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, err := handler.UpdateAPIKey(ctx, &pb.UpdateAPIKeyRequest{Id: created.ApiKey.Id, UpdateScopes: true}); err != nil {
+		t.Fatalf("UpdateAPIKey failed: %v", err)
+	}
+	if role := resolve(created.Key); role != grpc_middleware.RoleViewer {
+		t.Errorf("expected cleared scopes to keep the viewer role, got %v", role)
+	}
+}
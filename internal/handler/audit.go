@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// AuditHandler 审计日志查询处理器，授权拦截器只允许 admin 调用
+type AuditHandler struct {
+	audit *service.AuditService
+	pb.UnimplementedAuditServiceServer
+}
+
+// NewAuditHandler 创建审计日志查询处理器
+func NewAuditHandler(audit *service.AuditService) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// ListAuditEvents 按 seq 倒序分页查询审计事件，verify 为 true 时同时校验哈希链
+func (h *AuditHandler) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	filter := repository.AuditFilter{UserID: req.UserId, Method: req.Method, Resource: req.Resource}
+	if req.Since != nil {
+		since := req.Since.AsTime()
+		filter.Since = &since
+	}
+	if req.Until != nil {
+		until := req.Until.AsTime()
+		filter.Until = &until
+	}
+
+	events, next, err := h.audit.List(ctx, filter, pageSize, req.PageToken)
+	if errors.Is(err, repository.ErrInvalidPageToken) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Audit handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, "failed to list audit events").ToGRPCStatus().Err()
+	}
+
+	resp := &pb.ListAuditEventsResponse{
+		Events:        make([]*pb.AuditEvent, 0, len(events)),
+		NextPageToken: next,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, toPBAuditEvent(event))
+	}
+
+	if req.Verify {
+		length, err := h.audit.Verify(ctx)
+		if err != nil && !errors.Is(err, repository.ErrAuditChainBroken) {
+			logger.Errorf("Audit handler error: %v", err)
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, "failed to verify audit chain").ToGRPCStatus().Err()
+		}
+		resp.ChainValid = err == nil
+		resp.ChainLength = length
+		if err != nil {
+			resp.ChainError = err.Error()
+		}
+	}
+	return resp, nil
+}
+
+// toPBAuditEvent 转换审计事件
+func toPBAuditEvent(e *model.AuditEvent) *pb.AuditEvent {
+	return &pb.AuditEvent{
+		Seq:           e.Seq,
+		Timestamp:     timestamppb.New(e.Timestamp),
+		UserId:        e.UserID,
+		AuthMethod:    e.AuthMethod,
+		Namespace:     e.Namespace,
+		Transport:     e.Transport,
+		Method:        e.Method,
+		Resource:      e.Resource,
+		RequestDigest: e.RequestDigest,
+		Code:          e.Code,
+		PrevHash:      e.PrevHash,
+		Hash:          e.Hash,
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// AuthHandler 认证处理器
+type AuthHandler struct {
+	auth *service.AuthService
+	pb.UnimplementedAuthServiceServer
+}
+
+// NewAuthHandler 创建认证处理器
+func NewAuthHandler(auth *service.AuthService) *AuthHandler {
+	return &AuthHandler{auth: auth}
+}
+
+// Login 用户名密码登录
+func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	if req.Username == "" || req.Password == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "username and password are required").ToGRPCStatus().Err()
+	}
+
+	pair, err := h.auth.Login(ctx, req.Username, req.Password)
+	if err != nil {
+		return nil, authError(err)
+	}
+	return toPBTokenResponse(pair), nil
+}
+
+// RefreshToken 用刷新令牌换取新的令牌对
+func (h *AuthHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "refresh_token is required").ToGRPCStatus().Err()
+	}
+
+	pair, err := h.auth.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, authError(err)
+	}
+	return toPBTokenResponse(pair), nil
+}
+
+// RevokeToken 吊销调用者自己的令牌，未指定时吊销当前访问令牌
+func (h *AuthHandler) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	callerID := grpc_middleware.GetUserID(ctx)
+	if callerID == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeUnauthorized, "authentication required").ToGRPCStatus().Err()
+	}
+
+	token := req.Token
+	if token == "" {
+		token = grpc_middleware.GetToken(ctx)
+	}
+
+	if err := h.auth.Revoke(ctx, token, callerID); err != nil {
+		return nil, authError(err)
+	}
+	return &pb.RevokeTokenResponse{Revoked: true}, nil
+}
+
+// authError 将认证服务错误转换为 gRPC 错误
+func authError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidToken):
+		return errorcode.NewTaskError(errorcode.ErrCodeUnauthorized, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrTokenNotOwned):
+		return errorcode.NewTaskError(errorcode.ErrCodeForbidden, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Errorf("Auth handler error: %v", err)
+	return errorcode.NewTaskError(errorcode.ErrCodeUnknown, "failed to issue token").ToGRPCStatus().Err()
+}
+
+// toPBTokenResponse 转换令牌响应
+func toPBTokenResponse(pair *service.TokenPair) *pb.TokenResponse {
+	return &pb.TokenResponse{
+		AccessToken:      pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(time.Until(pair.AccessExpiresAt).Seconds()),
+		RefreshExpiresIn: int64(time.Until(pair.RefreshExpiresAt).Seconds()),
+	}
+}
@@ -2,25 +2,38 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
 	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
 	"taskflow/internal/model"
 	"taskflow/internal/repository"
+	"taskflow/internal/service"
 	pb "taskflow/proto"
 )
 
 // TaskHandler 任务处理器
 type TaskHandler struct {
 	repo         *repository.TaskRepository
-	watchers     map[string][]chan *pb.TaskChangeEvent
+	tasks        *service.TaskService
+	janitor      *service.Janitor
+	backups      *service.BackupManager
+	bulk         *service.BulkManager
+	namespaces   *service.NamespaceService
+	secrets      *service.SecretCipher
+	watchers     map[string][]*watcher
 	watchersMu   sync.RWMutex
 	taskUpdateCh chan *pb.TaskChangeEvent
+	publishedSeq atomic.Int64 // 已推送给订阅者的最大 outbox seq，中继重试时跳过已推送的记录
 	pb.UnimplementedTaskServiceServer
 }
 
@@ -28,7 +41,10 @@ type TaskHandler struct {
 func NewTaskHandler(repo *repository.TaskRepository) *TaskHandler {
 	h := &TaskHandler{
 		repo:         repo,
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
+		tasks:        service.NewTaskService(repo),
+		janitor:      service.NewJanitor(repo, nil, nil, 0, 0),
+		bulk:         service.NewBulkManager(repo),
+		watchers:     make(map[string][]*watcher),
 		taskUpdateCh: make(chan *pb.TaskChangeEvent, 100),
 	}
 	// 启动任务变更通知循环
@@ -36,12 +52,94 @@ func NewTaskHandler(repo *repository.TaskRepository) *TaskHandler {
 	return h
 }
 
+// SetJanitor 设置清理器（决定 DeleteTask/PurgeTasks 的归档方式），默认直接删除不归档
+func (h *TaskHandler) SetJanitor(janitor *service.Janitor) {
+	h.janitor = janitor
+}
+
+// SetBackupManager 设置备份管理器，未设置时 BackupDatabase 不可用
+func (h *TaskHandler) SetBackupManager(backups *service.BackupManager) {
+	h.backups = backups
+}
+
+// SetBulkManager 设置批量操作管理器，便于服务关闭时统一停止
+func (h *TaskHandler) SetBulkManager(bulk *service.BulkManager) {
+	h.bulk = bulk
+}
+
+// SetNamespaceService 设置命名空间服务，设置后创建任务前检查命名空间存在且未超出配额
+func (h *TaskHandler) SetNamespaceService(namespaces *service.NamespaceService) {
+	h.namespaces = namespaces
+}
+
+// SetSecretCipher 设置机密参数密钥环，未设置时拒绝带 secret_params 的创建请求
+func (h *TaskHandler) SetSecretCipher(secrets *service.SecretCipher) {
+	h.secrets = secrets
+	h.tasks.SetSecretCipher(secrets)
+}
+
+// scoped 返回限定在调用者命名空间内的仓储，所有按请求读写任务的地方都应通过它访问
+func (h *TaskHandler) scoped(ctx context.Context) *repository.TaskRepository {
+	return h.repo.InNamespace(grpc_middleware.GetNamespace(ctx))
+}
+
+// checkQuota 检查调用者的命名空间还能创建 n 个任务
+func (h *TaskHandler) checkQuota(ctx context.Context, n int) error {
+	if h.namespaces == nil {
+		return nil
+	}
+	err := h.namespaces.CheckQuota(ctx, grpc_middleware.GetNamespace(ctx), n)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrNamespaceNotFound):
+		return errorcode.NewTaskError(errorcode.ErrCodeNotFound,
+			fmt.Sprintf("namespace %s not found", grpc_middleware.GetNamespace(ctx))).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return errorcode.NewTaskError(errorcode.ErrCodeRateLimit, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Errorf("Handler error: %v", err)
+	return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+}
+
+// sealSecretParams 加密创建请求中的机密参数，写入任务的 SecretParams
+// 错误信息只包含参数名，不包含参数值
+func (h *TaskHandler) sealSecretParams(task *model.Task, req *pb.CreateTaskRequest) error {
+	if len(req.SecretParams) == 0 {
+		return nil
+	}
+	if h.secrets == nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidState, "secret_params require a secrets key file").ToGRPCStatus().Err()
+	}
+	for name := range req.SecretParams {
+		if name == "" {
+			return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "secret param name is required").ToGRPCStatus().Err()
+		}
+		if _, ok := req.InputParams[name]; ok {
+			return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+				fmt.Sprintf("param %q is set in both input_params and secret_params", name)).ToGRPCStatus().Err()
+		}
+	}
+
+	sealed, err := h.secrets.Seal(task.ID, req.SecretParams)
+	if err != nil {
+		logger.Errorf("Failed to seal secret params of task %s: %v", task.ID, err)
+		return errorcode.NewTaskError(errorcode.ErrCodeUnknown, "failed to encrypt secret params").ToGRPCStatus().Err()
+	}
+	task.SecretParams = sealed
+	return nil
+}
+
 // CreateTask 创建任务
 func (h *TaskHandler) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
 	// 参数验证
 	if req.Name == "" {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
 	}
+	createdBy, err := callerIdentity(ctx, req.CreatedBy, "created_by")
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建任务模型
 	task := model.NewTask(
@@ -52,12 +150,20 @@ func (h *TaskHandler) CreateTask(ctx context.Context, req *pb.CreateTaskRequest)
 		req.InputParams,
 		req.Dependencies,
 		req.MaxRetries,
-		req.CreatedBy,
+		createdBy,
 	)
 	task.ID = uuid.New().String()
+	task.Labels = req.Labels
+	if err := h.sealSecretParams(task, req); err != nil {
+		return nil, err
+	}
+
+	if err := h.checkQuota(ctx, 1); err != nil {
+		return nil, err
+	}
 
 	// 保存到数据库
-	if err := h.repo.Create(task); err != nil {
+	if err := h.scoped(ctx).Create(task); err != nil {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
 
@@ -70,7 +176,7 @@ func (h *TaskHandler) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
 	}
 
-	task, err := h.repo.GetByID(req.Id)
+	task, err := h.scoped(ctx).GetByID(req.Id)
 	if err != nil { logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
@@ -82,53 +188,515 @@ func (h *TaskHandler) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.
 }
 
 // ListTasks 列出任务
+// 未指定 page 时使用游标分页（page_token/next_page_token），否则兼容旧的页码分页
 func (h *TaskHandler) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
 	// 分页参数
 	pageSize := int(req.PageSize)
 	if pageSize <= 0 || pageSize > 100 {
 		pageSize = 20
 	}
-	pageIndex := int(req.Page)
-	if pageIndex < 0 {
-		pageIndex = 0
-	}
-	offset := pageIndex * pageSize
 
 	// 构建过滤条件
+	filter := taskFilterFromRequest(req)
+	filter.PageSize = pageSize
+	filter.PageToken = req.PageToken
+
+	var tasks []*model.Task
+	var total int
+	var nextPageToken string
+
+	if req.PageToken != "" || req.Page <= 0 {
+		page, err := h.scoped(ctx).ListByCursor(filter)
+		if isInvalidListParam(err) {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+		}
+		if err != nil { logger.Errorf("Handler error: %v", err)
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		tasks, total, nextPageToken = page.Tasks, page.Total, page.NextPageToken
+	} else {
+		// 页码从 1 开始
+		filter.PageIndex = int(req.Page) - 1
+		var err error
+		tasks, total, err = h.scoped(ctx).ListByFilter(filter)
+		if isInvalidListParam(err) {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+		}
+		if err != nil { logger.Errorf("Handler error: %v", err)
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+	}
+
+	// 转换
+	pbTasks := make([]*pb.Task, len(tasks))
+	for i, task := range tasks {
+		pbTasks[i] = h.toPBTask(task, false)
+	}
+
+	return &pb.ListTasksResponse{
+		Tasks:         pbTasks,
+		Total:         int32(total),
+		Page:          req.Page,
+		PageSize:      int32(pageSize),
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// DeleteTask 删除任务（按清理器配置先归档）
+// 非终态任务需要 force 才能删除
+func (h *TaskHandler) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	task, err := h.scoped(ctx).GetByID(req.Id)
+	if err != nil { logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if task == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	if err := authorizeTaskWrite(ctx, task); err != nil {
+		return nil, err
+	}
+	if !task.IsTerminal() && !req.Force {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidState,
+			fmt.Sprintf("task is %s, set force to delete a non-terminal task", task.Status)).ToGRPCStatus().Err()
+	}
+
+	if err := h.janitor.DeleteTask(task); err != nil { logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return &pb.DeleteTaskResponse{Id: task.ID, Deleted: true}, nil
+}
+
+// CancelTask 取消任务
+func (h *TaskHandler) CancelTask(ctx context.Context, req *pb.CancelTaskRequest) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	if err := h.checkTaskOwner(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	operator, err := callerIdentity(ctx, req.Operator, "operator")
+	if err != nil {
+		return nil, err
+	}
+	if err := h.tasks.CancelTask(ctx, req.Id, operator); err != nil {
+		return nil, serviceError(err)
+	}
+	return h.GetTask(ctx, &pb.GetTaskRequest{Id: req.Id})
+}
+
+// RetryTask 重试失败的任务，任务重新进入 PENDING
+func (h *TaskHandler) RetryTask(ctx context.Context, req *pb.RetryTaskRequest) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	if err := h.checkTaskOwner(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	operator, err := callerIdentity(ctx, req.Operator, "operator")
+	if err != nil {
+		return nil, err
+	}
+	if err := h.tasks.RetryTask(ctx, req.Id, operator); err != nil {
+		return nil, serviceError(err)
+	}
+	return h.GetTask(ctx, &pb.GetTaskRequest{Id: req.Id})
+}
+
+// authorizeTaskWrite submitter 只能修改自己创建的任务，operator 及以上不受限制
+// 未启用授权（上下文中没有角色）时不检查
+func authorizeTaskWrite(ctx context.Context, task *model.Task) error {
+	role := grpc_middleware.GetRole(ctx)
+	if role == grpc_middleware.RoleNone || role >= grpc_middleware.RoleOperator {
+		return nil
+	}
+	if task.CreatedBy != grpc_middleware.GetUserID(ctx) {
+		return errorcode.NewTaskError(errorcode.ErrCodeForbidden,
+			fmt.Sprintf("task %s was created by another user", task.ID)).ToGRPCStatus().Err()
+	}
+	return nil
+}
+
+// callerIdentity 返回写入 created_by 或事件 operator 的身份
+// 认证后取调用者的 user ID，只有 admin 可以代他人填写；未启用认证时沿用请求中的值
+func callerIdentity(ctx context.Context, claimed, field string) (string, error) {
+	userID := grpc_middleware.GetUserID(ctx)
+	if userID == "" {
+		return claimed, nil
+	}
+	if claimed == "" || claimed == userID {
+		return userID, nil
+	}
+	if grpc_middleware.GetRole(ctx) != grpc_middleware.RoleAdmin {
+		return "", errorcode.NewTaskError(errorcode.ErrCodeForbidden,
+			fmt.Sprintf("%s must match the caller's identity", field)).ToGRPCStatus().Err()
+	}
+	return claimed, nil
+}
+
+// checkTaskOwner 在调用者的命名空间中按 ID 读取任务并检查所有权
+// 服务层按 ID 操作不区分命名空间，其他命名空间的任务必须在这里以 NotFound 拒绝
+func (h *TaskHandler) checkTaskOwner(ctx context.Context, id string) error {
+	task, err := h.scoped(ctx).GetByID(id)
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if task == nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	return authorizeTaskWrite(ctx, task)
+}
+
+// serviceError 将服务层错误转换为 gRPC 错误
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		return errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrTaskTerminated):
+		return errorcode.NewTaskError(errorcode.ErrCodeTaskTerminated, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrTaskNotRetryable):
+		return errorcode.NewTaskError(errorcode.ErrCodeTaskRetryExhausted, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, repository.ErrStatusMismatch):
+		// 状态在读取后被并发修改，或状态机不允许该转换
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidState, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Errorf("Handler error: %v", err)
+	return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+}
+
+// BulkCancel 批量取消任务
+func (h *TaskHandler) BulkCancel(ctx context.Context, req *pb.BulkTaskRequest) (*pb.BulkOperation, error) {
+	return h.startBulk(ctx, service.BulkActionCancel, req.Ids, req.Filter, req.DryRun, req.Operator, 0)
+}
+
+// BulkRetry 批量重试失败的任务
+func (h *TaskHandler) BulkRetry(ctx context.Context, req *pb.BulkTaskRequest) (*pb.BulkOperation, error) {
+	return h.startBulk(ctx, service.BulkActionRetry, req.Ids, req.Filter, req.DryRun, req.Operator, 0)
+}
+
+// BulkSetPriority 批量调整未完成任务的优先级
+func (h *TaskHandler) BulkSetPriority(ctx context.Context, req *pb.BulkSetPriorityRequest) (*pb.BulkOperation, error) {
+	return h.startBulk(ctx, service.BulkActionSetPriority, req.Ids, req.Filter, req.DryRun, req.Operator, model.TaskPriority(req.Priority))
+}
+
+// startBulk 启动批量操作，返回初始进度
+func (h *TaskHandler) startBulk(ctx context.Context, action service.BulkAction, ids []string, filterReq *pb.ListTasksRequest, dryRun bool, operator string, priority model.TaskPriority) (*pb.BulkOperation, error) {
+	operator, err := callerIdentity(ctx, operator, "operator")
+	if err != nil {
+		return nil, err
+	}
+	req := service.BulkRequest{
+		Action:    action,
+		IDs:       ids,
+		Priority:  priority,
+		Operator:  operator,
+		DryRun:    dryRun,
+		Namespace: grpc_middleware.GetNamespace(ctx),
+	}
+	if filterReq != nil {
+		filter := taskFilterFromRequest(filterReq)
+		// 排序只影响处理顺序，统一按创建时间处理
+		filter.SortBy, filter.SortDesc = "", false
+		req.Filter = &filter
+	}
+
+	op, err := h.bulk.Start(ctx, req)
+	if errors.Is(err, service.ErrBulkNoSelector) || errors.Is(err, service.ErrBulkTooLarge) ||
+		errors.Is(err, service.ErrBulkInvalidPriority) || isInvalidListParam(err) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if err != nil { logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return toPBBulkOperation(op), nil
+}
+
+// GetBulkOperation 查询批量操作进度
+func (h *TaskHandler) GetBulkOperation(ctx context.Context, req *pb.GetBulkOperationRequest) (*pb.BulkOperation, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	op := h.bulk.Get(req.Id)
+	if op == nil || op.Namespace != grpc_middleware.GetNamespace(ctx) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "bulk operation not found").ToGRPCStatus().Err()
+	}
+	return toPBBulkOperation(op), nil
+}
+
+// toPBBulkOperation 转换批量操作进度
+func toPBBulkOperation(op *service.BulkOperation) *pb.BulkOperation {
+	pbOp := &pb.BulkOperation{
+		Id:        op.ID,
+		Action:    string(op.Action),
+		State:     string(op.State),
+		DryRun:    op.DryRun,
+		Total:     int32(op.Total),
+		Affected:  int32(op.Affected),
+		Processed: int32(op.Processed),
+		Succeeded: int32(op.Succeeded),
+		Failed:    int32(op.Failed),
+		Skipped:   int32(op.Skipped),
+		Operator:  op.Operator,
+		Namespace: op.Namespace,
+		CreatedAt: op.CreatedAt.Unix(),
+	}
+	if op.FinishedAt != nil {
+		pbOp.FinishedAt = op.FinishedAt.Unix()
+	}
+	for _, itemErr := range op.Errors {
+		pbOp.Errors = append(pbOp.Errors, &pb.BulkItemError{TaskId: itemErr.TaskID, Error: itemErr.Error})
+	}
+	return pbOp
+}
+
+// PurgeTasks 按条件批量清理终态任务
+func (h *TaskHandler) PurgeTasks(ctx context.Context, req *pb.PurgeTasksRequest) (*pb.PurgeTasksResponse, error) {
+	if req.OlderThanSeconds < 0 || req.Limit < 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "older_than_seconds and limit must be non-negative").ToGRPCStatus().Err()
+	}
+
+	// 清理只作用于调用者当前的命名空间，admin 可通过 x-namespace 切换
+	filter := repository.TaskFilter{
+		TaskTypes:  req.TaskTypes,
+		Expression: req.Filter,
+		Namespace:  grpc_middleware.GetNamespace(ctx),
+	}
+	for _, status := range req.Statuses {
+		filter.Statuses = append(filter.Statuses, model.TaskStatus(status))
+	}
+	if req.OlderThanSeconds > 0 {
+		before := time.Now().Add(-time.Duration(req.OlderThanSeconds) * time.Second)
+		filter.FinishedBefore = &before
+	}
+
+	result, err := h.janitor.Purge(ctx, filter, req.DryRun, int(req.Limit))
+	if errors.Is(err, service.ErrNonTerminalPurge) || errors.Is(err, repository.ErrInvalidFilter) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if err != nil { logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	if !result.DryRun && result.Deleted > 0 {
+		metrics.RecordTasksPurged("admin", result.Deleted)
+		logger.Infof("Purged %d tasks", result.Deleted)
+	}
+
+	return &pb.PurgeTasksResponse{
+		Matched: int32(result.Matched),
+		Deleted: int32(result.Deleted),
+		DryRun:  result.DryRun,
+	}, nil
+}
+
+// BackupDatabase 生成数据库在线快照
+func (h *TaskHandler) BackupDatabase(ctx context.Context, req *pb.BackupDatabaseRequest) (*pb.BackupDatabaseResponse, error) {
+	if h.backups == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeGRPCNotReady, "backup is not configured").ToGRPCStatus().Err()
+	}
+
+	info, err := h.backups.Backup(ctx)
+	if err != nil { logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return &pb.BackupDatabaseResponse{
+		Path:      info.Path,
+		SizeBytes: info.Size,
+		CreatedAt: info.CreatedAt.Unix(),
+	}, nil
+}
+
+// taskFilterFromRequest 将 ListTasksRequest 转换为仓储过滤条件（不含分页参数）
+func taskFilterFromRequest(req *pb.ListTasksRequest) repository.TaskFilter {
 	filter := repository.TaskFilter{
-		PageSize:  pageSize,
-		PageIndex: offset,
 		Keyword:   req.Keyword,
 		TaskType:  req.TaskType,
+		TaskTypes: req.TaskTypes,
+		CreatedBy: req.CreatedBy,
+		HasError:  req.HasError,
+		Labels:    req.Labels,
+		SortBy:    req.SortBy,
+		SortDesc:  req.SortDesc,
+
+		Expression: req.Filter,
 	}
 
-	if len(req.StatusFilter) > 0 {
-		status := model.TaskStatus(req.StatusFilter[0])
-		filter.Status = &status
+	for _, status := range req.StatusFilter {
+		filter.Statuses = append(filter.Statuses, model.TaskStatus(status))
 	}
 	if req.Priority != 0 {
 		priority := model.TaskPriority(req.Priority)
 		filter.Priority = &priority
 	}
 
-	// 查询
-	tasks, total, err := h.repo.ListByFilter(filter)
+	filter.CreatedAfter = unixTime(req.CreatedAfter)
+	filter.CreatedBefore = unixTime(req.CreatedBefore)
+	filter.UpdatedAfter = unixTime(req.UpdatedAfter)
+	filter.UpdatedBefore = unixTime(req.UpdatedBefore)
+	filter.CompletedAfter = unixTime(req.CompletedAfter)
+	filter.CompletedBefore = unixTime(req.CompletedBefore)
+
+	return filter
+}
+
+// isInvalidListParam 是否为客户端传入的列表参数错误
+func isInvalidListParam(err error) bool {
+	return errors.Is(err, repository.ErrInvalidPageToken) ||
+		errors.Is(err, repository.ErrInvalidSortField) ||
+		errors.Is(err, repository.ErrInvalidFilter)
+}
+
+// unixTime 将 Unix 秒转换为时间，0 表示未设置
+func unixTime(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0)
+	return &t
+}
+
+// ListTaskEvents 分页获取任务事件
+func (h *TaskHandler) ListTaskEvents(ctx context.Context, req *pb.ListTaskEventsRequest) (*pb.ListTaskEventsResponse, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	events, nextPageToken, err := h.scoped(ctx).ListEventsByTaskID(req.TaskId, pageSize, req.PageToken)
+	if errors.Is(err, repository.ErrInvalidPageToken) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
 	if err != nil { logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
 
-	// 转换
-	pbTasks := make([]*pb.Task, len(tasks))
-	for i, task := range tasks {
-		pbTasks[i] = h.toPBTask(task, false)
+	resp := &pb.ListTaskEventsResponse{NextPageToken: nextPageToken}
+	for _, e := range events {
+		resp.Events = append(resp.Events, toPBEvent(e))
 	}
 
-	return &pb.ListTasksResponse{
-		Tasks:    pbTasks,
-		Total:    int32(total),
-		Page:     req.Page,
-		PageSize: req.PageSize,
-	}, nil
+	return resp, nil
+}
+
+const (
+	// defaultWaitTimeout WaitTask 未指定超时时的等待时间
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout WaitTask 最长等待时间
+	maxWaitTimeout = 5 * time.Minute
+)
+
+// terminalStatuses 未指定目标状态时 WaitTask 等待的终态
+var terminalStatuses = []pb.TaskStatus{
+	pb.TaskStatus_TASK_STATUS_SUCCEEDED,
+	pb.TaskStatus_TASK_STATUS_FAILED,
+	pb.TaskStatus_TASK_STATUS_CANCELLED,
+	pb.TaskStatus_TASK_STATUS_TIMEOUT,
+}
+
+// WaitTask 阻塞等待任务进入终态或指定状态
+// 基于订阅者机制等待变更，不轮询数据库；超时返回当前状态并标记 timed_out
+func (h *TaskHandler) WaitTask(ctx context.Context, req *pb.WaitTaskRequest) (*pb.WaitTaskResponse, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+	if req.TimeoutMs < 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "timeout_ms must not be negative").ToGRPCStatus().Err()
+	}
+
+	timeout := defaultWaitTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	if timeout > maxWaitTimeout {
+		timeout = maxWaitTimeout
+	}
+
+	targets := req.TargetStatuses
+	if len(targets) == 0 {
+		targets = terminalStatuses
+	}
+	reached := func(status pb.TaskStatus) bool {
+		for _, s := range targets {
+			if s == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 先订阅再读库，读库之后发生的变更不会丢失
+	ids := []string{req.Id}
+	w := h.addWatcher(ids, nil)
+	defer h.removeWatcher(ids, w)
+
+	repo := h.scoped(ctx)
+	current := func() (*pb.Task, error) {
+		task, err := repo.GetByID(req.Id)
+		if err != nil {
+			logger.Errorf("Handler error: %v", err)
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		if task == nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+		}
+		return h.toPBTask(task, false), nil
+	}
+
+	task, err := current()
+	if err != nil {
+		return nil, err
+	}
+	if reached(task.Status) {
+		return &pb.WaitTaskResponse{Task: task}, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeTimeout, ctx.Err().Error()).ToGRPCStatus().Err()
+		case <-timer.C:
+			task, err := current()
+			if err != nil {
+				return nil, err
+			}
+			return &pb.WaitTaskResponse{Task: task, TimedOut: !reached(task.Status)}, nil
+		case <-w.overflow:
+			// 丢失了变更，以数据库中的状态为准
+			task, err := current()
+			if err != nil {
+				return nil, err
+			}
+			if reached(task.Status) {
+				return &pb.WaitTaskResponse{Task: task}, nil
+			}
+		case event := <-w.ch:
+			if event.ChangeType == model.ChangeTypeDeleted {
+				return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task deleted").ToGRPCStatus().Err()
+			}
+			if reached(event.ToStatus) && event.Task != nil {
+				return &pb.WaitTaskResponse{Task: event.Task}, nil
+			}
+		}
+	}
 }
 
 // UpdateTask 更新任务
@@ -138,13 +706,16 @@ func (h *TaskHandler) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest)
 	}
 
 	// 获取现有任务
-	task, err := h.repo.GetByID(req.Id)
+	task, err := h.scoped(ctx).GetByID(req.Id)
 	if err != nil { logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
 	if task == nil {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
 	}
+	if err := authorizeTaskWrite(ctx, task); err != nil {
+		return nil, err
+	}
 
 	// 更新字段
 	if req.Status != 0 {
@@ -158,7 +729,11 @@ func (h *TaskHandler) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest)
 		}
 
 		// 原子更新状态
-		err := h.repo.UpdateStatusWithEvent(req.Id, oldStatus, newStatus, "system", "status updated")
+		operator := grpc_middleware.GetUserID(ctx)
+		if operator == "" {
+			operator = "system"
+		}
+		err := h.scoped(ctx).UpdateStatusWithEvent(req.Id, oldStatus, newStatus, operator, "status updated")
 		if err != nil { logger.Errorf("Handler error: %v", err)
 			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 		}
@@ -177,7 +752,7 @@ func (h *TaskHandler) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest)
 	task.UpdatedAt = time.Now()
 
 	// 保存
-	if err := h.repo.Update(task); err != nil {
+	if err := h.scoped(ctx).Update(task); err != nil {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
 
@@ -219,31 +794,84 @@ func (h *TaskHandler) toPBTask(task *model.Task, includeEvents bool) *pb.Task {
 		CreatedAt:    task.CreatedAt.Unix(),
 		UpdatedAt:    task.UpdatedAt.Unix(),
 		CreatedBy:    task.CreatedBy,
+		Namespace:    task.Namespace,
+		Labels:       task.Labels,
+		SecretParams: task.RedactedSecretParams(),
+		CreateTime:   timestamppb.New(task.CreatedAt),
+		UpdateTime:   timestamppb.New(task.UpdatedAt),
 	}
 
 	if task.StartedAt != nil {
 		pbTask.StartedAt = task.StartedAt.Unix()
+		pbTask.StartTime = timestamppb.New(*task.StartedAt)
 	}
 	if task.CompletedAt != nil {
 		pbTask.CompletedAt = task.CompletedAt.Unix()
+		pbTask.CompleteTime = timestamppb.New(*task.CompletedAt)
 	}
 
 	if includeEvents {
 		for _, e := range task.Events {
-			pbTask.Events = append(pbTask.Events, &pb.TaskEvent{
-				Id:         e.ID,
-				FromStatus: pb.TaskStatus(e.FromStatus),
-				ToStatus:   pb.TaskStatus(e.ToStatus),
-				Message:    e.Message,
-				Timestamp:  e.Timestamp.Unix(),
-				Operator:   e.Operator,
-			})
+			pbTask.Events = append(pbTask.Events, toPBEvent(e))
 		}
 	}
 
 	return pbTask
 }
 
+// fromPBTask 将 Protobuf 任务转换为模型（不含事件），用于过滤表达式匹配
+func fromPBTask(t *pb.Task) *model.Task {
+	task := &model.Task{
+		ID:           t.Id,
+		Name:         t.Name,
+		Description:  t.Description,
+		Status:       model.TaskStatus(t.Status),
+		Priority:     model.TaskPriority(t.Priority),
+		TaskType:     t.TaskType,
+		InputParams:  t.InputParams,
+		OutputResult: t.OutputResult,
+		Dependencies: t.Dependencies,
+		RetryCount:   t.RetryCount,
+		MaxRetries:   t.MaxRetries,
+		ErrorMessage: t.ErrorMessage,
+		CreatedAt:    pbTime(t.CreateTime, t.CreatedAt),
+		UpdatedAt:    pbTime(t.UpdateTime, t.UpdatedAt),
+		CreatedBy:    t.CreatedBy,
+		Namespace:    t.Namespace,
+		Labels:       t.Labels,
+	}
+	if t.StartTime != nil || t.StartedAt != 0 {
+		startedAt := pbTime(t.StartTime, t.StartedAt)
+		task.StartedAt = &startedAt
+	}
+	if t.CompleteTime != nil || t.CompletedAt != 0 {
+		completedAt := pbTime(t.CompleteTime, t.CompletedAt)
+		task.CompletedAt = &completedAt
+	}
+	return task
+}
+
+// pbTime 优先使用 Timestamp 字段，未设置时回退到 Unix 秒字段
+func pbTime(ts *timestamppb.Timestamp, legacy int64) time.Time {
+	if ts != nil {
+		return ts.AsTime()
+	}
+	return time.Unix(legacy, 0)
+}
+
+// toPBEvent 转换为 Protobuf 任务事件
+func toPBEvent(e model.TaskEvent) *pb.TaskEvent {
+	return &pb.TaskEvent{
+		Id:         e.ID,
+		FromStatus: pb.TaskStatus(e.FromStatus),
+		ToStatus:   pb.TaskStatus(e.ToStatus),
+		Message:    e.Message,
+		Timestamp:  e.Timestamp.Unix(),
+		Operator:   e.Operator,
+		EventTime:  timestamppb.New(e.Timestamp),
+	}
+}
+
 // RegisterTaskHandlers 注册任务服务句柄
 func RegisterTaskHandlers(repo *repository.TaskRepository) *TaskHandler {
 	return NewTaskHandler(repo)
@@ -251,6 +879,95 @@ func RegisterTaskHandlers(repo *repository.TaskRepository) *TaskHandler {
 
 // ========== 流式 RPC 实现 ==========
 
+const (
+	// changeTypeInitial include_initial 推送的当前状态
+	changeTypeInitial = "initial"
+	// changeTypeOverflow 订阅者消费过慢丢失了变更，可用事件中的 sequence 续传
+	changeTypeOverflow = "overflow"
+	// changeTypeResync 请求的序号已不在保留的变更记录中，需要重新加载任务后从事件中的 sequence 续传
+	changeTypeResync = "resync"
+
+	// watcherBufferSize 每个订阅者的缓冲大小，写满即视为溢出
+	watcherBufferSize = 64
+	// replayBatchSize 续传回放每批读取的记录数
+	replayBatchSize = 100
+)
+
+// watcher 变更订阅者，缓冲写满时不再静默丢弃，而是通过 overflow 通知消费方
+type watcher struct {
+	ch       chan *pb.TaskChangeEvent
+	overflow chan struct{}
+	matcher  *watchMatcher // 为 nil 时接收全部变更
+}
+
+// newWatcher 创建订阅者
+func newWatcher(size int) *watcher {
+	return &watcher{
+		ch:       make(chan *pb.TaskChangeEvent, size),
+		overflow: make(chan struct{}, 1),
+	}
+}
+
+// send 非阻塞投递，缓冲已满时标记溢出
+func (w *watcher) send(event *pb.TaskChangeEvent) {
+	select {
+	case w.ch <- event:
+	default:
+		select {
+		case w.overflow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// addWatcher 注册订阅者：指定任务 ID 时只挂在这些 ID 下，否则挂在全局列表（key 为空）中
+func (h *TaskHandler) addWatcher(taskIDs []string, m *watchMatcher) *watcher {
+	w := newWatcher(watcherBufferSize)
+	w.matcher = m
+
+	h.watchersMu.Lock()
+	defer h.watchersMu.Unlock()
+	for _, key := range watchKeys(taskIDs) {
+		h.watchers[key] = append(h.watchers[key], w)
+	}
+	return w
+}
+
+// removeWatcher 注销订阅者
+func (h *TaskHandler) removeWatcher(taskIDs []string, w *watcher) {
+	h.watchersMu.Lock()
+	defer h.watchersMu.Unlock()
+
+	for _, key := range watchKeys(taskIDs) {
+		chs := h.watchers[key]
+		for i, c := range chs {
+			if c == w {
+				h.watchers[key] = append(chs[:i], chs[i+1:]...)
+				break
+			}
+		}
+		if len(h.watchers[key]) == 0 {
+			delete(h.watchers, key)
+		}
+	}
+}
+
+// watchKeys 订阅索引的 key，重复 ID 只保留一个
+func watchKeys(taskIDs []string) []string {
+	if len(taskIDs) == 0 {
+		return []string{""}
+	}
+	seen := make(map[string]bool, len(taskIDs))
+	keys := make([]string, 0, len(taskIDs))
+	for _, id := range taskIDs {
+		if !seen[id] {
+			seen[id] = true
+			keys = append(keys, id)
+		}
+	}
+	return keys
+}
+
 // taskUpdateNotifier 任务变更通知器
 func (h *TaskHandler) taskUpdateNotifier() {
 	for event := range h.taskUpdateCh {
@@ -259,120 +976,315 @@ func (h *TaskHandler) taskUpdateNotifier() {
 }
 
 // notifyWatchers 通知所有订阅者
+// 按任务 ID 索引找到订阅者并在入队前完成过滤，任务只转换一次，避免每个订阅者重复解析
 func (h *TaskHandler) notifyWatchers(event *pb.TaskChangeEvent) {
+	var task *model.Task
+	if event.Task != nil {
+		task = fromPBTask(event.Task)
+	}
+
 	h.watchersMu.RLock()
 	defer h.watchersMu.RUnlock()
 
-	if chs, ok := h.watchers[event.TaskId]; ok {
-		for _, ch := range chs {
-			select {
-			case ch <- event:
-			default:
+	for _, key := range []string{event.TaskId, ""} {
+		for _, w := range h.watchers[key] {
+			if w.matcher.match(event, task) {
+				w.send(event)
 			}
 		}
 	}
+}
 
-	if globalChs, ok := h.watchers[""]; ok {
-		for _, ch := range globalChs {
-			select {
-			case ch <- event:
-			default:
-			}
+// Publish 实现 service.OutboxSink：将 outbox 记录推送给 WatchTask 订阅者
+// 中继重启或重试时可能再次投递已推送过的记录，seq 不大于已推送位置的直接丢弃
+func (h *TaskHandler) Publish(entry *model.OutboxEntry) error {
+	for {
+		last := h.publishedSeq.Load()
+		if entry.Seq <= last {
+			return nil
+		}
+		if h.publishedSeq.CompareAndSwap(last, entry.Seq) {
+			break
 		}
 	}
+	if entry.Task == nil {
+		return nil
+	}
+	h.taskUpdateCh <- h.outboxEvent(entry)
+	return nil
 }
 
-// broadcastTaskChange 广播任务变更
-func (h *TaskHandler) broadcastTaskChange(taskId string, task *model.Task, fromStatus, toStatus model.TaskStatus, changeType string) {
-	event := &pb.TaskChangeEvent{
-		TaskId:     taskId,
-		Task:       h.toPBTask(task, false),
-		FromStatus: pb.TaskStatus(fromStatus),
-		ToStatus:   pb.TaskStatus(toStatus),
-		ChangedAt:  time.Now().Unix(),
+// outboxEvent 将 outbox 记录转换为变更事件
+func (h *TaskHandler) outboxEvent(entry *model.OutboxEntry) *pb.TaskChangeEvent {
+	return &pb.TaskChangeEvent{
+		TaskId:     entry.TaskID,
+		Task:       h.toPBTask(entry.Task, false),
+		FromStatus: pb.TaskStatus(entry.FromStatus),
+		ToStatus:   pb.TaskStatus(entry.ToStatus),
+		ChangedAt:  entry.CreatedAt.Unix(),
+		ChangeType: entry.ChangeType,
+		ChangeTime: timestamppb.New(entry.CreatedAt),
+		Sequence:   entry.Seq,
+	}
+}
+
+// controlEvent 构造 overflow/resync 控制事件，sequence 为客户端续传时应使用的序号
+func controlEvent(changeType string, sequence int64) *pb.TaskChangeEvent {
+	now := time.Now()
+	return &pb.TaskChangeEvent{
+		ChangedAt:  now.Unix(),
 		ChangeType: changeType,
+		ChangeTime: timestamppb.New(now),
+		Sequence:   sequence,
+	}
+}
+
+// workflowLabel 标识任务所属工作流的标签
+const workflowLabel = "workflow"
+
+// watchMatcher WatchTask 的事件过滤条件，各条件之间为 AND，同一条件内多值为 OR
+type watchMatcher struct {
+	taskIDs   map[string]bool
+	statuses  map[pb.TaskStatus]bool
+	taskTypes map[string]bool
+	creators  map[string]bool
+	labels    map[string]string // 标签需全部匹配
+	expr      *repository.FilterExpr
+	namespace string // 只推送该命名空间的任务，为空时不限制
+}
+
+// newWatchMatcher 根据请求构建过滤条件
+func newWatchMatcher(req *pb.WatchTaskRequest) (*watchMatcher, error) {
+	expr, err := repository.ParseFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &watchMatcher{
+		taskIDs:   stringSet(req.TaskIds),
+		taskTypes: stringSet(req.TaskTypes),
+		creators:  stringSet(req.CreatedBy),
+		expr:      expr,
+	}
+	if len(req.StatusFilter) > 0 {
+		m.statuses = make(map[pb.TaskStatus]bool, len(req.StatusFilter))
+		for _, s := range req.StatusFilter {
+			m.statuses[s] = true
+		}
+	}
+	if len(req.Labels) > 0 || req.Workflow != "" {
+		m.labels = make(map[string]string, len(req.Labels)+1)
+		for k, v := range req.Labels {
+			m.labels[k] = v
+		}
+		if req.Workflow != "" {
+			if v, ok := m.labels[workflowLabel]; ok && v != req.Workflow {
+				return nil, fmt.Errorf("workflow %q conflicts with label %s=%q", req.Workflow, workflowLabel, v)
+			}
+			m.labels[workflowLabel] = req.Workflow
+		}
+	}
+	return m, nil
+}
+
+// stringSet 转换为集合，空切片返回 nil（不过滤）
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// match 判断事件是否需要推送，task 为事件中任务的模型形式（可能为 nil）
+func (m *watchMatcher) match(event *pb.TaskChangeEvent, task *model.Task) bool {
+	if m == nil {
+		return true
+	}
+	if m.namespace != "" && (task == nil || task.Namespace != m.namespace) {
+		return false
+	}
+	if m.taskIDs != nil && !m.taskIDs[event.TaskId] {
+		return false
+	}
+	if m.statuses != nil && !m.statuses[event.ToStatus] {
+		return false
 	}
-	h.taskUpdateCh <- event
+	if m.taskTypes == nil && m.creators == nil && m.labels == nil && m.expr == nil {
+		return true
+	}
+	if task == nil {
+		return false
+	}
+	if m.taskTypes != nil && !m.taskTypes[task.TaskType] {
+		return false
+	}
+	if m.creators != nil && !m.creators[task.CreatedBy] {
+		return false
+	}
+	for k, v := range m.labels {
+		if task.Labels[k] != v {
+			return false
+		}
+	}
+	return m.expr.Match(task)
+}
+
+// matchTask 判断任务当前状态是否满足条件（用于 include_initial）
+func (m *watchMatcher) matchTask(task *model.Task) bool {
+	event := &pb.TaskChangeEvent{TaskId: task.ID, ToStatus: pb.TaskStatus(task.Status)}
+	return m.match(event, task)
+}
+
+// replayOutbox 回放 sequence 大于 after 的变更，返回最后回放的序号
+// 所需记录已被清理时返回 ok=false，调用方应通知客户端重新同步
+func (h *TaskHandler) replayOutbox(send func(*pb.TaskChangeEvent) error, m *watchMatcher, after int64) (last int64, ok bool, err error) {
+	oldest, latest, err := h.repo.OutboxBounds()
+	if err != nil {
+		return after, false, err
+	}
+	if after > latest || (after < latest && (oldest == 0 || oldest > after+1)) {
+		return latest, false, nil
+	}
+
+	last = after
+	for {
+		entries, err := h.repo.ListOutboxSince(last, replayBatchSize)
+		if err != nil {
+			return last, false, err
+		}
+		for _, entry := range entries {
+			last = entry.Seq
+			if !m.match(&pb.TaskChangeEvent{TaskId: entry.TaskID, ToStatus: pb.TaskStatus(entry.ToStatus)}, entry.Task) {
+				continue
+			}
+			if err := send(h.outboxEvent(entry)); err != nil {
+				return last, false, err
+			}
+		}
+		if len(entries) < replayBatchSize {
+			return last, true, nil
+		}
+	}
+}
+
+// ValidateWatchRequest 校验订阅请求，供 HTTP 端点在开始推送前返回参数错误
+func ValidateWatchRequest(req *pb.WatchTaskRequest) error {
+	_, err := watchMatcherFromRequest(req)
+	return err
+}
+
+// watchMatcherFromRequest 校验请求并构建过滤条件
+func watchMatcherFromRequest(req *pb.WatchTaskRequest) (*watchMatcher, error) {
+	if req.ResumeFromSequence < 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "resume_from_sequence must not be negative").ToGRPCStatus().Err()
+	}
+	m, err := newWatchMatcher(req)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	return m, nil
 }
 
 // WatchTask 服务端流式 - 监听任务状态变化
 func (h *TaskHandler) WatchTask(req *pb.WatchTaskRequest, stream pb.TaskService_WatchTaskServer) error {
-	ch := make(chan *pb.TaskChangeEvent, 10)
-	taskIDs := req.TaskIds
+	return h.Watch(stream.Context(), req, stream.Send)
+}
 
-	h.watchersMu.Lock()
-	watchKey := ""
-	if len(taskIDs) == 1 {
-		watchKey = taskIDs[0]
+// Watch 订阅任务变更并通过 send 推送，直到 ctx 结束或 send 失败
+// WatchTask 与 HTTP 的 SSE、WebSocket 共用此实现，过滤与续传语义一致
+func (h *TaskHandler) Watch(ctx context.Context, req *pb.WatchTaskRequest, send func(*pb.TaskChangeEvent) error) error {
+	m, err := watchMatcherFromRequest(req)
+	if err != nil {
+		return err
 	}
-	h.watchers[watchKey] = append(h.watchers[watchKey], ch)
-	h.watchersMu.Unlock()
+	m.namespace = grpc_middleware.GetNamespace(ctx)
+	repo := h.scoped(ctx)
+
+	// 先注册再回放，回放期间的实时变更留在缓冲中，按序号去重
+	w := h.addWatcher(req.TaskIds, m)
+	defer h.removeWatcher(req.TaskIds, w)
 
 	if req.IncludeInitial {
 		var tasks []*model.Task
-		if len(taskIDs) > 0 {
-			for _, id := range taskIDs {
-				task, err := h.repo.GetByID(id)
+		if len(req.TaskIds) > 0 {
+			for id := range m.taskIDs {
+				task, err := repo.GetByID(id)
 				if err == nil && task != nil {
 					tasks = append(tasks, task)
 				}
 			}
 		} else {
-			tasks, _, _ = h.repo.ListByFilter(repository.TaskFilter{PageSize: 50, PageIndex: 0})
+			tasks, _, _ = repo.ListByFilter(repository.TaskFilter{
+				TaskTypes:  req.TaskTypes,
+				Labels:     m.labels,
+				Expression: req.Filter,
+				PageSize:   50,
+				PageIndex:  0,
+			})
 		}
 
 		for _, task := range tasks {
+			if !m.matchTask(task) {
+				continue
+			}
 			event := &pb.TaskChangeEvent{
 				TaskId:     task.ID,
 				Task:       h.toPBTask(task, false),
 				FromStatus: pb.TaskStatus(task.Status),
 				ToStatus:   pb.TaskStatus(task.Status),
 				ChangedAt:  task.UpdatedAt.Unix(),
-				ChangeType: "initial",
+				ChangeType: changeTypeInitial,
+				ChangeTime: timestamppb.New(task.UpdatedAt),
+			}
+			if err := send(event); err != nil {
+				return err
 			}
-			stream.Send(event)
 		}
 	}
 
-	ctx := stream.Context()
-	defer func() {
-		h.watchersMu.Lock()
-		if chs, ok := h.watchers[watchKey]; ok {
-			for i, c := range chs {
-				if c == ch {
-					h.watchers[watchKey] = append(chs[:i], chs[i+1:]...)
-					break
-				}
-			}
+	var lastSeq int64
+	if req.ResumeFromSequence > 0 {
+		last, ok, err := h.replayOutbox(send, m, req.ResumeFromSequence)
+		if err != nil {
+			logger.Errorf("Handler error: %v", err)
+			return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 		}
-		h.watchersMu.Unlock()
-		close(ch)
-	}()
+		if !ok {
+			return send(controlEvent(changeTypeResync, last))
+		}
+		lastSeq = last
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case event := <-ch:
-			if len(req.StatusFilter) > 0 {
-				filtered := true
-				for _, s := range req.StatusFilter {
-					if event.ToStatus == s {
-						filtered = false
-						break
-					}
-				}
-				if filtered {
+		case <-w.overflow:
+			// 缓冲已满丢失了变更：告知客户端从最后送达的序号续传
+			metrics.RecordWatchOverflow("watch")
+			return send(controlEvent(changeTypeOverflow, lastSeq))
+		case event := <-w.ch:
+			if event.Sequence > 0 {
+				if event.Sequence <= lastSeq {
 					continue
 				}
+				lastSeq = event.Sequence
+			}
+			if err := send(event); err != nil {
+				return err
 			}
-			stream.Send(event)
 		}
 	}
 }
 
 // BatchCreateTasks 客户端流式 - 批量创建任务
 func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksServer) error {
+	ctx := stream.Context()
+	repo := h.scoped(ctx)
 	var tasks []*pb.Task
 	var errors []string
 	successCount := 0
@@ -391,6 +1303,14 @@ func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksSer
 			continue
 		}
 
+		createdBy, err := callerIdentity(ctx, req.CreatedBy, "created_by")
+		if err != nil {
+			failedCount++
+			errors = append(errors, err.Error())
+			tasks = append(tasks, nil)
+			continue
+		}
+
 		task := model.NewTask(
 			req.Name,
 			req.Description,
@@ -399,18 +1319,29 @@ func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksSer
 			req.InputParams,
 			req.Dependencies,
 			req.MaxRetries,
-			req.CreatedBy,
+			createdBy,
 		)
 		task.ID = uuid.New().String()
-
-		if err := h.repo.Create(task); err != nil {
+		task.Labels = req.Labels
+		if err := h.sealSecretParams(task, req); err != nil {
 			failedCount++
 			errors = append(errors, err.Error())
 			tasks = append(tasks, nil)
 			continue
 		}
 
-		h.broadcastTaskChange(task.ID, task, model.TaskStatusUnspecified, model.TaskStatusPending, "created")
+		if err := h.checkQuota(ctx, 1); err != nil {
+			failedCount++
+			errors = append(errors, err.Error())
+			tasks = append(tasks, nil)
+			continue
+		}
+		if err := repo.Create(task); err != nil {
+			failedCount++
+			errors = append(errors, err.Error())
+			tasks = append(tasks, nil)
+			continue
+		}
 
 		pbTask := h.toPBTask(task, false)
 		tasks = append(tasks, pbTask)
@@ -455,22 +1386,11 @@ func (h *TaskHandler) TaskUpdates(stream pb.TaskService_TaskUpdatesServer) error
 		}
 	}()
 
-	globalCh := make(chan *pb.TaskChangeEvent, 10)
-	h.watchersMu.Lock()
-	h.watchers[""] = append(h.watchers[""], globalCh)
-	h.watchersMu.Unlock()
+	global := h.addWatcher(nil, &watchMatcher{namespace: grpc_middleware.GetNamespace(ctx)})
+	var lastSeq int64
 
 	defer func() {
-		h.watchersMu.Lock()
-		if chs, ok := h.watchers[""]; ok {
-			for i, c := range chs {
-				if c == globalCh {
-					h.watchers[""] = append(chs[:i], chs[i+1:]...)
-					break
-				}
-			}
-		}
-		h.watchersMu.Unlock()
+		h.removeWatcher(nil, global)
 		close(eventCh)
 		close(sendCh)
 		wg.Wait()
@@ -520,7 +1440,17 @@ func (h *TaskHandler) TaskUpdates(stream pb.TaskService_TaskUpdatesServer) error
 					Error:     "unknown update type",
 				}
 			}
-		case event := <-globalCh:
+		case <-global.overflow:
+			// 双向流不中断，只提示客户端变更有缺口
+			metrics.RecordWatchOverflow("updates")
+			sendCh <- &pb.TaskUpdateResponse{
+				ChangeEvent: controlEvent(changeTypeOverflow, lastSeq),
+				Success:     true,
+			}
+		case event := <-global.ch:
+			if event.Sequence > lastSeq {
+				lastSeq = event.Sequence
+			}
 			resp := &pb.TaskUpdateResponse{
 				ChangeEvent: event,
 				Success:     true,
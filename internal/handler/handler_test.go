@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// TestHandler_CancelAndRetry tests lifecycle RPCs and their error codes
+func TestHandler_CancelAndRetry(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+	ctx := context.Background()
+
+	task := model.NewTask("lifecycle", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+	task.ID = "lifecycle-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	cancelled, err := handler.CancelTask(ctx, &pb.CancelTaskRequest{Id: task.ID, Operator: "alice"})
+	if err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if cancelled.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("expected CANCELLED, got %v", cancelled.Status)
+	}
+
+	events, err := repo.GetEventsByTaskID(task.ID)
+	if err != nil || len(events) == 0 || events[len(events)-1].Operator != "alice" {
+		t.Errorf("expected cancel event by alice, got %v (%v)", events, err)
+	}
+
+	if _, err := handler.CancelTask(ctx, &pb.CancelTaskRequest{Id: task.ID}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition when cancelling a terminal task, got %v", err)
+	}
+	if _, err := handler.RetryTask(ctx, &pb.RetryTaskRequest{Id: task.ID}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition when retrying a cancelled task, got %v", err)
+	}
+
+	failed := model.NewTask("failed", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+	failed.ID = "lifecycle-2"
+	failed.Status = model.TaskStatusFailed
+	if err := repo.Create(failed); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	retried, err := handler.RetryTask(ctx, &pb.RetryTaskRequest{Id: failed.ID, Operator: "bob"})
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if retried.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Errorf("expected PENDING after retry, got %v", retried.Status)
+	}
+
+	if _, err := handler.CancelTask(ctx, &pb.CancelTaskRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if _, err := handler.RetryTask(ctx, &pb.RetryTaskRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+// TestHandler_BulkOperations tests bulk RPC validation, dry run and progress lookup
+func TestHandler_BulkOperations(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"bulk-1", "bulk-2"} {
+		task := model.NewTask(id, "test", model.TaskPriorityNormal, "etl", nil, nil, 3, "test")
+		task.ID = id
+		task.Status = model.TaskStatusFailed
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	filter := &pb.ListTasksRequest{StatusFilter: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_FAILED}}
+	dry, err := handler.BulkRetry(ctx, &pb.BulkTaskRequest{Filter: filter, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !dry.DryRun || dry.Affected != 2 || dry.Id != "" {
+		t.Errorf("unexpected dry run result %v", dry)
+	}
+
+	op, err := handler.BulkRetry(ctx, &pb.BulkTaskRequest{Filter: filter, Operator: "ops"})
+	if err != nil {
+		t.Fatalf("bulk retry failed: %v", err)
+	}
+	for op.State == "running" {
+		time.Sleep(10 * time.Millisecond)
+		if op, err = handler.GetBulkOperation(ctx, &pb.GetBulkOperationRequest{Id: op.Id}); err != nil {
+			t.Fatalf("get bulk operation failed: %v", err)
+		}
+	}
+	if op.State != "completed" || op.Succeeded != 2 {
+		t.Errorf("unexpected bulk result %v", op)
+	}
+
+	if _, err := handler.BulkCancel(ctx, &pb.BulkTaskRequest{Ids: []string{"bulk-1"}, Filter: filter}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for ids with filter, got %v", err)
+	}
+	if _, err := handler.BulkCancel(ctx, &pb.BulkTaskRequest{Filter: &pb.ListTasksRequest{Filter: "bad ="}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for invalid filter, got %v", err)
+	}
+	if _, err := handler.GetBulkOperation(ctx, &pb.GetBulkOperationRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+// callerContext 构造已认证并授权的调用者上下文
+func callerContext(userID string, role grpc_middleware.Role) context.Context {
+	claims := &grpc_middleware.Claims{}
+	claims.Subject = userID
+	return grpc_middleware.WithRole(grpc_middleware.WithClaims(context.Background(), claims, ""), role)
+}
+
+// TestHandler_TaskOwnership submitters may only modify their own tasks
+func TestHandler_TaskOwnership(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+
+	for _, id := range []string{"owned-1", "owned-2", "owned-3"} {
+		task := model.NewTask(id, "test", model.TaskPriorityNormal, "default", nil, nil, 3, "alice")
+		task.ID = id
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	bob := callerContext("bob", grpc_middleware.RoleSubmitter)
+	if _, err := handler.CancelTask(bob, &pb.CancelTaskRequest{Id: "owned-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied when cancelling another user's task, got %v", err)
+	}
+	if _, err := handler.UpdateTask(bob, &pb.UpdateTaskRequest{Id: "owned-1", ErrorMessage: "x"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied when updating another user's task, got %v", err)
+	}
+	if _, err := handler.DeleteTask(bob, &pb.DeleteTaskRequest{Id: "owned-1", Force: true}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied when deleting another user's task, got %v", err)
+	}
+	if _, err := handler.CancelTask(bob, &pb.CancelTaskRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a missing task, got %v", err)
+	}
+
+	alice := callerContext("alice", grpc_middleware.RoleSubmitter)
+	if _, err := handler.CancelTask(alice, &pb.CancelTaskRequest{Id: "owned-1"}); err != nil {
+		t.Errorf("owner cancel failed: %v", err)
+	}
+
+	ops := callerContext("ops", grpc_middleware.RoleOperator)
+	if _, err := handler.CancelTask(ops, &pb.CancelTaskRequest{Id: "owned-2"}); err != nil {
+		t.Errorf("operator cancel failed: %v", err)
+	}
+}
+
+// TestHandler_NamespaceIsolation tasks are only visible and writable in their own namespace
+func TestHandler_NamespaceIsolation(t *testing.T) {
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "namespace.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	handler := NewTaskHandler(repository.NewTaskRepository(db))
+	namespaces := service.NewNamespaceService(repository.NewNamespaceRepository(db))
+	handler.SetNamespaceService(namespaces)
+	if _, err := namespaces.Create(context.Background(), "team-a", "", 1, 0); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+
+	teamA := grpc_middleware.WithNamespace(context.Background(), "team-a")
+	task, err := handler.CreateTask(teamA, &pb.CreateTaskRequest{Name: "a"})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if task.Namespace != "team-a" {
+		t.Errorf("expected task in team-a, got %q", task.Namespace)
+	}
+	if _, err := handler.CreateTask(teamA, &pb.CreateTaskRequest{Name: "b"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted over quota, got %v", err)
+	}
+	teamB := grpc_middleware.WithNamespace(context.Background(), "team-b")
+	if _, err := handler.CreateTask(teamB, &pb.CreateTaskRequest{Name: "b"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown namespace, got %v", err)
+	}
+
+	other := context.Background()
+	if _, err := handler.GetTask(other, &pb.GetTaskRequest{Id: task.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound from another namespace, got %v", err)
+	}
+	if _, err := handler.CancelTask(other, &pb.CancelTaskRequest{Id: task.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound when cancelling from another namespace, got %v", err)
+	}
+	if resp, err := handler.ListTasks(other, &pb.ListTasksRequest{}); err != nil || resp.Total != 0 {
+		t.Errorf("expected no tasks in default namespace, got %v %v", resp, err)
+	}
+	if _, err := handler.CancelTask(teamA, &pb.CancelTaskRequest{Id: task.Id}); err != nil {
+		t.Errorf("cancel in own namespace failed: %v", err)
+	}
+}
+
+// TestHandler_CallerIdentity created_by and event operators come from the authenticated caller
+func TestHandler_CallerIdentity(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+
+	alice := callerContext("alice", grpc_middleware.RoleSubmitter)
+	if _, err := handler.CreateTask(alice, &pb.CreateTaskRequest{Name: "spoofed", CreatedBy: "mallory"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a spoofed created_by, got %v", err)
+	}
+	created, err := handler.CreateTask(alice, &pb.CreateTaskRequest{Name: "own"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if created.CreatedBy != "alice" {
+		t.Errorf("expected created_by alice, got %q", created.CreatedBy)
+	}
+
+	admin := callerContext("root", grpc_middleware.RoleAdmin)
+	onBehalf, err := handler.CreateTask(admin, &pb.CreateTaskRequest{Name: "on-behalf", CreatedBy: "bob"})
+	if err != nil {
+		t.Fatalf("admin CreateTask failed: %v", err)
+	}
+	if onBehalf.CreatedBy != "bob" {
+		t.Errorf("expected admin to set created_by bob, got %q", onBehalf.CreatedBy)
+	}
+
+	if _, err := handler.CancelTask(alice, &pb.CancelTaskRequest{Id: created.Id, Operator: "mallory"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a spoofed operator, got %v", err)
+	}
+	if _, err := handler.UpdateTask(alice, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if _, err := handler.CancelTask(alice, &pb.CancelTaskRequest{Id: created.Id}); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+
+	events, _, err := repo.ListEventsByTaskID(created.Id, 100, "")
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(events) < 2 {
+		t.Fatalf("expected status events, got %d", len(events))
+	}
+	for _, event := range events[len(events)-2:] {
+		if event.Operator != "alice" {
+			t.Errorf("expected operator alice for %s -> %s, got %q", event.FromStatus, event.ToStatus, event.Operator)
+		}
+	}
+}
+
+// TestHandler_SecretParams tests that secret params are encrypted at rest and redacted in responses
+func TestHandler_SecretParams(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+	ctx := context.Background()
+	req := &pb.CreateTaskRequest{
+		Name:         "deploy",
+		InputParams:  map[string]string{"env": "prod"},
+		SecretParams: map[string]string{"password": "hunter2"},
+	}
+
+	if _, err := handler.CreateTask(ctx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition without a key file, got %v", err)
+	}
+
+	secrets, err := service.ParseSecretCipher(strings.NewReader("k1:"+base64.StdEncoding.EncodeToString(make([]byte, 32))), "")
+	if err != nil {
+		t.Fatalf("failed to parse key file: %v", err)
+	}
+	handler.SetSecretCipher(secrets)
+
+	conflict := &pb.CreateTaskRequest{Name: "conflict", InputParams: map[string]string{"password": "x"}, SecretParams: map[string]string{"password": "y"}}
+	if _, err := handler.CreateTask(ctx, conflict); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a param in both maps, got %v", err)
+	}
+
+	created, err := handler.CreateTask(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if created.SecretParams["password"] != model.RedactedValue || created.InputParams["password"] != "" {
+		t.Errorf("expected redacted secret params, got %v / %v", created.SecretParams, created.InputParams)
+	}
+
+	got, err := handler.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id})
+	if err != nil || got.SecretParams["password"] != model.RedactedValue {
+		t.Errorf("expected redacted secret params from GetTask, got %v: %v", got.GetSecretParams(), err)
+	}
+
+	stored, err := repo.GetByID(created.Id)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if strings.Contains(stored.SecretParams["password"], "hunter2") {
+		t.Error("secret param stored in plaintext")
+	}
+	if params, err := secrets.Open(stored.ID, stored.SecretParams); err != nil || params["password"] != "hunter2" {
+		t.Errorf("expected stored ciphertext to decrypt, got %v: %v", params, err)
+	}
+
+	// outbox 快照（推送给订阅者与 Webhook）只带参数名
+	entries, err := repo.ListOutboxSince(0, 10)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("expected outbox entries: %v", err)
+	}
+	if v := entries[len(entries)-1].Task.SecretParams["password"]; v != model.RedactedValue {
+		t.Errorf("expected redacted outbox snapshot, got %q", v)
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// NamespaceHandler 命名空间管理处理器，授权拦截器只允许 admin 调用
+type NamespaceHandler struct {
+	namespaces *service.NamespaceService
+	pb.UnimplementedNamespaceServiceServer
+}
+
+// NewNamespaceHandler 创建命名空间管理处理器
+func NewNamespaceHandler(namespaces *service.NamespaceService) *NamespaceHandler {
+	return &NamespaceHandler{namespaces: namespaces}
+}
+
+// CreateNamespace 创建命名空间
+func (h *NamespaceHandler) CreateNamespace(ctx context.Context, req *pb.CreateNamespaceRequest) (*pb.Namespace, error) {
+	ns, err := h.namespaces.Create(ctx, req.Name, req.Description, int(req.MaxTasks), int(req.MaxActiveTasks))
+	if err != nil {
+		return nil, namespaceError(err)
+	}
+	return toPBNamespace(ns, repository.NamespaceUsage{}), nil
+}
+
+// GetNamespace 获取命名空间及当前用量
+func (h *NamespaceHandler) GetNamespace(ctx context.Context, req *pb.GetNamespaceRequest) (*pb.Namespace, error) {
+	if req.Name == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	}
+
+	ns, err := h.namespaces.Get(ctx, req.Name)
+	if err != nil {
+		return nil, namespaceError(err)
+	}
+	return h.withUsage(ctx, ns)
+}
+
+// ListNamespaces 列出全部命名空间及当前用量
+func (h *NamespaceHandler) ListNamespaces(ctx context.Context, req *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	namespaces, err := h.namespaces.List(ctx)
+	if err != nil {
+		return nil, namespaceError(err)
+	}
+
+	resp := &pb.ListNamespacesResponse{Namespaces: make([]*pb.Namespace, 0, len(namespaces))}
+	for _, ns := range namespaces {
+		pbNs, err := h.withUsage(ctx, ns)
+		if err != nil {
+			return nil, err
+		}
+		resp.Namespaces = append(resp.Namespaces, pbNs)
+	}
+	return resp, nil
+}
+
+// UpdateNamespace 修改描述或配额
+func (h *NamespaceHandler) UpdateNamespace(ctx context.Context, req *pb.UpdateNamespaceRequest) (*pb.Namespace, error) {
+	if req.Name == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	}
+
+	update := service.NamespaceUpdate{Description: req.Description}
+	if req.MaxTasks != nil {
+		n := int(*req.MaxTasks)
+		update.MaxTasks = &n
+	}
+	if req.MaxActiveTasks != nil {
+		n := int(*req.MaxActiveTasks)
+		update.MaxActiveTasks = &n
+	}
+
+	ns, err := h.namespaces.Update(ctx, req.Name, update)
+	if err != nil {
+		return nil, namespaceError(err)
+	}
+	return h.withUsage(ctx, ns)
+}
+
+// DeleteNamespace 删除命名空间，命名空间中仍有任务时失败
+func (h *NamespaceHandler) DeleteNamespace(ctx context.Context, req *pb.DeleteNamespaceRequest) (*pb.DeleteNamespaceResponse, error) {
+	if req.Name == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	}
+
+	if err := h.namespaces.Delete(ctx, req.Name); err != nil {
+		return nil, namespaceError(err)
+	}
+	return &pb.DeleteNamespaceResponse{Deleted: true}, nil
+}
+
+// withUsage 查询用量并转换
+func (h *NamespaceHandler) withUsage(ctx context.Context, ns *repository.Namespace) (*pb.Namespace, error) {
+	usage, err := h.namespaces.Usage(ctx, ns.Name)
+	if err != nil {
+		return nil, namespaceError(err)
+	}
+	return toPBNamespace(ns, usage), nil
+}
+
+// namespaceError 将命名空间服务错误转换为 gRPC 错误
+func namespaceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNamespaceNotFound):
+		return errorcode.NewTaskError(errorcode.ErrCodeNotFound, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, repository.ErrNamespaceExists):
+		return errorcode.NewTaskError(errorcode.ErrCodeAlreadyExists, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, service.ErrInvalidNamespace), errors.Is(err, service.ErrInvalidQuota):
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	case errors.Is(err, repository.ErrNamespaceNotEmpty), errors.Is(err, service.ErrDefaultNamespace):
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidState, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Errorf("Namespace handler error: %v", err)
+	return errorcode.NewTaskError(errorcode.ErrCodeDBError, "failed to access namespaces").ToGRPCStatus().Err()
+}
+
+// toPBNamespace 转换命名空间
+func toPBNamespace(ns *repository.Namespace, usage repository.NamespaceUsage) *pb.Namespace {
+	return &pb.Namespace{
+		Name:            ns.Name,
+		Description:     ns.Description,
+		MaxTasks:        int32(ns.MaxTasks),
+		MaxActiveTasks:  int32(ns.MaxActiveTasks),
+		TaskCount:       int32(usage.Tasks),
+		ActiveTaskCount: int32(usage.ActiveTasks),
+		CreatedAt:       timestamppb.New(ns.CreatedAt),
+		UpdatedAt:       timestamppb.New(ns.UpdatedAt),
+	}
+}
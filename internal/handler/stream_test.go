@@ -1,16 +1,27 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
 	pb "taskflow/proto"
 )
 
 // TestHandler_StreamMethodsExist verifies streaming methods exist
 func TestHandler_StreamMethodsExist(t *testing.T) {
 	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
+		watchers:     make(map[string][]*watcher),
 		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
 	}
 
@@ -18,45 +29,77 @@ func TestHandler_StreamMethodsExist(t *testing.T) {
 	_ = handler.WatchTask
 	_ = handler.BatchCreateTasks
 	_ = handler.TaskUpdates
-	_ = handler.broadcastTaskChange
+	_ = handler.Publish
 	_ = handler.notifyWatchers
 	_ = handler.taskUpdateNotifier
 
 	t.Log("All streaming methods exist on handler")
 }
 
-// TestHandler_NotifyWatchers tests task notification
-func TestHandler_NotifyWatchers(t *testing.T) {
+// newNotifyHandler 创建只带订阅者表的处理器并启动通知循环
+func newNotifyHandler(t testing.TB) *TaskHandler {
 	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
+		watchers:     make(map[string][]*watcher),
 		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
 	}
-
-	// Start notifier
 	go handler.taskUpdateNotifier()
+	t.Cleanup(func() { close(handler.taskUpdateCh) })
+	return handler
+}
+
+// outboxEntry 构造一条 outbox 记录
+func outboxEntry(seq int64, task *model.Task, from, to model.TaskStatus, changeType string) *model.OutboxEntry {
+	return &model.OutboxEntry{
+		Seq:        seq,
+		TaskID:     task.ID,
+		ChangeType: changeType,
+		FromStatus: from,
+		ToStatus:   to,
+		Task:       task,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// receiveEvent 等待订阅者收到一条事件
+func receiveEvent(t testing.TB, w *watcher) *pb.TaskChangeEvent {
+	t.Helper()
+	select {
+	case event := <-w.ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+// TestHandler_NotifyWatchers tests task notification
+func TestHandler_NotifyWatchers(t *testing.T) {
+	handler := newNotifyHandler(t)
+	w := handler.addWatcher([]string{"notify-task-id"}, nil)
 
 	// Create a test task
 	task := model.NewTask("notify-test", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
 	task.ID = "notify-task-id"
 
-	// Test broadcast
-	handler.broadcastTaskChange(task.ID, task, model.TaskStatusPending, model.TaskStatusRunning, "started")
-
-	// Wait for notification
-	<-handler.taskUpdateCh
+	if err := handler.Publish(outboxEntry(1, task, model.TaskStatusPending, model.TaskStatusRunning, "started")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
 
-	t.Log("Task notification test passed")
+	event := receiveEvent(t, w)
+	if event.TaskId != task.ID || event.Sequence != 1 || event.ChangeType != "started" {
+		t.Errorf("unexpected event %+v", event)
+	}
 }
 
 // TestHandler_MultipleWatchers tests multiple watchers
 func TestHandler_MultipleWatchers(t *testing.T) {
 	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
+		watchers:     make(map[string][]*watcher),
 		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
 	}
 
-	// Create channel for watcher
-	ch := make(chan *pb.TaskChangeEvent, 10)
+	// Create watcher
+	ch := newWatcher(10)
 
 	// Register watcher
 	handler.watchersMu.Lock()
@@ -77,41 +120,32 @@ func TestHandler_MultipleWatchers(t *testing.T) {
 
 // TestHandler_ConcurrentNotifications tests concurrent notifications
 func TestHandler_ConcurrentNotifications(t *testing.T) {
-	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
-		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
-	}
-
-	// Start notifier
-	go handler.taskUpdateNotifier()
+	handler := newNotifyHandler(t)
 
 	// Create multiple watchers
+	var watchers []*watcher
 	for i := 0; i < 5; i++ {
-		ch := make(chan *pb.TaskChangeEvent, 10)
-		handler.watchersMu.Lock()
-		handler.watchers[""] = append(handler.watchers[""], ch)
-		handler.watchersMu.Unlock()
+		watchers = append(watchers, handler.addWatcher(nil, nil))
 	}
 
-	// Broadcast notification
 	task := model.NewTask("concurrent-test", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
-	handler.broadcastTaskChange(task.ID, task, model.TaskStatusPending, model.TaskStatusRunning, "started")
-
-	// Wait for notification
-	<-handler.taskUpdateCh
+	task.ID = "concurrent-task-id"
+	if err := handler.Publish(outboxEntry(1, task, model.TaskStatusPending, model.TaskStatusRunning, "started")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
 
-	t.Log("Concurrent notifications test passed")
+	// Every watcher receives the change
+	for i, w := range watchers {
+		if event := receiveEvent(t, w); event.TaskId != task.ID {
+			t.Errorf("watcher %d: expected %s, got %s", i, task.ID, event.TaskId)
+		}
+	}
 }
 
 // TestHandler_StatusTransitionInNotification tests status in notification
 func TestHandler_StatusTransitionInNotification(t *testing.T) {
-	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
-		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
-	}
-
-	// Start notifier
-	go handler.taskUpdateNotifier()
+	handler := newNotifyHandler(t)
+	w := handler.addWatcher(nil, nil)
 
 	// Test different status transitions
 	transitions := []struct {
@@ -125,30 +159,323 @@ func TestHandler_StatusTransitionInNotification(t *testing.T) {
 	}
 
 	task := model.NewTask("status-test", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
-
-	for _, tr := range transitions {
-		handler.broadcastTaskChange(task.ID, task, tr.from, tr.to, "test")
-		<-handler.taskUpdateCh
+	task.ID = "status-task-id"
+
+	for i, tr := range transitions {
+		if err := handler.Publish(outboxEntry(int64(i+1), task, tr.from, tr.to, "test")); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+		event := receiveEvent(t, w)
+		if event.FromStatus != pb.TaskStatus(tr.from) || event.ToStatus != pb.TaskStatus(tr.to) {
+			t.Errorf("expected %v -> %v, got %v -> %v", tr.from, tr.to, event.FromStatus, event.ToStatus)
+		}
 	}
-
-	t.Log("Status transition test passed")
 }
 
 // BenchmarkHandler_NotifyWatchers benchmarks notification
 func BenchmarkHandler_NotifyWatchers(b *testing.B) {
-	handler := &TaskHandler{
-		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
-		taskUpdateCh: make(chan *pb.TaskChangeEvent, 1000),
-	}
-
-	// Start notifier
-	go handler.taskUpdateNotifier()
+	handler := newNotifyHandler(b)
+	w := handler.addWatcher(nil, nil)
 
 	task := model.NewTask("bench-test", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+	task.ID = "bench-task-id"
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		handler.broadcastTaskChange(task.ID, task, model.TaskStatusPending, model.TaskStatusRunning, "started")
-		<-handler.taskUpdateCh
+		handler.Publish(outboxEntry(int64(i+1), task, model.TaskStatusPending, model.TaskStatusRunning, "started"))
+		<-w.ch
+	}
+}
+
+// fakeWatchStream 记录 WatchTask 推送的事件，收到 limit 条后取消上下文
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	events []*pb.TaskChangeEvent
+	limit  int
+}
+
+func newFakeWatchStream(limit int) *fakeWatchStream {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return &fakeWatchStream{ctx: ctx, cancel: cancel, limit: limit}
+}
+
+func (s *fakeWatchStream) Context() context.Context { return s.ctx }
+
+func (s *fakeWatchStream) Send(event *pb.TaskChangeEvent) error {
+	s.events = append(s.events, event)
+	if len(s.events) >= s.limit {
+		s.cancel()
+	}
+	return nil
+}
+
+// setupStreamHandler 创建基于临时数据库的处理器
+func setupStreamHandler(t *testing.T) (*TaskHandler, *repository.TaskRepository) {
+	t.Helper()
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "stream.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	repo := repository.NewTaskRepository(db)
+	return NewTaskHandler(repo), repo
+}
+
+// TestHandler_WatchTaskResume tests replay from resume_from_sequence
+func TestHandler_WatchTaskResume(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+
+	for i := 0; i < 3; i++ {
+		task := model.NewTask(fmt.Sprintf("resume-%d", i), "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+		task.ID = fmt.Sprintf("resume-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	if err := repo.UpdateStatus("resume-0", model.TaskStatusPending, model.TaskStatusRunning); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+
+	// 从序号 1 续传：应收到其后的 3 条变更，且顺序与序号一致
+	stream := newFakeWatchStream(3)
+	if err := handler.WatchTask(&pb.WatchTaskRequest{ResumeFromSequence: 1}, stream); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		taskID     string
+		changeType string
+	}{
+		{"resume-1", model.ChangeTypeCreated},
+		{"resume-2", model.ChangeTypeCreated},
+		{"resume-0", model.ChangeTypeStatusChanged},
+	}
+	for i, w := range want {
+		event := stream.events[i]
+		if event.TaskId != w.taskID || event.ChangeType != w.changeType {
+			t.Errorf("event %d: expected %s/%s, got %s/%s", i, w.taskID, w.changeType, event.TaskId, event.ChangeType)
+		}
+		if event.Sequence != int64(i+2) {
+			t.Errorf("event %d: expected sequence %d, got %d", i, i+2, event.Sequence)
+		}
+	}
+
+	// 已发布记录被清理后无法续传，应收到 resync 并结束
+	if _, err := repo.MarkOutboxPublished(4); err != nil {
+		t.Fatalf("failed to mark published: %v", err)
+	}
+	if _, err := repo.PruneOutbox(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to prune outbox: %v", err)
+	}
+	stream = newFakeWatchStream(10)
+	if err := handler.WatchTask(&pb.WatchTaskRequest{ResumeFromSequence: 1}, stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stream.events) != 1 || stream.events[0].ChangeType != changeTypeResync || stream.events[0].Sequence != 4 {
+		t.Fatalf("expected resync at sequence 4, got %v", stream.events)
+	}
+}
+
+// TestHandler_WatcherOverflow tests that a full buffer signals overflow instead of dropping silently
+func TestHandler_WatcherOverflow(t *testing.T) {
+	w := newWatcher(1)
+	w.send(&pb.TaskChangeEvent{Sequence: 1})
+	w.send(&pb.TaskChangeEvent{Sequence: 2})
+
+	select {
+	case <-w.overflow:
+	default:
+		t.Fatal("expected overflow signal")
+	}
+	if event := <-w.ch; event.Sequence != 1 {
+		t.Errorf("expected buffered sequence 1, got %d", event.Sequence)
+	}
+}
+
+// TestHandler_WatchSelectors tests multi-ID and selector subscriptions filtered before enqueueing
+func TestHandler_WatchSelectors(t *testing.T) {
+	handler := &TaskHandler{
+		watchers:     make(map[string][]*watcher),
+		taskUpdateCh: make(chan *pb.TaskChangeEvent, 10),
+	}
+
+	newMatcher := func(req *pb.WatchTaskRequest) *watchMatcher {
+		m, err := newWatchMatcher(req)
+		if err != nil {
+			t.Fatalf("failed to build matcher: %v", err)
+		}
+		return m
+	}
+
+	byIDs := handler.addWatcher([]string{"a", "b", "a"}, newMatcher(&pb.WatchTaskRequest{TaskIds: []string{"a", "b", "a"}}))
+	byType := handler.addWatcher(nil, newMatcher(&pb.WatchTaskRequest{TaskTypes: []string{"etl"}}))
+	byCreator := handler.addWatcher(nil, newMatcher(&pb.WatchTaskRequest{CreatedBy: []string{"alice"}}))
+	byWorkflow := handler.addWatcher(nil, newMatcher(&pb.WatchTaskRequest{
+		Workflow:     "nightly",
+		Labels:       map[string]string{"team": "data"},
+		StatusFilter: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_FAILED},
+	}))
+
+	event := func(id, taskType, creator string, status pb.TaskStatus, labels map[string]string) *pb.TaskChangeEvent {
+		return &pb.TaskChangeEvent{
+			TaskId:   id,
+			ToStatus: status,
+			Task:     &pb.Task{Id: id, TaskType: taskType, CreatedBy: creator, Status: status, Labels: labels},
+		}
+	}
+	handler.notifyWatchers(event("a", "report", "bob", pb.TaskStatus_TASK_STATUS_RUNNING, nil))
+	handler.notifyWatchers(event("b", "etl", "alice", pb.TaskStatus_TASK_STATUS_RUNNING, nil))
+	handler.notifyWatchers(event("c", "etl", "bob", pb.TaskStatus_TASK_STATUS_FAILED, map[string]string{"workflow": "nightly", "team": "data"}))
+	handler.notifyWatchers(event("d", "report", "bob", pb.TaskStatus_TASK_STATUS_RUNNING, map[string]string{"workflow": "nightly", "team": "data"}))
+
+	received := func(w *watcher) []string {
+		var ids []string
+		for len(w.ch) > 0 {
+			ids = append(ids, (<-w.ch).TaskId)
+		}
+		return ids
+	}
+	cases := []struct {
+		name string
+		w    *watcher
+		want string
+	}{
+		{"ids", byIDs, "a,b"},
+		{"type", byType, "b,c"},
+		{"creator", byCreator, "b"},
+		{"workflow", byWorkflow, "c"},
+	}
+	for _, tc := range cases {
+		if got := fmt.Sprint(received(tc.w)); got != fmt.Sprint(strings.Split(tc.want, ",")) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	handler.removeWatcher([]string{"a", "b", "a"}, byIDs)
+	if len(handler.watchers["a"]) != 0 || len(handler.watchers["b"]) != 0 {
+		t.Error("expected multi-ID watcher to be removed from every key")
+	}
+
+	if _, err := newWatchMatcher(&pb.WatchTaskRequest{Workflow: "x", Labels: map[string]string{"workflow": "y"}}); err == nil {
+		t.Error("expected conflicting workflow error")
+	}
+}
+
+// TestHandler_PublishSkipsDelivered 中继重试时再次投递的记录不会重复推送给订阅者
+func TestHandler_PublishSkipsDelivered(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+	w := handler.addWatcher(nil, nil)
+	defer handler.removeWatcher(nil, w)
+
+	for i := 0; i < 2; i++ {
+		task := model.NewTask(fmt.Sprintf("dedup-%d", i), "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+		task.ID = fmt.Sprintf("dedup-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	entries, err := repo.ListOutboxSince(0, 10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 outbox entries, got %d (%v)", len(entries), err)
+	}
+
+	for _, entry := range append(entries, entries...) {
+		if err := handler.Publish(entry); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	for _, entry := range entries {
+		select {
+		case event := <-w.ch:
+			if event.Sequence != entry.Seq {
+				t.Errorf("expected sequence %d, got %d", entry.Seq, event.Sequence)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected event for seq %d", entry.Seq)
+		}
+	}
+	select {
+	case event := <-w.ch:
+		t.Errorf("unexpected duplicate event %d", event.Sequence)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestHandler_WaitTask tests long-poll waiting backed by watcher notifications
+func TestHandler_WaitTask(t *testing.T) {
+	handler, repo := setupStreamHandler(t)
+	relay := service.NewOutboxRelay(repo, time.Second, time.Hour)
+	relay.AddSink(handler)
+
+	task := model.NewTask("wait", "test", model.TaskPriorityNormal, "default", nil, nil, 3, "test")
+	task.ID = "wait-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 未达到目标状态时超时返回当前状态
+	resp, err := handler.WaitTask(context.Background(), &pb.WaitTaskRequest{Id: task.ID, TimeoutMs: 50})
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if !resp.TimedOut || resp.Task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("expected timed out pending task, got timed_out=%v status=%v", resp.TimedOut, resp.Task.Status)
+	}
+
+	type result struct {
+		resp *pb.WaitTaskResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := handler.WaitTask(context.Background(), &pb.WaitTaskRequest{Id: task.ID, TimeoutMs: 5000})
+		done <- result{resp, err}
+	}()
+
+	// 中间状态不会唤醒等待者，进入终态后立即返回
+	for _, tr := range [][2]model.TaskStatus{
+		{model.TaskStatusPending, model.TaskStatusRunning},
+		{model.TaskStatusRunning, model.TaskStatusSucceeded},
+	} {
+		time.Sleep(20 * time.Millisecond)
+		if err := repo.UpdateStatus(task.ID, tr[0], tr[1]); err != nil {
+			t.Fatalf("failed to update status: %v", err)
+		}
+		if _, err := relay.RunOnce(); err != nil {
+			t.Fatalf("relay failed: %v", err)
+		}
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("wait failed: %v", r.err)
+		}
+		if r.resp.TimedOut || r.resp.Task.Status != pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+			t.Errorf("expected succeeded task, got timed_out=%v status=%v", r.resp.TimedOut, r.resp.Task.Status)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("WaitTask did not return after the task completed")
+	}
+
+	// 已处于目标状态时立即返回
+	resp, err = handler.WaitTask(context.Background(), &pb.WaitTaskRequest{
+		Id:             task.ID,
+		TimeoutMs:      5000,
+		TargetStatuses: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED},
+	})
+	if err != nil || resp.TimedOut {
+		t.Fatalf("expected immediate return, got %v, %v", resp, err)
+	}
+
+	if _, err := handler.WaitTask(context.Background(), &pb.WaitTaskRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
 	}
 }
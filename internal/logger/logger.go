@@ -5,8 +5,8 @@ import (
 )
 
 var (
-	// Logger 全局日志实例
-	Logger *zap.SugaredLogger
+	// Logger 全局日志实例（未初始化前为空操作日志，避免测试等场景下空指针）
+	Logger = zap.NewNop().Sugar()
 )
 
 // Init 初始化日志
@@ -44,6 +44,36 @@ var (
 		Help:    "gRPC request latency in seconds",
 		Buckets: prometheus.DefBuckets,
 	}, []string{"method"})
+
+	// TasksPurged - purged task counter
+	TasksPurged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_tasks_purged_total",
+		Help: "Total number of tasks removed by retention or purge",
+	}, []string{"reason"})
+
+	// Backups - database backup counter
+	Backups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_backups_total",
+		Help: "Total number of database backups by result",
+	}, []string{"result"})
+
+	// LastBackupTimestamp - time of the last successful backup
+	LastBackupTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_last_backup_timestamp_seconds",
+		Help: "Unix timestamp of the last successful database backup",
+	})
+
+	// WatchOverflows - watch streams that fell behind and were asked to resync
+	WatchOverflows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_watch_overflows_total",
+		Help: "Total number of watch stream overflows by stream type",
+	}, []string{"stream"})
+
+	// LastBackupSize - size of the last successful backup
+	LastBackupSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_last_backup_size_bytes",
+		Help: "Size in bytes of the last successful database backup",
+	})
 )
 
 // RecordTaskStatus records task status count
@@ -75,3 +105,24 @@ func RecordGRPCRequest(method, status string) {
 func RecordGRPCLatency(method string, duration float64) {
 	GRPCLatency.WithLabelValues(method).Observe(duration)
 }
+
+// RecordTasksPurged records purged tasks
+func RecordTasksPurged(reason string, count int) {
+	TasksPurged.WithLabelValues(reason).Add(float64(count))
+}
+
+// RecordBackup records a backup attempt; size is only used on success
+func RecordBackup(success bool, size int64) {
+	if !success {
+		Backups.WithLabelValues("error").Inc()
+		return
+	}
+	Backups.WithLabelValues("success").Inc()
+	LastBackupTimestamp.SetToCurrentTime()
+	LastBackupSize.Set(float64(size))
+}
+
+// RecordWatchOverflow records a watcher whose buffer overflowed
+func RecordWatchOverflow(stream string) {
+	WatchOverflows.WithLabelValues(stream).Inc()
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+)
+
+// maxAuditResponseCapture 为提取新建资源 ID 最多缓存的响应体字节数
+const maxAuditResponseCapture = 64 << 10
+
+// Audit 审计中间件：须在 Auth 之后、Authorize 之前使用，被拒绝的调用同样记录
+// methods 将 "METHOD 路由" 映射为 gRPC 方法名，与 gRPC 审计拦截器记录相同的方法名；只读请求不记录
+func Audit(recorder grpc_middleware.AuditRecorder, methods map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		method, ok := methods[c.Request.Method+" "+route]
+		if !ok {
+			method = c.Request.Method + " " + route
+		}
+		if grpc_middleware.ReadOnlyMethods[method] {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		digest := sha256.Sum256(body)
+
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Auth 与 Namespace 中间件替换了 c.Request，这里读到的是带身份与命名空间的上下文
+		ctx := c.Request.Context()
+		event := grpc_middleware.NewAuditEvent(ctx, model.AuditTransportHTTP, method,
+			auditResource(c, body, writer.body.Bytes()), hex.EncodeToString(digest[:]), codeFromHTTPStatus(c.Writer.Status()))
+		recorder.RecordAudit(ctx, event)
+	}
+}
+
+// captureWriter 缓存响应体开头部分，用于提取新建资源的 ID
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if remaining := maxAuditResponseCapture - w.body.Len(); remaining > 0 {
+		if len(data) < remaining {
+			remaining = len(data)
+		}
+		w.body.Write(data[:remaining])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// auditResource 目标资源：路由参数中的 id 或 name，其次是响应中新建资源的 id，最后是请求中的 name
+func auditResource(c *gin.Context, reqBody, respBody []byte) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	if name := c.Param("name"); name != "" {
+		return name
+	}
+
+	var fields struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(respBody, &fields) == nil && fields.ID != "" {
+		return fields.ID
+	}
+	fields.Name = ""
+	if json.Unmarshal(reqBody, &fields) == nil && fields.Name != "" {
+		return fields.Name
+	}
+	return ""
+}
+
+// codeFromHTTPStatus 将 HTTP 状态码映射为 gRPC 状态码，与 gRPC 审计记录使用相同的结果码
+func codeFromHTTPStatus(status int) codes.Code {
+	switch {
+	case status < 400:
+		return codes.OK
+	case status == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case status == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case status == http.StatusForbidden:
+		return codes.PermissionDenied
+	case status == http.StatusNotFound:
+		return codes.NotFound
+	case status == http.StatusConflict:
+		return codes.AlreadyExists
+	case status == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case status == http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case status == http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case status < 500:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
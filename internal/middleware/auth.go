@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+)
+
+// Auth 认证中间件：与 gRPC 拦截器相同，接受 Authorization: Bearer 令牌、X-API-Key 或双向 TLS 客户端证书
+// 认证通过后身份写入 c.Request.Context()，可用 grpc_middleware.GetUserID 获取
+func Auth(verifier *grpc_middleware.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if state := c.Request.TLS; state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			ctx = grpc_middleware.WithPeerCertificate(ctx, state.VerifiedChains[0][0])
+		}
+
+		ctx, err := grpc_middleware.Authenticate(ctx, verifier,
+			c.GetHeader("Authorization"), c.GetHeader(grpc_middleware.APIKeyHeader))
+		if err != nil {
+			errorcode.HandleGinError(c, errorcode.FromGRPCStatus(status.Convert(err)))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Authorize 授权中间件：须在 Auth 之后使用
+// methods 将 "METHOD 路由" 映射为 gRPC 方法名，与 gRPC 授权拦截器共用 grpc_middleware.MethodRoles
+func Authorize(defaultRole grpc_middleware.Role, methods map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, err := grpc_middleware.Authorize(c.Request.Context(), methods[c.Request.Method+" "+c.FullPath()], defaultRole)
+		if err != nil {
+			errorcode.HandleGinError(c, errorcode.FromGRPCStatus(status.Convert(err)))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Namespace 命名空间中间件：须在 Auth、Authorize 之后使用
+// 按调用者身份与 X-Namespace 请求头确定命名空间，与 gRPC 拦截器规则相同
+func Namespace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, err := grpc_middleware.ResolveNamespace(c.Request.Context(), c.GetHeader(grpc_middleware.NamespaceHeader))
+		if err != nil {
+			errorcode.HandleGinError(c, errorcode.FromGRPCStatus(status.Convert(err)))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
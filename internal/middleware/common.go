@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	"taskflow/internal/logger"
+	"taskflow/internal/model"
 )
 
 // RequestID 中间件 - 添加请求ID
@@ -77,8 +79,8 @@ func Recovery() gin.HandlerFunc {
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-API-Key, X-Namespace, X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -97,16 +99,45 @@ func RequestBodyLogger() gin.HandlerFunc {
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 			if len(bodyBytes) > 0 && len(bodyBytes) < 1024 {
-				logger.Debugf("[%s] Request Body: %s", c.GetHeader("X-Request-ID"), string(bodyBytes))
+				logger.Debugf("[%s] Request Body: %s", c.GetHeader("X-Request-ID"), string(redactSecretParams(bodyBytes)))
 			}
 		}
 		c.Next()
 	}
 }
 
+// redactSecretParams 将请求体中 secret_params 的值替换为占位值，非 JSON 或没有机密参数时原样返回
+func redactSecretParams(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+	var secrets map[string]json.RawMessage
+	if json.Unmarshal(fields["secret_params"], &secrets) != nil || len(secrets) == 0 {
+		return body
+	}
+	for name := range secrets {
+		secrets[name] = json.RawMessage(`"` + model.RedactedValue + `"`)
+	}
+	fields["secret_params"], _ = json.Marshal(secrets)
+	redacted, _ := json.Marshal(fields)
+	return redacted
+}
+
 // Timeout 超时控制中间件（优化版 - 修复goroutine泄漏）
-func Timeout(timeout time.Duration) gin.HandlerFunc {
+// skipPaths 中的路由（如 SSE、WebSocket 长连接）不受超时限制
+func Timeout(timeout time.Duration, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+
 	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
 		// 使用 context 实现超时控制
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 审计事件的调用来源
+const (
+	AuditTransportGRPC = "grpc"
+	AuditTransportHTTP = "http"
+)
+
+// AuditEvent 一次修改类 API 调用的审计记录，只追加不修改
+// Hash 覆盖上一条记录的 Hash 与本条内容，构成哈希链，任何一条被改动或删除都会使之后的链校验失败
+type AuditEvent struct {
+	Seq           int64     `json:"seq"`
+	Timestamp     time.Time `json:"timestamp"`
+	UserID        string    `json:"user_id"`     // 调用者身份，未启用认证时为空
+	AuthMethod    string    `json:"auth_method"` // 凭证类型：access、api_key、certificate
+	Namespace     string    `json:"namespace"`
+	Transport     string    `json:"transport"`      // grpc 或 http
+	Method        string    `json:"method"`         // gRPC 完整方法名
+	Resource      string    `json:"resource"`       // 目标资源 ID 或名称
+	RequestDigest string    `json:"request_digest"` // 请求内容的 SHA-256
+	Code          string    `json:"code"`           // gRPC 状态码名称
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// ComputeHash 计算本条记录的链式哈希，时间按 UTC 纳秒精度参与计算
+func (e *AuditEvent) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		strconv.FormatInt(e.Seq, 10),
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.AuthMethod,
+		e.Namespace,
+		e.Transport,
+		e.Method,
+		e.Resource,
+		e.RequestDigest,
+		e.Code,
+	}
+	// 字段以长度前缀拼接，避免不同字段组合得到相同输入
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(strconv.Itoa(len(f)))
+		b.WriteByte(':')
+		b.WriteString(f)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
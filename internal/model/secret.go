@@ -0,0 +1,16 @@
+package model
+
+// RedactedValue 机密参数在 API 响应、变更推送与日志中的占位值
+const RedactedValue = "[REDACTED]"
+
+// RedactedSecretParams 返回机密参数名到占位值的映射，没有机密参数时返回 nil
+func (t *Task) RedactedSecretParams() map[string]string {
+	if len(t.SecretParams) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(t.SecretParams))
+	for name := range t.SecretParams {
+		redacted[name] = RedactedValue
+	}
+	return redacted
+}
@@ -36,6 +36,9 @@ func (s TaskStatus) String() string {
 	}
 }
 
+// DefaultNamespace 默认命名空间，未指定命名空间的任务与调用者归属于此
+const DefaultNamespace = "default"
+
 // TaskPriority 任务优先级枚举
 type TaskPriority int32
 
@@ -81,6 +84,9 @@ type Task struct {
 	StartedAt     *time.Time        `json:"started_at,omitempty" bson:"started_at,omitempty"`
 	CompletedAt   *time.Time        `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
 	CreatedBy     string            `json:"created_by" bson:"created_by"`
+	Namespace     string            `json:"namespace,omitempty" bson:"namespace,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty" bson:"labels,omitempty"`
+	SecretParams  map[string]string `json:"secret_params,omitempty" bson:"secret_params,omitempty"` // 机密输入参数，值为密文，JSON 序列化时脱敏
 	Events        []TaskEvent       `json:"events" bson:"events"`
 }
 
@@ -95,6 +101,25 @@ type TaskEvent struct {
 	Operator   string     `json:"operator" bson:"operator"`
 }
 
+// 任务变更类型
+const (
+	ChangeTypeCreated       = "created"
+	ChangeTypeUpdated       = "updated"
+	ChangeTypeStatusChanged = "status_changed"
+	ChangeTypeDeleted       = "deleted"
+)
+
+// OutboxEntry 任务变更 outbox 记录，与变更在同一事务中写入，Seq 即提交顺序
+type OutboxEntry struct {
+	Seq        int64      `json:"seq"`
+	TaskID     string     `json:"task_id"`
+	ChangeType string     `json:"change_type"`
+	FromStatus TaskStatus `json:"from_status"`
+	ToStatus   TaskStatus `json:"to_status"`
+	Task       *Task      `json:"task"` // 变更后的任务快照（删除时为删除前的快照）
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
 // NewTask 创建新任务
 func NewTask(name, description string, priority TaskPriority, taskType string, inputParams map[string]string, dependencies []string, maxRetries int32, createdBy string) *Task {
 	now := time.Now()
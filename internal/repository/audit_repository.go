@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ErrAuditChainBroken 审计日志哈希链不完整，记录被修改、删除或插入
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// AuditFilter 审计事件查询条件，零值字段不参与过滤
+type AuditFilter struct {
+	UserID   string
+	Method   string
+	Resource string
+	Since    *time.Time
+	Until    *time.Time
+}
+
+// conditions 构建过滤条件
+func (f AuditFilter) conditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.Method != "" {
+		conditions = append(conditions, "method = ?")
+		args = append(args, f.Method)
+	}
+	if f.Resource != "" {
+		conditions = append(conditions, "resource = ?")
+		args = append(args, f.Resource)
+	}
+	if f.Since != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, formatTime(*f.Since))
+	}
+	if f.Until != nil {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, formatTime(*f.Until))
+	}
+	return conditions, args
+}
+
+// AuditRepository 审计日志仓储，只追加
+type AuditRepository struct {
+	db *SQLite
+}
+
+// NewAuditRepository 创建审计日志仓储
+func NewAuditRepository(db *SQLite) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditColumns = `seq, timestamp, user_id, auth_method, namespace, transport, method, resource, request_digest, code, prev_hash, hash`
+
+// Append 追加一条审计事件，填充 Seq、PrevHash 与 Hash
+// 读取链尾与写入在同一写事务中完成，保证链连续
+func (r *AuditRepository) Append(event *model.AuditEvent) error {
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var seq int64
+		var prevHash string
+		err := tx.QueryRow(`SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&seq, &prevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		event.Seq = seq + 1
+		event.PrevHash = prevHash
+		event.Hash = event.ComputeHash()
+
+		_, err = tx.Exec(`INSERT INTO audit_events (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.Seq, formatTime(event.Timestamp), event.UserID, event.AuthMethod, event.Namespace, event.Transport,
+			event.Method, event.Resource, event.RequestDigest, event.Code, event.PrevHash, event.Hash)
+		return err
+	})
+}
+
+// List 按 seq 倒序游标分页查询审计事件
+func (r *AuditRepository) List(filter AuditFilter, pageSize int, pageToken string) ([]*model.AuditEvent, string, error) {
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+
+	fingerprint := queryFingerprint(filter)
+	conditions, args := filter.conditions()
+	if pageToken != "" {
+		cursor, err := decodePageToken(pageToken, fingerprint, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		cond, condArgs := keysetCondition(nil, "seq", true, cursor)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + orderByClause(nil, "seq", true) + " LIMIT ?"
+	args = append(args, pageSize+1)
+
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var nextPageToken string
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		nextPageToken = encodePageToken(pageCursor{
+			Fingerprint: fingerprint,
+			Keys:        []interface{}{},
+			ID:          strconv.FormatInt(events[len(events)-1].Seq, 10),
+		})
+	}
+	return events, nextPageToken, nil
+}
+
+// Verify 按 seq 顺序校验整条哈希链，返回校验过的记录数
+// 发现断链或内容被修改时返回 ErrAuditChainBroken 及出错的 seq
+func (r *AuditRepository) Verify() (int64, error) {
+	rows, err := r.db.DB().Query(`SELECT ` + auditColumns + ` FROM audit_events ORDER BY seq`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	var prevHash string
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return count, err
+		}
+		if event.Seq != count+1 {
+			return count, fmt.Errorf("%w: expected seq %d, found %d", ErrAuditChainBroken, count+1, event.Seq)
+		}
+		if event.PrevHash != prevHash || event.ComputeHash() != event.Hash {
+			return count, fmt.Errorf("%w at seq %d", ErrAuditChainBroken, event.Seq)
+		}
+		prevHash = event.Hash
+		count++
+	}
+	return count, rows.Err()
+}
+
+// scanAuditEvent 扫描审计事件行
+func scanAuditEvent(row interface{ Scan(...interface{}) error }) (*model.AuditEvent, error) {
+	var event model.AuditEvent
+	var timestamp string
+	if err := row.Scan(&event.Seq, &timestamp, &event.UserID, &event.AuthMethod, &event.Namespace, &event.Transport,
+		&event.Method, &event.Resource, &event.RequestDigest, &event.Code, &event.PrevHash, &event.Hash); err != nil {
+		return nil, err
+	}
+	event.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp)
+	return &event, nil
+}
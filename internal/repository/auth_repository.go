@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RevokedToken 已吊销的令牌，过期后可清理
+type RevokedToken struct {
+	JTI       string
+	Subject   string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// APIKey 服务间调用使用的 API Key，只保存密钥的 SHA-256 摘要
+type APIKey struct {
+	ID         string
+	Name       string
+	Owner      string
+	KeyHash    string
+	Prefix     string // 密钥前几位，便于识别
+	Scopes     []string
+	Namespace  string // Key 调用时所在的命名空间
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// AuthRepository 认证相关数据仓储
+type AuthRepository struct {
+	db *SQLite
+}
+
+// NewAuthRepository 创建认证仓储
+func NewAuthRepository(db *SQLite) *AuthRepository {
+	return &AuthRepository{db: db}
+}
+
+// RevokeToken 记录吊销的令牌，重复吊销不报错
+func (r *AuthRepository) RevokeToken(token *RevokedToken) error {
+	_, err := r.db.Writer().Exec(`INSERT OR IGNORE INTO revoked_tokens (jti, subject, expires_at, revoked_at)
+		VALUES (?, ?, ?, ?)`, token.JTI, token.Subject, formatTime(token.ExpiresAt), formatTime(token.RevokedAt))
+	return err
+}
+
+// ListRevokedTokens 读取尚未过期的吊销记录
+func (r *AuthRepository) ListRevokedTokens(now time.Time) ([]*RevokedToken, error) {
+	rows, err := r.db.DB().Query(`SELECT jti, subject, expires_at, revoked_at FROM revoked_tokens WHERE expires_at > ?`,
+		formatTime(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*RevokedToken
+	for rows.Next() {
+		var token RevokedToken
+		var expiresAt, revokedAt string
+		if err := rows.Scan(&token.JTI, &token.Subject, &expiresAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		token.ExpiresAt, _ = time.Parse(time.RFC3339Nano, expiresAt)
+		token.RevokedAt, _ = time.Parse(time.RFC3339Nano, revokedAt)
+		tokens = append(tokens, &token)
+	}
+	return tokens, rows.Err()
+}
+
+// PruneRevokedTokens 删除已过期的吊销记录（过期令牌本身已无法通过校验），返回删除数量
+func (r *AuthRepository) PruneRevokedTokens(now time.Time) (int, error) {
+	result, err := r.db.Writer().Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, formatTime(now))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+const apiKeyColumns = `id, name, owner, key_hash, prefix, scopes, expires_at, last_used_at, created_at, namespace`
+
+// CreateAPIKey 保存 API Key
+func (r *AuthRepository) CreateAPIKey(key *APIKey) error {
+	scopes, err := json.Marshal(nonNilScopes(key.Scopes))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Writer().Exec(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Owner, key.KeyHash, key.Prefix, string(scopes),
+		nullableTime(key.ExpiresAt), nullableTime(key.LastUsedAt), formatTime(key.CreatedAt), key.Namespace)
+	return err
+}
+
+// GetAPIKey 按 ID 获取 API Key，不存在返回 nil
+func (r *AuthRepository) GetAPIKey(id string) (*APIKey, error) {
+	return r.getAPIKey(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id)
+}
+
+// GetAPIKeyByHash 按密钥摘要获取 API Key，不存在返回 nil
+func (r *AuthRepository) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	return r.getAPIKey(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, hash)
+}
+
+func (r *AuthRepository) getAPIKey(query string, arg string) (*APIKey, error) {
+	key, err := scanAPIKey(r.db.DB().QueryRow(query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// ListAPIKeys 列出 API Key，owner 为空时列出全部
+func (r *AuthRepository) ListAPIKeys(owner string) ([]*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	var args []interface{}
+	if owner != "" {
+		query += ` WHERE owner = ?`
+		args = append(args, owner)
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// UpdateAPIKey 更新名称、权限范围与过期时间
+func (r *AuthRepository) UpdateAPIKey(key *APIKey) error {
+	scopes, err := json.Marshal(nonNilScopes(key.Scopes))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Writer().Exec(`UPDATE api_keys SET name = ?, scopes = ?, expires_at = ? WHERE id = ?`,
+		key.Name, string(scopes), nullableTime(key.ExpiresAt), key.ID)
+	return err
+}
+
+// DeleteAPIKey 删除 API Key，返回是否存在
+func (r *AuthRepository) DeleteAPIKey(id string) (bool, error) {
+	result, err := r.db.Writer().Exec(`DELETE FROM api_keys WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIKey 记录最近使用时间
+func (r *AuthRepository) TouchAPIKey(id string, usedAt time.Time) error {
+	_, err := r.db.Writer().Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, formatTime(usedAt), id)
+	return err
+}
+
+// scanAPIKey 扫描 API Key 行
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var scopes, createdAt string
+	var expiresAt, lastUsedAt sql.NullString
+
+	if err := row.Scan(&key.ID, &key.Name, &key.Owner, &key.KeyHash, &key.Prefix, &scopes,
+		&expiresAt, &lastUsedAt, &createdAt, &key.Namespace); err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	if expiresAt.Valid {
+		key.ExpiresAt, _ = parseTime(expiresAt.String)
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt, _ = parseTime(lastUsedAt.String)
+	}
+	json.Unmarshal([]byte(scopes), &key.Scopes)
+
+	return &key, nil
+}
+
+// nonNilScopes 空权限范围存为 []
+func nonNilScopes(scopes []string) []string {
+	if scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInvalidSnapshot 快照文件不是可用的 TaskFlow 数据库
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// requiredTables 快照中必须存在的表
+var requiredTables = []string{"tasks", "task_events"}
+
+// SnapshotInfo 快照校验结果
+type SnapshotInfo struct {
+	Path   string
+	Size   int64
+	Tasks  int
+	Events int
+}
+
+// Backup 使用 VACUUM INTO 生成一致性在线快照
+// 先写入临时文件，完成后再原子重命名为 dest，避免留下半成品
+func (s *SQLite) Backup(ctx context.Context, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup target already exists: %s", dest)
+	}
+
+	tmp := dest + ".tmp"
+	_ = os.Remove(tmp)
+
+	if _, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := syncFile(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// ValidateSnapshot 以只读方式打开快照并检查完整性与表结构
+func ValidateSnapshot(path string) (*SnapshotInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrInvalidSnapshot, path)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if result != "ok" {
+		return nil, fmt.Errorf("%w: integrity check failed: %s", ErrInvalidSnapshot, result)
+	}
+
+	for _, table := range requiredTables {
+		var n int
+		err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%w: missing table %s", ErrInvalidSnapshot, table)
+		}
+	}
+
+	info := &SnapshotInfo{Path: path, Size: stat.Size()}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&info.Tasks); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM task_events`).Scan(&info.Events); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	return info, nil
+}
+
+// RestoreSnapshot 校验快照后替换数据库文件（服务需处于停止状态）
+// 原数据库及其 -wal/-shm 文件会被重命名保留，返回备份后的原数据库路径（不存在时为空）
+func RestoreSnapshot(snapshot, dbPath string) (string, error) {
+	if _, err := ValidateSnapshot(snapshot); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return "", err
+	}
+
+	// 先复制到目标目录，保证后续重命名在同一文件系统内完成
+	tmp := dbPath + ".restore"
+	if err := copyFile(snapshot, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().Format("20060102-150405"))
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if _, err := os.Stat(dbPath + suffix); err != nil {
+				continue
+			}
+			if err := os.Rename(dbPath+suffix, previous+suffix); err != nil {
+				_ = os.Remove(tmp)
+				return "", err
+			}
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return previous, err
+	}
+
+	// 补齐迁移并重建全文索引（VACUUM INTO 可能改变 rowid）
+	db, err := NewSQLite(dbPath)
+	if err != nil {
+		return previous, err
+	}
+	defer db.Close()
+
+	if err := db.InitSchema(); err != nil {
+		return previous, err
+	}
+	return previous, db.RebuildSearchIndex()
+}
+
+// copyFile 复制文件并落盘
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncFile 将文件内容刷入磁盘
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
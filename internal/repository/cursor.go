@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPageToken 分页令牌无效（格式错误或与查询条件不匹配）
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// pageCursor 游标分页令牌内容：上一页最后一行的排序键与 ID
+type pageCursor struct {
+	Fingerprint string        `json:"f"`
+	Keys        []interface{} `json:"k"`
+	ID          string        `json:"id"`
+}
+
+// sortKey 排序字段
+type sortKey struct {
+	Column string
+	Desc   bool
+}
+
+// encodePageToken 编码分页令牌（对客户端不透明）
+func encodePageToken(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken 解码分页令牌，并校验其与当前查询匹配
+func decodePageToken(token, fingerprint string, keyCount int) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	if c.Fingerprint != fingerprint || len(c.Keys) != keyCount || c.ID == "" {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &c, nil
+}
+
+// queryFingerprint 生成查询条件指纹，防止令牌跨查询复用
+// 使用 JSON 序列化：指针按值展开、map 按键排序，相同条件得到相同指纹
+func queryFingerprint(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// keysetCondition 构建 (k1, k2, ..., id) 严格位于游标之后的条件
+// 各排序键方向可不同；ID 作为最后的决胜键，方向由调用方通过 idDesc 指定，须与传给 orderByClause 的 idDesc 相同
+func keysetCondition(keys []sortKey, idColumn string, idDesc bool, c *pageCursor) (string, []interface{}) {
+	columns := append(append([]sortKey{}, keys...), sortKey{Column: idColumn, Desc: idDesc})
+	values := append(append([]interface{}{}, c.Keys...), c.ID)
+
+	var clauses []string
+	var args []interface{}
+	for i, key := range columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", key.Column, op))
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// orderByClause 生成与 keysetCondition 一致的排序子句
+func orderByClause(keys []sortKey, idColumn string, idDesc bool) string {
+	var parts []string
+	for _, key := range append(append([]sortKey{}, keys...), sortKey{Column: idColumn, Desc: idDesc}) {
+		if key.Desc {
+			parts = append(parts, key.Column+" DESC")
+		} else {
+			parts = append(parts, key.Column+" ASC")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,700 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"taskflow/internal/model"
+)
+
+// ErrInvalidFilter 过滤表达式无效
+var ErrInvalidFilter = errors.New("invalid filter")
+
+const (
+	maxFilterLength = 4096 // 表达式最大长度
+	maxFilterDepth  = 32   // 最大嵌套深度
+	maxFilterInList = 100  // IN 列表最大元素数
+)
+
+// FilterExpr 已解析的任务过滤表达式（AIP-160 子集）
+//
+// 语法：
+//
+//	expr       := term { OR term }
+//	term       := factor { AND factor }
+//	factor     := NOT factor | "(" expr ")" | comparison
+//	comparison := field op value | field [NOT] IN "(" value { "," value } ")"
+//	op         := = | != | < | <= | > | >=
+//
+// 字段：id、name、description、status、priority、task_type、created_by、error_message、
+// retry_count、max_retries、created_at、updated_at、started_at、completed_at，
+// 以及 input.<key>、output.<key>、labels.<key>（从 JSON 列中提取）。
+// 时间值支持 now、now-24h、now+30m、now-7d、"2024-01-01T00:00:00Z" 与 2024-01-01。
+//
+// 同一表达式既可编译为参数化 SQL（仓储查询），也可直接匹配内存中的任务（WatchTask）。
+type FilterExpr struct {
+	source string
+	root   filterNode
+}
+
+// ParseFilter 解析过滤表达式，空表达式返回 nil
+func ParseFilter(expr string) (*FilterExpr, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	if len(expr) > maxFilterLength {
+		return nil, fmt.Errorf("%w: expression longer than %d bytes", ErrInvalidFilter, maxFilterLength)
+	}
+
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	return &FilterExpr{source: expr, root: root}, nil
+}
+
+// String 返回原始表达式
+func (e *FilterExpr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.source
+}
+
+// Match 判断任务是否满足表达式，nil 表达式匹配所有任务
+func (e *FilterExpr) Match(task *model.Task) bool {
+	if e == nil {
+		return true
+	}
+	return e.root.match(task, time.Now())
+}
+
+// sql 编译为参数化 SQL 条件，now 用于解析相对时间
+func (e *FilterExpr) sql(now time.Time) (string, []interface{}) {
+	return e.root.sql(now)
+}
+
+// ========== 词法分析 ==========
+
+type filterTokenKind int
+
+const (
+	tokEOF filterTokenKind = iota
+	tokIdent
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+// isFilterIdentRune 标识符字符：字段名、枚举名、数字、日期与 now-24h 等相对时间
+func isFilterIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '+'
+}
+
+// lexFilter 将表达式切分为词法单元
+func lexFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{kind: tokComma, text: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string (at position %d)", ErrInvalidFilter, start+1)
+			}
+			i++
+			tokens = append(tokens, filterToken{kind: tokString, text: sb.String(), pos: start})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			i += len(op)
+			switch op {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			case "!":
+				return nil, fmt.Errorf("%w: unexpected \"!\" (at position %d)", ErrInvalidFilter, start+1)
+			}
+			tokens = append(tokens, filterToken{kind: tokOp, text: op, pos: start})
+		case isFilterIdentRune(r):
+			start := i
+			for i < len(runes) && isFilterIdentRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q (at position %d)", ErrInvalidFilter, r, i+1)
+		}
+	}
+
+	return append(tokens, filterToken{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// ========== 语法分析 ==========
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	depth  int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword 判断当前词法单元是否为关键字（不区分大小写）
+func (p *filterParser) isKeyword(kw string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && strings.EqualFold(tok.text, kw)
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s (at position %d)", ErrInvalidFilter, fmt.Sprintf(format, args...), tok.pos+1)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (filterNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxFilterDepth {
+		return nil, p.errorf(p.peek(), "expression nested too deeply")
+	}
+
+	if p.isKeyword("not") {
+		p.next()
+		inner, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner: inner}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, p.errorf(tok, "expected \")\"")
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	tok := p.next()
+	if tok.kind != tokIdent {
+		return nil, p.errorf(tok, "expected field name")
+	}
+	field, err := lookupFilterField(tok.text)
+	if err != nil {
+		return nil, p.errorf(tok, "%v", err)
+	}
+
+	node := &compareNode{field: field}
+
+	// [NOT] IN (...)
+	if p.isKeyword("not") || p.isKeyword("in") {
+		if p.isKeyword("not") {
+			p.next()
+			node.negate = true
+			if !p.isKeyword("in") {
+				return nil, p.errorf(p.peek(), "expected IN after NOT")
+			}
+		}
+		p.next()
+		node.op = "in"
+		if tok := p.next(); tok.kind != tokLParen {
+			return nil, p.errorf(tok, "expected \"(\" after IN")
+		}
+		for {
+			valueTok := p.next()
+			value, err := field.parseValue(valueTok)
+			if err != nil {
+				return nil, p.errorf(valueTok, "%v", err)
+			}
+			node.values = append(node.values, value)
+			if len(node.values) > maxFilterInList {
+				return nil, p.errorf(valueTok, "IN list longer than %d values", maxFilterInList)
+			}
+
+			sep := p.next()
+			if sep.kind == tokRParen {
+				break
+			}
+			if sep.kind != tokComma {
+				return nil, p.errorf(sep, "expected \",\" or \")\"")
+			}
+		}
+		return node, nil
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, p.errorf(opTok, "expected comparison operator after %q", field.name)
+	}
+	node.op = opTok.text
+
+	valueTok := p.next()
+	value, err := field.parseValue(valueTok)
+	if err != nil {
+		return nil, p.errorf(valueTok, "%v", err)
+	}
+	node.values = []interface{}{value}
+
+	return node, nil
+}
+
+// ========== 字段与取值 ==========
+
+type filterFieldKind int
+
+const (
+	fieldString filterFieldKind = iota
+	fieldInt
+	fieldStatus
+	fieldPriority
+	fieldTime
+)
+
+// filterField 可过滤字段
+type filterField struct {
+	name     string
+	kind     filterFieldKind
+	column   string // 数据库列
+	jsonKey  string // 非空时从 JSON 列中提取该键
+	nullable bool   // 列可能为 NULL，比较结果需要归一化为 false
+}
+
+// filterFields 可过滤的普通字段（白名单）
+var filterFields = map[string]filterField{
+	"id":            {kind: fieldString, column: "id"},
+	"name":          {kind: fieldString, column: "name"},
+	"description":   {kind: fieldString, column: "description"},
+	"status":        {kind: fieldStatus, column: "status"},
+	"priority":      {kind: fieldPriority, column: "priority"},
+	"task_type":     {kind: fieldString, column: "task_type"},
+	"created_by":    {kind: fieldString, column: "created_by"},
+	"error_message": {kind: fieldString, column: "error_message"},
+	"retry_count":   {kind: fieldInt, column: "retry_count"},
+	"max_retries":   {kind: fieldInt, column: "max_retries"},
+	"created_at":    {kind: fieldTime, column: "created_at"},
+	"updated_at":    {kind: fieldTime, column: "updated_at"},
+	"started_at":    {kind: fieldTime, column: "started_at", nullable: true},
+	"completed_at":  {kind: fieldTime, column: "completed_at", nullable: true},
+}
+
+// filterJSONColumns JSON 字段前缀到列的映射
+var filterJSONColumns = map[string]string{
+	"input":  "input_params",
+	"output": "output_result",
+	"labels": "labels",
+	"label":  "labels",
+}
+
+// filterJSONKeyPattern JSON 键限定字符集，避免构造 JSON 路径时的转义问题
+var filterJSONKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// lookupFilterField 查找字段定义
+func lookupFilterField(name string) (filterField, error) {
+	if field, ok := filterFields[name]; ok {
+		field.name = name
+		return field, nil
+	}
+
+	if prefix, key, ok := strings.Cut(name, "."); ok {
+		if column, ok := filterJSONColumns[prefix]; ok {
+			if !filterJSONKeyPattern.MatchString(key) {
+				return filterField{}, fmt.Errorf("invalid key %q", key)
+			}
+			return filterField{name: name, kind: fieldString, column: column, jsonKey: key, nullable: true}, nil
+		}
+	}
+
+	return filterField{}, fmt.Errorf("unknown field %q", name)
+}
+
+// timeValue 时间比较值，relative 为 true 时相对于当前时间
+type timeValue struct {
+	abs      time.Time
+	offset   time.Duration
+	relative bool
+}
+
+func (v timeValue) resolve(now time.Time) time.Time {
+	if v.relative {
+		return now.Add(v.offset)
+	}
+	return v.abs
+}
+
+// parseValue 按字段类型解析比较值：int64、string 或 timeValue
+func (f filterField) parseValue(tok filterToken) (interface{}, error) {
+	if tok.kind != tokIdent && tok.kind != tokString {
+		return nil, fmt.Errorf("expected value for %q", f.name)
+	}
+
+	switch f.kind {
+	case fieldString:
+		return tok.text, nil
+	case fieldInt:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q expects an integer, got %q", f.name, tok.text)
+		}
+		return n, nil
+	case fieldStatus:
+		return parseEnumValue(tok.text, "TASK_STATUS_", func(i int32) string { return model.TaskStatus(i).String() }, int32(model.TaskStatusTimeout))
+	case fieldPriority:
+		return parseEnumValue(tok.text, "TASK_PRIORITY_", func(i int32) string { return model.TaskPriority(i).String() }, int32(model.TaskPriorityUrgent))
+	case fieldTime:
+		return parseTimeValue(tok.text)
+	}
+
+	return nil, fmt.Errorf("unsupported field %q", f.name)
+}
+
+// parseEnumValue 解析枚举值：数值、名称（FAILED）或完整枚举名（TASK_STATUS_FAILED）
+func parseEnumValue(text, prefix string, name func(int32) string, max int32) (interface{}, error) {
+	if n, err := strconv.ParseInt(text, 10, 32); err == nil {
+		return n, nil
+	}
+
+	upper := strings.TrimPrefix(strings.ToUpper(text), prefix)
+	for i := int32(1); i <= max; i++ {
+		if name(i) == upper {
+			return int64(i), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown value %q", text)
+}
+
+// parseTimeValue 解析时间：now、now±duration（支持 d 表示天）、RFC3339 或日期
+func parseTimeValue(text string) (interface{}, error) {
+	lower := strings.ToLower(text)
+	if lower == "now" {
+		return timeValue{relative: true}, nil
+	}
+	if strings.HasPrefix(lower, "now-") || strings.HasPrefix(lower, "now+") {
+		offset, err := parseFilterDuration(lower[4:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in %q", text)
+		}
+		if lower[3] == '-' {
+			offset = -offset
+		}
+		return timeValue{offset: offset, relative: true}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+		return timeValue{abs: t}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", text, time.Local); err == nil {
+		return timeValue{abs: t}, nil
+	}
+	return nil, fmt.Errorf("invalid time %q", text)
+}
+
+// parseFilterDuration 解析时长，在 time.ParseDuration 基础上支持天（7d）
+func parseFilterDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// sqlExpr 字段对应的 SQL 表达式
+func (f filterField) sqlExpr() (string, []interface{}) {
+	if f.jsonKey != "" {
+		return fmt.Sprintf("json_extract(%s, ?)", f.column), []interface{}{`$."` + f.jsonKey + `"`}
+	}
+	return f.column, nil
+}
+
+// value 取任务上的字段值，字段不存在（NULL 或 JSON 键缺失）时返回 false
+func (f filterField) value(task *model.Task) (interface{}, bool) {
+	if f.jsonKey != "" {
+		var m map[string]string
+		switch f.column {
+		case "input_params":
+			m = task.InputParams
+		case "output_result":
+			m = task.OutputResult
+		case "labels":
+			m = task.Labels
+		}
+		v, ok := m[f.jsonKey]
+		return v, ok
+	}
+
+	switch f.column {
+	case "id":
+		return task.ID, true
+	case "name":
+		return task.Name, true
+	case "description":
+		return task.Description, true
+	case "status":
+		return int64(task.Status), true
+	case "priority":
+		return int64(task.Priority), true
+	case "task_type":
+		return task.TaskType, true
+	case "created_by":
+		return task.CreatedBy, true
+	case "error_message":
+		return task.ErrorMessage, true
+	case "retry_count":
+		return int64(task.RetryCount), true
+	case "max_retries":
+		return int64(task.MaxRetries), true
+	case "created_at":
+		return task.CreatedAt, true
+	case "updated_at":
+		return task.UpdatedAt, true
+	case "started_at":
+		if task.StartedAt == nil {
+			return nil, false
+		}
+		return *task.StartedAt, true
+	case "completed_at":
+		if task.CompletedAt == nil {
+			return nil, false
+		}
+		return *task.CompletedAt, true
+	}
+	return nil, false
+}
+
+// ========== 语法树 ==========
+
+// filterNode 表达式节点
+type filterNode interface {
+	sql(now time.Time) (string, []interface{})
+	match(task *model.Task, now time.Time) bool
+}
+
+// logicalNode AND / OR
+type logicalNode struct {
+	op          string
+	left, right filterNode
+}
+
+func (n *logicalNode) sql(now time.Time) (string, []interface{}) {
+	left, leftArgs := n.left.sql(now)
+	right, rightArgs := n.right.sql(now)
+	return "(" + left + " " + n.op + " " + right + ")", append(leftArgs, rightArgs...)
+}
+
+func (n *logicalNode) match(task *model.Task, now time.Time) bool {
+	if n.op == "AND" {
+		return n.left.match(task, now) && n.right.match(task, now)
+	}
+	return n.left.match(task, now) || n.right.match(task, now)
+}
+
+// notNode NOT
+type notNode struct {
+	inner filterNode
+}
+
+func (n *notNode) sql(now time.Time) (string, []interface{}) {
+	inner, args := n.inner.sql(now)
+	return "(NOT " + inner + ")", args
+}
+
+func (n *notNode) match(task *model.Task, now time.Time) bool {
+	return !n.inner.match(task, now)
+}
+
+// compareNode 字段比较
+type compareNode struct {
+	field  filterField
+	op     string // =, !=, <, <=, >, >=, in
+	negate bool   // NOT IN
+	values []interface{}
+}
+
+func (n *compareNode) sql(now time.Time) (string, []interface{}) {
+	lhs, args := n.field.sqlExpr()
+
+	var expr string
+	if n.op == "in" {
+		op := "IN"
+		if n.negate {
+			op = "NOT IN"
+		}
+		expr = fmt.Sprintf("%s %s (%s)", lhs, op, placeholders(len(n.values)))
+	} else {
+		expr = fmt.Sprintf("%s %s ?", lhs, n.op)
+	}
+	for _, v := range n.values {
+		args = append(args, sqlFilterValue(v, now))
+	}
+
+	// NULL 参与比较结果为 NULL，归一化为 false，与内存匹配语义一致
+	if n.field.nullable {
+		expr = "IFNULL(" + expr + ", 0)"
+	}
+	return "(" + expr + ")", args
+}
+
+func (n *compareNode) match(task *model.Task, now time.Time) bool {
+	actual, ok := n.field.value(task)
+	if !ok {
+		return false
+	}
+
+	if n.op == "in" {
+		found := false
+		for _, v := range n.values {
+			if compareFilterValues(actual, v, now) == 0 {
+				found = true
+				break
+			}
+		}
+		return found != n.negate
+	}
+
+	c := compareFilterValues(actual, n.values[0], now)
+	switch n.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// sqlFilterValue 比较值转换为 SQL 参数，时间使用存储格式
+func sqlFilterValue(v interface{}, now time.Time) interface{} {
+	if tv, ok := v.(timeValue); ok {
+		return formatTime(tv.resolve(now))
+	}
+	return v
+}
+
+// compareFilterValues 比较任务字段值与表达式值
+// 时间按存储格式比较，与 SQL 中的字符串比较结果一致
+func compareFilterValues(actual, expected interface{}, now time.Time) int {
+	switch a := actual.(type) {
+	case int64:
+		b, _ := expected.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		b, _ := expected.(string)
+		return strings.Compare(a, b)
+	case time.Time:
+		b, _ := expected.(timeValue)
+		return strings.Compare(formatTime(a), formatTime(b.resolve(now)))
+	}
+	return -1
+}
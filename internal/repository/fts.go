@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ftsRank bm25 排序表达式，权重顺序与 tasks_fts 列定义一致：
+// name, description, task_type, input_params, output_result, error_message
+const ftsRank = "bm25(tasks_fts, 10.0, 5.0, 3.0, 1.0, 1.0, 2.0)"
+
+// ftsMatchJoin 将 FTS 命中结果（含相关度）连接到 tasks 表
+const ftsMatchJoin = ` JOIN (SELECT rowid AS fts_rowid, ` + ftsRank + ` AS fts_rank
+	FROM tasks_fts WHERE tasks_fts MATCH ?) AS fts ON fts.fts_rowid = tasks.rowid`
+
+// likeSearchCondition FTS 不可用时的降级搜索条件
+const likeSearchCondition = `(name LIKE ? OR description LIKE ? OR task_type LIKE ?
+	OR input_params LIKE ? OR output_result LIKE ? OR error_message LIKE ?)`
+
+// likeSearchArgs 生成降级搜索条件的参数
+func likeSearchArgs(keyword string) []interface{} {
+	pattern := "%" + keyword + "%"
+	return []interface{}{pattern, pattern, pattern, pattern, pattern, pattern}
+}
+
+// buildFTSQuery 将用户输入的关键词转换为安全的 FTS5 查询表达式
+// 支持 "短语查询" 与 前缀* 查询，其余词项按 AND 组合；
+// 每个词项都会被加引号，避免 FTS5 运算符注入导致语法错误
+func buildFTSQuery(keyword string) string {
+	var terms []string
+	rs := []rune(keyword)
+
+	for i := 0; i < len(rs); {
+		switch {
+		case unicode.IsSpace(rs[i]):
+			i++
+		case rs[i] == '"':
+			// 短语查询
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if phrase := strings.TrimSpace(string(rs[i+1 : j])); phrase != "" {
+				terms = append(terms, quoteFTS(phrase))
+			}
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '"' {
+				j++
+			}
+			token := string(rs[i:j])
+			prefix := strings.HasSuffix(token, "*")
+			if token = strings.TrimRight(token, "*"); token != "" {
+				term := quoteFTS(token)
+				if prefix {
+					term += "*"
+				}
+				terms = append(terms, term)
+			}
+			i = j
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// quoteFTS 将字符串转义为 FTS5 字符串字面量
+func quoteFTS(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
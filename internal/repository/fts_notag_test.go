@@ -0,0 +1,6 @@
+//go:build !sqlite_fts5
+
+package repository
+
+// ftsBuildTag 未使用 sqlite_fts5 标签构建，全文搜索测试跳过
+const ftsBuildTag = false
@@ -0,0 +1,6 @@
+//go:build sqlite_fts5
+
+package repository
+
+// ftsBuildTag 使用 sqlite_fts5 标签构建时全文索引必须可用，测试不能跳过
+const ftsBuildTag = true
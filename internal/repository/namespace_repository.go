@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+var (
+	// ErrNamespaceExists 命名空间已存在
+	ErrNamespaceExists = errors.New("namespace already exists")
+	// ErrNamespaceNotEmpty 命名空间中仍有任务
+	ErrNamespaceNotEmpty = errors.New("namespace still has tasks")
+)
+
+// Namespace 命名空间及其配额，配额为 0 表示不限制
+type Namespace struct {
+	Name           string
+	Description    string
+	MaxTasks       int // 任务总数上限（含终态任务）
+	MaxActiveTasks int // PENDING 与 RUNNING 任务数上限
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NamespaceUsage 命名空间当前用量
+type NamespaceUsage struct {
+	Tasks       int
+	ActiveTasks int
+}
+
+// NamespaceRepository 命名空间仓储
+type NamespaceRepository struct {
+	db *SQLite
+}
+
+// NewNamespaceRepository 创建命名空间仓储
+func NewNamespaceRepository(db *SQLite) *NamespaceRepository {
+	return &NamespaceRepository{db: db}
+}
+
+const namespaceColumns = `name, description, max_tasks, max_active_tasks, created_at, updated_at`
+
+// Create 创建命名空间，已存在返回 ErrNamespaceExists
+func (r *NamespaceRepository) Create(ns *Namespace) error {
+	result, err := r.db.Writer().Exec(`INSERT OR IGNORE INTO namespaces (`+namespaceColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		ns.Name, ns.Description, ns.MaxTasks, ns.MaxActiveTasks, formatTime(ns.CreatedAt), formatTime(ns.UpdatedAt))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNamespaceExists
+	}
+	return nil
+}
+
+// Get 获取命名空间，不存在返回 nil
+func (r *NamespaceRepository) Get(name string) (*Namespace, error) {
+	ns, err := scanNamespace(r.db.DB().QueryRow(`SELECT `+namespaceColumns+` FROM namespaces WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return ns, err
+}
+
+// List 按名称列出全部命名空间
+func (r *NamespaceRepository) List() ([]*Namespace, error) {
+	rows, err := r.db.DB().Query(`SELECT ` + namespaceColumns + ` FROM namespaces ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var namespaces []*Namespace
+	for rows.Next() {
+		ns, err := scanNamespace(rows)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
+
+// Update 更新描述与配额
+func (r *NamespaceRepository) Update(ns *Namespace) error {
+	_, err := r.db.Writer().Exec(`UPDATE namespaces SET description = ?, max_tasks = ?, max_active_tasks = ?, updated_at = ? WHERE name = ?`,
+		ns.Description, ns.MaxTasks, ns.MaxActiveTasks, formatTime(ns.UpdatedAt), ns.Name)
+	return err
+}
+
+// Delete 删除命名空间，返回是否存在；仍有任务时返回 ErrNamespaceNotEmpty
+// 检查与删除在同一事务中完成，避免删除期间有新任务写入
+func (r *NamespaceRepository) Delete(name string) (bool, error) {
+	var found bool
+	err := r.db.ExecTx(func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow(`SELECT 1 FROM tasks WHERE namespace = ? LIMIT 1`, name).Scan(&exists)
+		if err == nil {
+			return ErrNamespaceNotEmpty
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM namespaces WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		found = n > 0
+		return err
+	})
+	return found, err
+}
+
+// Usage 统计命名空间的任务数与活跃任务数
+func (r *NamespaceRepository) Usage(name string) (NamespaceUsage, error) {
+	var usage NamespaceUsage
+	err := r.db.DB().QueryRow(`SELECT COUNT(*), COALESCE(SUM(CASE WHEN status IN (?, ?) THEN 1 ELSE 0 END), 0)
+		FROM tasks WHERE namespace = ?`, model.TaskStatusPending, model.TaskStatusRunning, name).
+		Scan(&usage.Tasks, &usage.ActiveTasks)
+	return usage, err
+}
+
+// scanNamespace 扫描命名空间行
+func scanNamespace(row interface{ Scan(...interface{}) error }) (*Namespace, error) {
+	var ns Namespace
+	var description sql.NullString
+	var createdAt, updatedAt string
+
+	if err := row.Scan(&ns.Name, &description, &ns.MaxTasks, &ns.MaxActiveTasks, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	ns.Description = description.String
+	ns.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	ns.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	return &ns, nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// insertOutboxQuery 写入 outbox 记录
+const insertOutboxQuery = `INSERT INTO task_outbox (
+	task_id, change_type, from_status, to_status, payload, created_at
+) VALUES (?, ?, ?, ?, ?, ?)`
+
+// outboxEmitter 在当前事务中写入一条 outbox 记录
+type outboxEmitter func(changeType string, task *model.Task, fromStatus, toStatus model.TaskStatus) error
+
+// withOutbox 在写事务中执行 fn，fn 通过 emit 写入 outbox 记录，提交成功后通知中继
+// 写连接只有一个，语句必须在事务开始前预编译，否则会与事务争用连接
+func (r *TaskRepository) withOutbox(fn func(tx *sql.Tx, emit outboxEmitter) error) error {
+	stmt, err := r.db.writeStmt(insertOutboxQuery)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.ExecTx(func(tx *sql.Tx) error {
+		txStmt := tx.Stmt(stmt)
+		emit := func(changeType string, task *model.Task, fromStatus, toStatus model.TaskStatus) error {
+			// 快照推送给订阅者与 Webhook，机密参数只保留参数名
+			snapshot := *task
+			snapshot.Events = nil
+			snapshot.SecretParams = task.RedactedSecretParams()
+			payload, err := json.Marshal(&snapshot)
+			if err != nil {
+				return err
+			}
+			_, err = txStmt.Exec(task.ID, changeType, fromStatus, toStatus, string(payload), formatTime(time.Now()))
+			return err
+		}
+		return fn(tx, emit)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.notifyOutbox()
+	return nil
+}
+
+// getTaskTx 在事务中读取任务（不含事件），不存在返回 nil
+func (r *TaskRepository) getTaskTx(tx *sql.Tx, id string) (*model.Task, error) {
+	task, err := r.scanTask(tx.QueryRow(getTaskByIDQuery, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return task, err
+}
+
+// notifyOutbox 通知中继有新的 outbox 记录（非阻塞）
+func (r *TaskRepository) notifyOutbox() {
+	select {
+	case r.outboxCh <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxNotify 返回 outbox 写入通知通道，事务提交后触发
+func (r *TaskRepository) OutboxNotify() <-chan struct{} {
+	return r.outboxCh
+}
+
+// ListUnpublishedOutbox 按提交顺序（seq 升序）读取未发布的 outbox 记录
+func (r *TaskRepository) ListUnpublishedOutbox(limit int) ([]*model.OutboxEntry, error) {
+	return r.queryOutbox(`SELECT seq, task_id, change_type, from_status, to_status, payload, created_at
+		FROM task_outbox WHERE published_at IS NULL ORDER BY seq ASC LIMIT ?`, limit)
+}
+
+// queryOutbox 查询并解析 outbox 记录
+func (r *TaskRepository) queryOutbox(query string, args ...interface{}) ([]*model.OutboxEntry, error) {
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*model.OutboxEntry
+	for rows.Next() {
+		var entry model.OutboxEntry
+		var payload, createdAt string
+		if err := rows.Scan(&entry.Seq, &entry.TaskID, &entry.ChangeType, &entry.FromStatus, &entry.ToStatus, &payload, &createdAt); err != nil {
+			return nil, err
+		}
+
+		var task model.Task
+		if err := json.Unmarshal([]byte(payload), &task); err != nil {
+			return nil, err
+		}
+		entry.Task = &task
+		entry.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// MarkOutboxPublished 将 seq 不大于 uptoSeq 的记录标记为已发布，返回新标记的数量
+func (r *TaskRepository) MarkOutboxPublished(uptoSeq int64) (int, error) {
+	result, err := r.db.Writer().Exec(`UPDATE task_outbox SET published_at = ? WHERE seq <= ? AND published_at IS NULL`,
+		formatTime(time.Now()), uptoSeq)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// LastPublishedOutboxSeq 返回已发布记录的最大 seq，没有时为 0
+func (r *TaskRepository) LastPublishedOutboxSeq() (int64, error) {
+	var seq int64
+	err := r.db.DB().QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM task_outbox WHERE published_at IS NOT NULL`).Scan(&seq)
+	return seq, err
+}
+
+// PruneOutbox 删除 before 之前已发布的记录，返回删除数量
+func (r *TaskRepository) PruneOutbox(before time.Time) (int, error) {
+	result, err := r.db.Writer().Exec(`DELETE FROM task_outbox WHERE published_at IS NOT NULL AND published_at < ?`,
+		formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// ListOutboxSince 按 seq 升序读取 seq 大于 afterSeq 的记录（含未发布），用于断线续传回放
+func (r *TaskRepository) ListOutboxSince(afterSeq int64, limit int) ([]*model.OutboxEntry, error) {
+	return r.queryOutbox(`SELECT seq, task_id, change_type, from_status, to_status, payload, created_at
+		FROM task_outbox WHERE seq > ? ORDER BY seq ASC LIMIT ?`, afterSeq, limit)
+}
+
+// OutboxBounds 返回仍保留的最小 seq 与已分配的最大 seq，表为空时 oldest 为 0
+func (r *TaskRepository) OutboxBounds() (oldest, latest int64, err error) {
+	if err = r.db.DB().QueryRow(`SELECT COALESCE(MIN(seq), 0) FROM task_outbox`).Scan(&oldest); err != nil {
+		return 0, 0, err
+	}
+	// 记录被清理后 MAX(seq) 会变小，已分配的序号以 sqlite_sequence 为准
+	err = r.db.DB().QueryRow(`SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'task_outbox'), 0)`).Scan(&latest)
+	if err != nil {
+		return 0, 0, err
+	}
+	return oldest, latest, nil
+}
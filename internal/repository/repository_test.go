@@ -1,14 +1,21 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"taskflow/internal/model"
 )
 
 // setupTestDB 创建测试数据库
-func setupTestDB(t *testing.T) (*SQLite, func()) {
+func setupTestDB(t testing.TB) (*SQLite, func()) {
 	tmpFile, err := os.CreateTemp("", "taskflow_test_*.db")
 	if err != nil {
 		t.Fatalf("failed to create temp file: %v", err)
@@ -511,3 +518,1059 @@ func TestTaskRepository_ListByFilter(t *testing.T) {
 		t.Errorf("expected 2 results, got %d", len(results3))
 	}
 }
+
+func TestTaskRepository_FullTextSearch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if !db.FTSEnabled() {
+		if ftsBuildTag {
+			t.Fatal("built with sqlite_fts5 but FTS5 is not available")
+		}
+		t.Skip("FTS5 not available, build with -tags sqlite_fts5")
+	}
+
+	repo := NewTaskRepository(db)
+
+	tasks := []*model.Task{
+		model.NewTask("Nightly ETL", "load warehouse", model.TaskPriorityNormal, "etl", map[string]string{"region": "eu-west"}, nil, 3, "test"),
+		model.NewTask("Report", "nightly etl summary report", model.TaskPriorityNormal, "report", nil, nil, 3, "test"),
+		model.NewTask("Cleanup", "remove temp files", model.TaskPriorityNormal, "maintenance", nil, nil, 3, "test"),
+	}
+	tasks[0].ID = "fts-test-1"
+	tasks[1].ID = "fts-test-2"
+	tasks[2].ID = "fts-test-3"
+	for _, task := range tasks {
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	// 名称命中的任务排在描述命中之前
+	results, err := repo.Search("etl", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != "fts-test-1" {
+		t.Errorf("expected fts-test-1 ranked first, got %s", results[0].ID)
+	}
+
+	// 短语查询
+	results, err = repo.Search(`"etl summary"`, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search phrase: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "fts-test-2" {
+		t.Errorf("expected phrase to match fts-test-2, got %d results", len(results))
+	}
+
+	// 前缀查询
+	results, err = repo.Search("maint*", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search prefix: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "fts-test-3" {
+		t.Errorf("expected prefix to match fts-test-3, got %d results", len(results))
+	}
+
+	// 搜索输入参数
+	results, err = repo.Search("eu", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search params: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "fts-test-1" {
+		t.Errorf("expected input params to match fts-test-1, got %d results", len(results))
+	}
+
+	// 更新后索引同步
+	tasks[2].ErrorMessage = "disk quota exceeded"
+	if err := repo.Update(tasks[2]); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+	results, err = repo.Search("quota", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search error message: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected 1 result after update, got %d", len(results))
+	}
+
+	// 删除后索引同步
+	if err := repo.Delete("fts-test-3"); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	results, err = repo.Search("quota", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to search after delete: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected 0 results after delete, got %d", len(results))
+	}
+
+	// 运算符字符不会导致语法错误
+	if _, err := repo.Search(`etl AND -( NEAR`, 10, 0); err != nil {
+		t.Errorf("unexpected error for operator input: %v", err)
+	}
+
+	// ListByFilter 关键词走全文索引
+	_, total, err := repo.ListByFilter(TaskFilter{Keyword: "nightly", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to filter by keyword: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected 2 total, got %d", total)
+	}
+}
+
+func TestTaskRepository_KeywordCursorRanking(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if !db.FTSEnabled() {
+		if ftsBuildTag {
+			t.Fatal("built with sqlite_fts5 but FTS5 is not available")
+		}
+		t.Skip("FTS5 not available, build with -tags sqlite_fts5")
+	}
+
+	repo := NewTaskRepository(db)
+
+	// 名称命中 > 描述命中 > 输入参数命中，创建顺序与相关度相反
+	tasks := []*model.Task{
+		model.NewTask("cleanup", "", model.TaskPriorityNormal, "maintenance", map[string]string{"target": "backup"}, nil, 3, "test"),
+		model.NewTask("report", "weekly backup report", model.TaskPriorityNormal, "report", nil, nil, 3, "test"),
+		model.NewTask("verify", "backup", model.TaskPriorityNormal, "maintenance", nil, nil, 3, "test"),
+		model.NewTask("backup", "", model.TaskPriorityNormal, "maintenance", nil, nil, 3, "test"),
+		model.NewTask("unrelated", "", model.TaskPriorityNormal, "maintenance", nil, nil, 3, "test"),
+	}
+	for i, task := range tasks {
+		task.ID = fmt.Sprintf("rank-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	expected, _, err := repo.ListByFilter(TaskFilter{Keyword: "backup", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to filter by keyword: %v", err)
+	}
+	if len(expected) != 4 || expected[0].ID != "rank-3" {
+		t.Fatalf("expected name match ranked first, got %v", taskIDs(expected))
+	}
+
+	// 游标分页逐页读取，顺序与按相关度的偏移分页一致
+	var got []*model.Task
+	filter := TaskFilter{Keyword: "backup", PageSize: 1}
+	for {
+		page, err := repo.ListByCursor(filter)
+		if err != nil {
+			t.Fatalf("failed to list by cursor: %v", err)
+		}
+		if page.Total != 4 {
+			t.Errorf("expected total 4, got %d", page.Total)
+		}
+		got = append(got, page.Tasks...)
+		if page.NextPageToken == "" {
+			break
+		}
+		filter.PageToken = page.NextPageToken
+	}
+	if fmt.Sprint(taskIDs(got)) != fmt.Sprint(taskIDs(expected)) {
+		t.Errorf("expected cursor order %v, got %v", taskIDs(expected), taskIDs(got))
+	}
+}
+
+func TestBuildFTSQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"etl", `"etl"`},
+		{"etl report", `"etl" "report"`},
+		{`"nightly etl" report`, `"nightly etl" "report"`},
+		{"rep*", `"rep"*`},
+		{`a"b`, `"a" "b"`},
+		{"AND OR NOT", `"AND" "OR" "NOT"`},
+		{"*", ""},
+	}
+
+	for _, tt := range tests {
+		if got := buildFTSQuery(tt.input); got != tt.expected {
+			t.Errorf("buildFTSQuery(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestTaskRepository_ListByCursor(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	for i := 0; i < 25; i++ {
+		task := model.NewTask(fmt.Sprintf("Cursor Task %d", i), "desc", model.TaskPriority(i%3+1), "test", nil, nil, 3, "test")
+		task.ID = fmt.Sprintf("cursor-test-%02d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	filter := TaskFilter{PageSize: 10}
+	pages := 0
+	for {
+		page, err := repo.ListByCursor(filter)
+		if err != nil {
+			t.Fatalf("failed to list by cursor: %v", err)
+		}
+		if page.Total < 25 {
+			t.Errorf("expected total >= 25, got %d", page.Total)
+		}
+		for _, task := range page.Tasks {
+			if seen[task.ID] {
+				t.Errorf("task %s returned twice", task.ID)
+			}
+			seen[task.ID] = true
+		}
+		pages++
+
+		// 翻页期间插入新任务不影响后续页
+		if pages == 1 {
+			task := model.NewTask("Inserted", "desc", model.TaskPriorityUrgent, "test", nil, nil, 3, "test")
+			task.ID = "cursor-test-inserted"
+			if err := repo.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		filter.PageToken = page.NextPageToken
+	}
+
+	for i := 0; i < 25; i++ {
+		if id := fmt.Sprintf("cursor-test-%02d", i); !seen[id] {
+			t.Errorf("task %s was skipped", id)
+		}
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+
+	// 令牌与查询条件不匹配
+	status := model.TaskStatusRunning
+	_, err := repo.ListByCursor(TaskFilter{Status: &status, PageSize: 10, PageToken: filter.PageToken})
+	if !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected ErrInvalidPageToken for mismatched filter, got %v", err)
+	}
+
+	// 格式错误的令牌
+	_, err = repo.ListByCursor(TaskFilter{PageSize: 10, PageToken: "not-a-token"})
+	if !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected ErrInvalidPageToken for malformed token, got %v", err)
+	}
+}
+
+func TestTaskRepository_ListEventsByTaskID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Events Page Test", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "test")
+	task.ID = "events-page-test-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	base := time.Now()
+	for i := 0; i < 7; i++ {
+		event := &model.TaskEvent{
+			ID:        fmt.Sprintf("events-page-%d", i),
+			TaskID:    task.ID,
+			ToStatus:  model.TaskStatusPending,
+			Message:   fmt.Sprintf("event %d", i),
+			Timestamp: base.Add(time.Duration(i/2) * time.Second),
+			Operator:  "test",
+		}
+		if err := repo.AddEvent(event); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+
+	var all []model.TaskEvent
+	token := ""
+	for {
+		events, next, err := repo.ListEventsByTaskID(task.ID, 3, token)
+		if err != nil {
+			t.Fatalf("failed to list events: %v", err)
+		}
+		all = append(all, events...)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	if len(all) != 7 {
+		t.Fatalf("expected 7 events, got %d", len(all))
+	}
+	for i, event := range all {
+		if expected := fmt.Sprintf("events-page-%d", i); event.ID != expected {
+			t.Errorf("expected event %s at position %d, got %s", expected, i, event.ID)
+		}
+	}
+
+	// 其他任务的令牌不可复用
+	if _, _, err := repo.ListEventsByTaskID("other-task", 3, token); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected ErrInvalidPageToken, got %v", err)
+	}
+}
+
+func TestTaskRepository_ListByFilterRich(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	now := time.Now()
+	tasks := []*model.Task{
+		{ID: "rich-1", Name: "alpha", Status: model.TaskStatusFailed, Priority: model.TaskPriorityHigh, TaskType: "etl", CreatedBy: "alice", ErrorMessage: "boom", Labels: map[string]string{"team": "data", "env": "prod"}},
+		{ID: "rich-2", Name: "bravo", Status: model.TaskStatusTimeout, Priority: model.TaskPriorityLow, TaskType: "report", CreatedBy: "bob", Labels: map[string]string{"team": "data"}},
+		{ID: "rich-3", Name: "charlie", Status: model.TaskStatusSucceeded, Priority: model.TaskPriorityNormal, TaskType: "etl", CreatedBy: "alice", Labels: map[string]string{"team": "web", "env": "prod"}},
+		{ID: "rich-4", Name: "delta", Status: model.TaskStatusPending, Priority: model.TaskPriorityUrgent, TaskType: "sync", CreatedBy: "carol"},
+	}
+	for i, task := range tasks {
+		task.CreatedAt = now.Add(time.Duration(i-4) * time.Hour)
+		task.UpdatedAt = task.CreatedAt
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	ids := func(result []*model.Task) []string {
+		var out []string
+		for _, task := range result {
+			out = append(out, task.ID)
+		}
+		return out
+	}
+	boolPtr := func(b bool) *bool { return &b }
+	timePtr := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name     string
+		filter   TaskFilter
+		expected []string
+	}{
+		{"multi status", TaskFilter{Statuses: []model.TaskStatus{model.TaskStatusFailed, model.TaskStatusTimeout}, SortBy: "name"}, []string{"rich-1", "rich-2"}},
+		{"multi type", TaskFilter{TaskTypes: []string{"etl", "sync"}, SortBy: "name", SortDesc: true}, []string{"rich-4", "rich-3", "rich-1"}},
+		{"created by", TaskFilter{CreatedBy: "alice", SortBy: "created_at"}, []string{"rich-1", "rich-3"}},
+		{"created range", TaskFilter{CreatedAfter: timePtr(now.Add(-3 * time.Hour)), CreatedBefore: timePtr(now.Add(-1 * time.Hour)), SortBy: "created_at"}, []string{"rich-2", "rich-3"}},
+		{"has error", TaskFilter{HasError: boolPtr(true)}, []string{"rich-1"}},
+		{"no error", TaskFilter{HasError: boolPtr(false), SortBy: "priority", SortDesc: true}, []string{"rich-4", "rich-3", "rich-2"}},
+		{"labels", TaskFilter{Labels: map[string]string{"team": "data", "env": "prod"}}, []string{"rich-1"}},
+		{"default sort", TaskFilter{}, []string{"rich-4", "rich-1", "rich-3", "rich-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, total, err := repo.ListByFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to list by filter: %v", err)
+			}
+			if total != len(tt.expected) {
+				t.Errorf("expected total %d, got %d", len(tt.expected), total)
+			}
+			if got := fmt.Sprint(ids(result)); got != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+
+			page, err := repo.ListByCursor(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to list by cursor: %v", err)
+			}
+			if got := fmt.Sprint(ids(page.Tasks)); got != fmt.Sprint(tt.expected) {
+				t.Errorf("cursor: expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	_, _, err := repo.ListByFilter(TaskFilter{SortBy: "input_params"})
+	if !errors.Is(err, ErrInvalidSortField) {
+		t.Errorf("expected ErrInvalidSortField, got %v", err)
+	}
+
+	// 相同条件的不同实例可复用分页令牌
+	status := model.TaskStatusFailed
+	page, err := repo.ListByCursor(TaskFilter{Statuses: []model.TaskStatus{status, model.TaskStatusSucceeded}, SortBy: "name", PageSize: 1})
+	if err != nil {
+		t.Fatalf("failed to list by cursor: %v", err)
+	}
+	other := model.TaskStatusFailed
+	next, err := repo.ListByCursor(TaskFilter{Statuses: []model.TaskStatus{other, model.TaskStatusSucceeded}, SortBy: "name", PageSize: 1, PageToken: page.NextPageToken})
+	if err != nil {
+		t.Fatalf("failed to reuse page token: %v", err)
+	}
+	if len(next.Tasks) != 1 || next.Tasks[0].ID != "rich-3" {
+		t.Errorf("expected rich-3 on second page, got %v", ids(next.Tasks))
+	}
+}
+
+func TestSQLite_MigrateAddsLabels(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "taskflow_migrate_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	db, err := NewSQLite(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	defer db.Close()
+
+	// 旧版本表结构（无 labels 列）
+	_, err = db.DB().Exec(`CREATE TABLE tasks (
+		id TEXT PRIMARY KEY, name TEXT NOT NULL, description TEXT,
+		status INTEGER NOT NULL DEFAULT 1, priority INTEGER NOT NULL DEFAULT 2,
+		task_type TEXT, input_params TEXT, output_result TEXT, dependencies TEXT,
+		retry_count INTEGER NOT NULL DEFAULT 0, max_retries INTEGER NOT NULL DEFAULT 0,
+		error_message TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL,
+		started_at TEXT, completed_at TEXT, created_by TEXT
+	);
+	INSERT INTO tasks (id, name, description, task_type, input_params, output_result, dependencies, error_message, created_at, updated_at, created_by)
+	VALUES ('old-1', 'old', '', 'test', '{}', '{}', '[]', '', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z', 'test');`)
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+
+	repo := NewTaskRepository(db)
+	task, err := repo.GetByID("old-1")
+	if err != nil || task == nil {
+		t.Fatalf("failed to read legacy task: %v", err)
+	}
+	if task.Labels != nil {
+		t.Errorf("expected nil labels, got %v", task.Labels)
+	}
+	if task.Namespace != model.DefaultNamespace {
+		t.Errorf("expected legacy task in default namespace, got %q", task.Namespace)
+	}
+}
+
+func TestTaskRepository_InNamespace(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	teamA := repo.InNamespace("team-a")
+	teamB := repo.InNamespace("team-b")
+
+	for _, id := range []string{"a-1", "a-2"} {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "test", nil, nil, 0, "alice")
+		task.ID = id
+		if err := teamA.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	task := model.NewTask("b-1", "", model.TaskPriorityNormal, "test", nil, nil, 0, "bob")
+	task.ID = "b-1"
+	if err := teamB.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 未限定的仓储可以看到全部任务
+	if got, _ := repo.GetByID("b-1"); got == nil || got.Namespace != "team-b" {
+		t.Fatalf("expected b-1 in team-b, got %+v", got)
+	}
+	if got, _ := teamA.GetByID("b-1"); got != nil {
+		t.Error("expected b-1 to be invisible in team-a")
+	}
+	if count, _ := teamA.Count(nil); count != 2 {
+		t.Errorf("expected 2 tasks in team-a, got %d", count)
+	}
+	if tasks, total, _ := teamB.ListByFilter(TaskFilter{}); total != 1 || tasks[0].ID != "b-1" {
+		t.Errorf("expected only b-1 in team-b, got %d tasks", total)
+	}
+	if page, _ := teamA.ListByCursor(TaskFilter{CreatedBy: "bob"}); page.Total != 0 {
+		t.Errorf("expected filter limited to team-a, got %d", page.Total)
+	}
+
+	// 跨命名空间的修改不生效
+	if err := teamA.UpdateStatus("b-1", model.TaskStatusPending, model.TaskStatusRunning); !errors.Is(err, ErrStatusMismatch) {
+		t.Errorf("expected ErrStatusMismatch for another namespace, got %v", err)
+	}
+	if deleted, _ := teamA.DeleteTasks([]string{"a-1", "b-1"}); deleted != 1 {
+		t.Errorf("expected only a-1 deleted, got %d", deleted)
+	}
+	if got, _ := repo.GetByID("b-1"); got == nil {
+		t.Error("b-1 must not be deleted from team-a")
+	}
+}
+
+func TestNamespaceRepository(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	namespaces := NewNamespaceRepository(db)
+	if ns, err := namespaces.Get(model.DefaultNamespace); err != nil || ns == nil {
+		t.Fatalf("expected seeded default namespace: %v", err)
+	}
+
+	now := time.Now()
+	ns := &Namespace{Name: "team-a", MaxTasks: 10, CreatedAt: now, UpdatedAt: now}
+	if err := namespaces.Create(ns); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	if err := namespaces.Create(ns); !errors.Is(err, ErrNamespaceExists) {
+		t.Errorf("expected ErrNamespaceExists, got %v", err)
+	}
+
+	task := model.NewTask("t", "", model.TaskPriorityNormal, "test", nil, nil, 0, "alice")
+	task.ID = "t-1"
+	if err := NewTaskRepository(db).InNamespace("team-a").Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	usage, err := namespaces.Usage("team-a")
+	if err != nil || usage.Tasks != 1 || usage.ActiveTasks != 1 {
+		t.Errorf("unexpected usage %+v: %v", usage, err)
+	}
+	if _, err := namespaces.Delete("team-a"); !errors.Is(err, ErrNamespaceNotEmpty) {
+		t.Errorf("expected ErrNamespaceNotEmpty, got %v", err)
+	}
+
+	if _, err := NewTaskRepository(db).DeleteTasks([]string{"t-1"}); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	if found, err := namespaces.Delete("team-a"); err != nil || !found {
+		t.Errorf("expected namespace deleted, got %v %v", found, err)
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	audit := NewAuditRepository(db)
+	for i, user := range []string{"alice", "bob", "alice"} {
+		event := &model.AuditEvent{
+			Timestamp: time.Now(),
+			UserID:    user,
+			Transport: model.AuditTransportGRPC,
+			Method:    "/taskflow.TaskService/CreateTask",
+			Resource:  fmt.Sprintf("t-%d", i),
+			Code:      "OK",
+		}
+		if err := audit.Append(event); err != nil {
+			t.Fatalf("failed to append audit event: %v", err)
+		}
+		if event.Seq != int64(i+1) || event.Hash == "" {
+			t.Fatalf("unexpected chain fields %+v", event)
+		}
+	}
+
+	events, next, err := audit.List(AuditFilter{UserID: "alice"}, 1, "")
+	if err != nil || len(events) != 1 || events[0].Seq != 3 || next == "" {
+		t.Fatalf("unexpected first page %v %q: %v", events, next, err)
+	}
+	events, next, err = audit.List(AuditFilter{UserID: "alice"}, 1, next)
+	if err != nil || len(events) != 1 || events[0].Seq != 1 || next != "" {
+		t.Fatalf("unexpected second page %v %q: %v", events, next, err)
+	}
+	if _, _, err := audit.List(AuditFilter{UserID: "bob"}, 1, "bogus"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("expected ErrInvalidPageToken, got %v", err)
+	}
+
+	if n, err := audit.Verify(); err != nil || n != 3 {
+		t.Fatalf("expected intact chain of 3, got %d: %v", n, err)
+	}
+
+	// 触发器阻止修改与删除
+	if _, err := db.DB().Exec(`UPDATE audit_events SET user_id = 'mallory' WHERE seq = 2`); err == nil {
+		t.Error("expected update to be rejected")
+	}
+	if _, err := db.DB().Exec(`DELETE FROM audit_events WHERE seq = 2`); err == nil {
+		t.Error("expected delete to be rejected")
+	}
+
+	// 绕过触发器直接改库，校验能发现篡改
+	if _, err := db.DB().Exec(`DROP TRIGGER audit_events_no_update`); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+	if _, err := db.DB().Exec(`UPDATE audit_events SET user_id = 'mallory' WHERE seq = 2`); err != nil {
+		t.Fatalf("failed to tamper: %v", err)
+	}
+	if n, err := audit.Verify(); !errors.Is(err, ErrAuditChainBroken) || n != 1 {
+		t.Errorf("expected broken chain after seq 1, got %d: %v", n, err)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	valid := []string{
+		`status in (FAILED, TIMEOUT) and task_type = "etl" and input.region = "eu" and created_at > now-24h`,
+		`not (priority >= HIGH or retry_count > 2)`,
+		`status NOT IN (TASK_STATUS_SUCCEEDED, 5) OR labels.team != 'web'`,
+		`completed_at < "2024-01-01T00:00:00Z" and created_at >= 2023-12-01`,
+		`name == etl-daily and created_at <= now+30m and updated_at > now-7d`,
+	}
+	for _, expr := range valid {
+		if _, err := ParseFilter(expr); err != nil {
+			t.Errorf("ParseFilter(%q) failed: %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		`status = BOGUS`,
+		`unknown_field = 1`,
+		`input.bad"key = 1`,
+		`retry_count > many`,
+		`status in (FAILED`,
+		`name = "unterminated`,
+		`(name = a`,
+		`name = a b`,
+		`name a`,
+		`created_at > yesterday`,
+		`name = a; drop table tasks`,
+		strings.Repeat("(", 100) + "name = a" + strings.Repeat(")", 100),
+	}
+	for _, expr := range invalid {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParseFilter(%q): expected ErrInvalidFilter, got %v", expr, err)
+		}
+	}
+
+	if expr, err := ParseFilter("  "); err != nil || expr != nil {
+		t.Errorf("expected nil filter for blank expression, got %v, %v", expr, err)
+	}
+}
+
+func TestTaskRepository_ListByFilterExpression(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	now := time.Now()
+	completed := now.Add(-30 * time.Minute)
+	tasks := []*model.Task{
+		{ID: "expr-1", Name: "eu etl", Status: model.TaskStatusFailed, Priority: model.TaskPriorityHigh, TaskType: "etl", InputParams: map[string]string{"region": "eu"}, CreatedAt: now.Add(-2 * time.Hour), RetryCount: 3},
+		{ID: "expr-2", Name: "us etl", Status: model.TaskStatusTimeout, Priority: model.TaskPriorityNormal, TaskType: "etl", InputParams: map[string]string{"region": "us"}, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "expr-3", Name: "old eu etl", Status: model.TaskStatusFailed, Priority: model.TaskPriorityLow, TaskType: "etl", InputParams: map[string]string{"region": "eu"}, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "expr-4", Name: "eu report", Status: model.TaskStatusSucceeded, Priority: model.TaskPriorityUrgent, TaskType: "report", InputParams: map[string]string{"region": "eu"}, CreatedAt: now.Add(-1 * time.Hour), CompletedAt: &completed, Labels: map[string]string{"team": "web"}},
+		{ID: "expr-5", Name: "no params", Status: model.TaskStatusPending, Priority: model.TaskPriorityNormal, TaskType: "etl", CreatedAt: now.Add(-10 * time.Minute)},
+	}
+	for _, task := range tasks {
+		task.UpdatedAt = task.CreatedAt
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{`status in (FAILED, TIMEOUT) and task_type = "etl" and input.region = "eu" and created_at > now-24h`, []string{"expr-1"}},
+		{`input.region != "eu"`, []string{"expr-2"}},
+		{`not input.region = "eu"`, []string{"expr-2", "expr-5"}},
+		{`completed_at > now-1h`, []string{"expr-4"}},
+		{`not completed_at > now-1h`, []string{"expr-1", "expr-2", "expr-3", "expr-5"}},
+		{`priority >= HIGH or retry_count > 2`, []string{"expr-1", "expr-4"}},
+		{`status not in (FAILED) and labels.team = web`, []string{"expr-4"}},
+		{`name = "eu etl" or (task_type = etl and created_at < now-1d)`, []string{"expr-1", "expr-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, total, err := repo.ListByFilter(TaskFilter{Expression: tt.expr, SortBy: "name"})
+			if err != nil {
+				t.Fatalf("failed to list by expression: %v", err)
+			}
+
+			got := map[string]bool{}
+			for _, task := range result {
+				got[task.ID] = true
+			}
+			if total != len(tt.expected) || len(got) != len(tt.expected) {
+				t.Errorf("expected %v, got %d tasks (total %d)", tt.expected, len(got), total)
+			}
+			for _, id := range tt.expected {
+				if !got[id] {
+					t.Errorf("expected %s in result", id)
+				}
+			}
+
+			// 内存匹配与 SQL 结果一致
+			expr, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			for _, task := range tasks {
+				if expr.Match(task) != got[task.ID] {
+					t.Errorf("Match(%s) = %v, SQL = %v", task.ID, expr.Match(task), got[task.ID])
+				}
+			}
+		})
+	}
+
+	if _, _, err := repo.ListByFilter(TaskFilter{Expression: "status = "}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected ErrInvalidFilter, got %v", err)
+	}
+}
+
+func TestSQLite_BackupAndRestore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	for i := 0; i < 3; i++ {
+		task := model.NewTask(fmt.Sprintf("backup-%d", i), "searchable backup task", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+		task.ID = fmt.Sprintf("backup-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	snapshot := dir + "/snapshot.db"
+	if err := db.Backup(context.Background(), snapshot); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := db.Backup(context.Background(), snapshot); err == nil {
+		t.Error("expected error when backup target exists")
+	}
+
+	info, err := ValidateSnapshot(snapshot)
+	if err != nil {
+		t.Fatalf("ValidateSnapshot failed: %v", err)
+	}
+	if info.Tasks != 3 {
+		t.Errorf("expected 3 tasks in snapshot, got %d", info.Tasks)
+	}
+
+	// 非数据库文件与缺表的数据库都应被拒绝
+	garbage := dir + "/garbage.db"
+	if err := os.WriteFile(garbage, []byte("not a database"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := ValidateSnapshot(garbage); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for garbage file, got %v", err)
+	}
+	empty, err := NewSQLite(dir + "/empty.db")
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	if _, err := empty.DB().Exec(`CREATE TABLE other (id TEXT)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	empty.Close()
+	if _, err := ValidateSnapshot(dir + "/empty.db"); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for missing tables, got %v", err)
+	}
+
+	// 恢复到已有数据库：原文件被保留
+	target := dir + "/live.db"
+	live, err := NewSQLite(target)
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	if err := live.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+	live.Close()
+
+	if _, err := RestoreSnapshot(garbage, target); err == nil {
+		t.Error("expected restore of invalid snapshot to fail")
+	}
+
+	previous, err := RestoreSnapshot(snapshot, target)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if _, err := os.Stat(previous); err != nil {
+		t.Errorf("expected previous database to be kept: %v", err)
+	}
+
+	restored, err := NewSQLite(target)
+	if err != nil {
+		t.Fatalf("failed to open restored db: %v", err)
+	}
+	defer restored.Close()
+	if err := restored.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	tasks, total, err := NewTaskRepository(restored).ListByFilter(TaskFilter{Keyword: "searchable", PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListByFilter failed: %v", err)
+	}
+	if total != 3 || len(tasks) != 3 {
+		t.Errorf("expected 3 restored tasks, got %d (total %d)", len(tasks), total)
+	}
+}
+
+func TestSQLite_WALAndConcurrentWrites(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var mode string
+	if err := db.DB().QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatalf("failed to query journal mode: %v", err)
+	}
+	if strings.ToLower(mode) != "wal" {
+		t.Errorf("expected WAL journal mode, got %s", mode)
+	}
+
+	repo := NewTaskRepository(db)
+	const workers, updates = 8, 25
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			task := model.NewTask(fmt.Sprintf("concurrent-%d", w), "", model.TaskPriorityNormal, "", nil, nil, 0, "test")
+			task.ID = fmt.Sprintf("concurrent-%d", w)
+			if err := repo.Create(task); err != nil {
+				errCh <- err
+				return
+			}
+
+			from, to := model.TaskStatusPending, model.TaskStatusRunning
+			for i := 0; i < updates; i++ {
+				if err := repo.UpdateStatusWithEvent(task.ID, from, to, "test", ""); err != nil {
+					errCh <- err
+					return
+				}
+				// 并发读不应被写阻塞
+				if _, err := repo.GetByID(task.ID); err != nil {
+					errCh <- err
+					return
+				}
+				from, to = to, from
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Errorf("concurrent write failed: %v", err)
+	}
+
+	var events int
+	if err := db.DB().QueryRow(`SELECT COUNT(*) FROM task_events`).Scan(&events); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if events != workers*updates {
+		t.Errorf("expected %d events, got %d", workers*updates, events)
+	}
+}
+
+// BenchmarkTaskRepository_UpdateStatusWithEvent 并发状态更新吞吐量
+// go test ./internal/repository -run ^$ -bench UpdateStatusWithEvent -cpu 1,4,16
+func BenchmarkTaskRepository_UpdateStatusWithEvent(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	var seq int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		id := fmt.Sprintf("bench-%d", atomic.AddInt64(&seq, 1))
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "", nil, nil, 0, "bench")
+		task.ID = id
+		if err := repo.Create(task); err != nil {
+			b.Error(err)
+			return
+		}
+
+		from, to := model.TaskStatusPending, model.TaskStatusRunning
+		for pb.Next() {
+			if err := repo.UpdateStatusWithEvent(id, from, to, "bench", ""); err != nil {
+				b.Error(err)
+				return
+			}
+			from, to = to, from
+		}
+	})
+}
+
+func TestSQLite_MigrateNanoTimestamps(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "taskflow_migrate_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	db, err := NewSQLite(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	defer db.Close()
+
+	// 旧版本数据：秒精度、带时区偏移
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+	_, err = db.DB().Exec(`PRAGMA user_version = 0;
+	INSERT INTO tasks (id, name, description, task_type, input_params, output_result, dependencies, error_message, created_at, updated_at, completed_at, created_by)
+	VALUES ('old-1', 'old', '', 'test', '{}', '{}', '[]', '', '2024-01-01T08:00:00+08:00', '2024-01-01T00:00:05Z', NULL, 'test');
+	INSERT INTO task_events (id, task_id, from_status, to_status, message, timestamp, operator)
+	VALUES ('old-1-e', 'old-1', 1, 2, '', '2024-01-01T08:00:01+08:00', 'test');`)
+	if err != nil {
+		t.Fatalf("failed to insert legacy rows: %v", err)
+	}
+
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var version int
+	if err := db.DB().QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("failed to read user_version: %v", err)
+	}
+	if version != len(schemaMigrations) {
+		t.Errorf("expected user_version %d, got %d", len(schemaMigrations), version)
+	}
+
+	var createdAt, eventTime string
+	var completedAt *string
+	if err := db.DB().QueryRow(`SELECT created_at, completed_at FROM tasks WHERE id = 'old-1'`).Scan(&createdAt, &completedAt); err != nil {
+		t.Fatalf("failed to read task: %v", err)
+	}
+	if createdAt != "2024-01-01T00:00:00.000000000Z" {
+		t.Errorf("unexpected migrated created_at %q", createdAt)
+	}
+	if completedAt != nil {
+		t.Errorf("expected NULL completed_at to be kept, got %q", *completedAt)
+	}
+	if err := db.DB().QueryRow(`SELECT timestamp FROM task_events WHERE id = 'old-1-e'`).Scan(&eventTime); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if eventTime != "2024-01-01T00:00:01.000000000Z" {
+		t.Errorf("unexpected migrated timestamp %q", eventTime)
+	}
+
+	// 同一秒内的事件按纳秒顺序返回
+	repo := NewTaskRepository(db)
+	base := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{900 * time.Millisecond, 5 * time.Millisecond, 50 * time.Millisecond} {
+		event := &model.TaskEvent{
+			ID:        fmt.Sprintf("sub-%d", i),
+			TaskID:    "old-1",
+			ToStatus:  model.TaskStatusRunning,
+			Timestamp: base.Add(offset),
+		}
+		if err := repo.AddEvent(event); err != nil {
+			t.Fatalf("failed to add event: %v", err)
+		}
+	}
+
+	events, err := repo.GetEventsByTaskID("old-1")
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.ID)
+	}
+	if strings.Join(got, ",") != "old-1-e,sub-1,sub-2,sub-0" {
+		t.Errorf("unexpected event order: %v", got)
+	}
+	if !events[3].Timestamp.Equal(base.Add(900 * time.Millisecond)) {
+		t.Errorf("expected sub-second precision, got %v", events[3].Timestamp)
+	}
+}
+
+func TestTaskRepository_Outbox(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	task := model.NewTask("outbox", "outbox task", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+	task.ID = "outbox-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "test", "start"); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	// 状态不匹配时事务回滚，不应产生记录
+	if err := repo.UpdateStatus(task.ID, model.TaskStatusPending, model.TaskStatusRunning); err == nil {
+		t.Fatal("expected status mismatch error")
+	}
+	if err := repo.Delete(task.ID); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+
+	select {
+	case <-repo.OutboxNotify():
+	default:
+		t.Error("expected outbox notification")
+	}
+
+	entries, err := repo.ListUnpublishedOutbox(10)
+	if err != nil {
+		t.Fatalf("failed to list outbox: %v", err)
+	}
+	want := []string{model.ChangeTypeCreated, model.ChangeTypeStatusChanged, model.ChangeTypeDeleted}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d outbox entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ChangeType != want[i] {
+			t.Errorf("entry %d: expected %s, got %s", i, want[i], entry.ChangeType)
+		}
+		if i > 0 && entry.Seq <= entries[i-1].Seq {
+			t.Errorf("entry %d: seq %d not increasing", i, entry.Seq)
+		}
+		if entry.Task == nil || entry.Task.ID != task.ID {
+			t.Errorf("entry %d: missing task snapshot", i)
+		}
+	}
+	if entries[1].FromStatus != model.TaskStatusPending || entries[1].ToStatus != model.TaskStatusRunning {
+		t.Errorf("unexpected transition %v -> %v", entries[1].FromStatus, entries[1].ToStatus)
+	}
+	if entries[1].Task.Status != model.TaskStatusRunning {
+		t.Errorf("expected snapshot status running, got %v", entries[1].Task.Status)
+	}
+
+	if _, err := repo.MarkOutboxPublished(entries[1].Seq); err != nil {
+		t.Fatalf("failed to mark published: %v", err)
+	}
+	entries, _ = repo.ListUnpublishedOutbox(10)
+	if len(entries) != 1 || entries[0].ChangeType != model.ChangeTypeDeleted {
+		t.Fatalf("expected only the delete entry to remain, got %d", len(entries))
+	}
+
+	n, err := repo.PruneOutbox(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to prune outbox: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 pruned entries, got %d", n)
+	}
+}
+
+// taskIDs 提取任务 ID 列表
+func taskIDs(tasks []*model.Task) []string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// DeleteTasks 在同一事务中删除任务及其事件，返回删除的任务数
+func (r *TaskRepository) DeleteTasks(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := r.withOutbox(func(tx *sql.Tx, emit outboxEmitter) error {
+		n, err := r.deleteTasksTx(tx, emit, ids)
+		deleted = n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+// ArchiveTasks 将任务（含事件）写入归档表并从主表删除，两步在同一事务中完成
+func (r *TaskRepository) ArchiveTasks(tasks []*model.Task) (int, error) {
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	archivedAt := formatTime(time.Now())
+	ids := make([]string, len(tasks))
+
+	var deleted int64
+	err := r.withOutbox(func(tx *sql.Tx, emit outboxEmitter) error {
+		stmt, err := tx.Prepare(`INSERT OR REPLACE INTO tasks_archive (
+			id, task_type, status, created_at, archived_at, data
+		) VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i, task := range tasks {
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(task.ID, task.TaskType, task.Status, formatTime(task.CreatedAt), archivedAt, string(data)); err != nil {
+				return err
+			}
+			ids[i] = task.ID
+		}
+
+		deleted, err = r.deleteTasksTx(tx, emit, ids)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+// GetArchivedTask 从归档表读取任务，不存在返回 nil
+func (r *TaskRepository) GetArchivedTask(id string) (*model.Task, error) {
+	var data string
+	err := r.db.DB().QueryRow(`SELECT data FROM tasks_archive WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var task model.Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		return nil, err
+	}
+	if task.Namespace == "" {
+		task.Namespace = model.DefaultNamespace // 引入命名空间之前归档的任务
+	}
+	if r.namespace != "" && task.Namespace != r.namespace {
+		return nil, nil
+	}
+	return &task, nil
+}
+
+// deleteTasksTx 删除任务及其事件（外键未启用，事件需显式删除），并为每个任务写入删除的 outbox 记录
+// 限定命名空间时只删除该命名空间内的任务
+func (r *TaskRepository) deleteTasksTx(tx *sql.Tx, emit outboxEmitter, ids []string) (int64, error) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	conditions, args := r.scope([]string{"id IN (" + placeholders(len(ids)) + ")"}, args)
+
+	rows, err := tx.Query(`SELECT `+taskColumns+` FROM tasks `+whereClause(conditions), args...)
+	if err != nil {
+		return 0, err
+	}
+	var tasks []*model.Task
+	for rows.Next() {
+		task, err := r.scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	args = make([]interface{}, len(tasks))
+	for i, task := range tasks {
+		if err := emit(model.ChangeTypeDeleted, task, task.Status, task.Status); err != nil {
+			return 0, err
+		}
+		args[i] = task.ID
+	}
+	in := placeholders(len(tasks))
+
+	if _, err := tx.Exec(`DELETE FROM task_events WHERE task_id IN (`+in+`)`, args...); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM tasks WHERE id IN (`+in+`)`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/json"
+)
+
+// ListSealedSecrets 列出带机密参数的任务 ID 与密文，供密钥轮换后重新加密
+// 不限定命名空间：轮换作用于整个数据库
+func (r *TaskRepository) ListSealedSecrets() (map[string]map[string]string, error) {
+	rows, err := r.db.DB().Query(`SELECT id, secret_params FROM tasks
+		WHERE secret_params IS NOT NULL AND secret_params NOT IN ('', 'null', '{}')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sealed := make(map[string]map[string]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var params map[string]string
+		if err := json.Unmarshal([]byte(data), &params); err != nil {
+			return nil, err
+		}
+		sealed[id] = params
+	}
+	return sealed, rows.Err()
+}
+
+// ReplaceSealedSecrets 用新密文替换任务的机密参数，密文已被并发修改时不替换并返回 false
+// 明文不变，因此不更新 updated_at，也不写入 outbox
+func (r *TaskRepository) ReplaceSealedSecrets(id string, old, sealed map[string]string) (bool, error) {
+	oldData, _ := json.Marshal(old)
+	newData, _ := json.Marshal(sealed)
+
+	result, err := r.db.Writer().Exec(`UPDATE tasks SET secret_params = ? WHERE id = ? AND secret_params = ?`,
+		string(newData), id, string(oldData))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
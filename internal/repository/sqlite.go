@@ -2,46 +2,196 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"taskflow/internal/model"
+)
+
+// 默认连接选项
+const (
+	DefaultJournalMode  = "WAL"
+	DefaultSynchronous  = "NORMAL"
+	DefaultBusyTimeout  = 5 * time.Second
+	DefaultMaxReadConns = 25
 )
 
+// SQLiteOptions SQLite 连接选项
+type SQLiteOptions struct {
+	JournalMode  string        // 日志模式：WAL, DELETE, TRUNCATE 等，默认 WAL
+	Synchronous  string        // 同步级别：OFF, NORMAL, FULL, EXTRA，默认 NORMAL
+	BusyTimeout  time.Duration // 等待锁的超时时间，默认 5s
+	MaxReadConns int           // 读连接池大小，默认 25
+}
+
+// DefaultSQLiteOptions 默认连接选项
+func DefaultSQLiteOptions() SQLiteOptions {
+	return SQLiteOptions{
+		JournalMode:  DefaultJournalMode,
+		Synchronous:  DefaultSynchronous,
+		BusyTimeout:  DefaultBusyTimeout,
+		MaxReadConns: DefaultMaxReadConns,
+	}
+}
+
 // SQLite SQLite 数据库
+// 读操作使用连接池；写操作通过单连接的 writer 串行执行，
+// 配合 WAL 模式读写互不阻塞，避免并发写入时出现 database is locked
 type SQLite struct {
-	db *sql.DB
+	db         *sql.DB // 读连接池
+	writer     *sql.DB // 单连接写通道
+	ftsEnabled bool
+
+	stmtMu sync.Mutex
+	stmts  map[stmtKey]*sql.Stmt
+}
+
+// stmtKey 预编译语句缓存键
+type stmtKey struct {
+	write bool
+	query string
 }
 
-// NewSQLite 创建 SQLite 实例
+// NewSQLite 使用默认选项创建 SQLite 实例
 func NewSQLite(dsn string) (*SQLite, error) {
-	db, err := sql.Open("sqlite3", dsn)
+	return NewSQLiteWithOptions(dsn, DefaultSQLiteOptions())
+}
+
+// NewSQLiteWithOptions 创建 SQLite 实例
+func NewSQLiteWithOptions(dsn string, opts SQLiteOptions) (*SQLite, error) {
+	defaults := DefaultSQLiteOptions()
+	if opts.JournalMode == "" {
+		opts.JournalMode = defaults.JournalMode
+	}
+	if opts.Synchronous == "" {
+		opts.Synchronous = defaults.Synchronous
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = defaults.BusyTimeout
+	}
+	if opts.MaxReadConns <= 0 {
+		opts.MaxReadConns = defaults.MaxReadConns
+	}
+
+	params := fmt.Sprintf("_journal_mode=%s&_synchronous=%s&_busy_timeout=%d",
+		opts.JournalMode, opts.Synchronous, opts.BusyTimeout.Milliseconds())
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+
+	// 写连接：BEGIN IMMEDIATE 立即获取写锁，避免读事务升级为写事务时死锁
+	writer, err := sql.Open("sqlite3", dsn+sep+params+"&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxLifetime(0)
+
+	if err := writer.Ping(); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	s := &SQLite{writer: writer, stmts: make(map[stmtKey]*sql.Stmt)}
+
+	// 内存数据库每个连接相互独立，读写必须共用同一连接
+	if isMemoryDSN(dsn) {
+		s.db = writer
+		return s, nil
+	}
+
+	db, err := sql.Open("sqlite3", dsn+sep+params)
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
 
 	// 设置连接池
-	db.SetMaxOpenConns(25)
+	db.SetMaxOpenConns(opts.MaxReadConns)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
 	// 验证连接
 	if err := db.Ping(); err != nil {
+		db.Close()
+		writer.Close()
 		return nil, err
 	}
 
-	return &SQLite{db: db}, nil
+	s.db = db
+	return s, nil
+}
+
+// isMemoryDSN 是否为内存数据库
+func isMemoryDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
 }
 
 // Close 关闭数据库连接
 func (s *SQLite) Close() error {
-	return s.db.Close()
+	s.stmtMu.Lock()
+	for key, stmt := range s.stmts {
+		stmt.Close()
+		delete(s.stmts, key)
+	}
+	s.stmtMu.Unlock()
+
+	if s.db != s.writer {
+		if err := s.db.Close(); err != nil {
+			s.writer.Close()
+			return err
+		}
+	}
+	return s.writer.Close()
 }
 
-// DB 获取数据库实例
+// DB 获取读连接池
 func (s *SQLite) DB() *sql.DB {
 	return s.db
 }
 
+// Writer 获取写连接，所有写操作应通过它串行执行
+func (s *SQLite) Writer() *sql.DB {
+	return s.writer
+}
+
+// readStmt 获取读连接池上缓存的预编译语句
+func (s *SQLite) readStmt(query string) (*sql.Stmt, error) {
+	return s.stmt(stmtKey{query: query})
+}
+
+// writeStmt 获取写连接上缓存的预编译语句
+func (s *SQLite) writeStmt(query string) (*sql.Stmt, error) {
+	return s.stmt(stmtKey{write: true, query: query})
+}
+
+// stmt 预编译并缓存语句
+func (s *SQLite) stmt(key stmtKey) (*sql.Stmt, error) {
+	s.stmtMu.Lock()
+	defer s.stmtMu.Unlock()
+
+	if stmt, ok := s.stmts[key]; ok {
+		return stmt, nil
+	}
+
+	db := s.db
+	if key.write {
+		db = s.writer
+	}
+	stmt, err := db.Prepare(key.query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[key] = stmt
+	return stmt, nil
+}
+
 // InitSchema 初始化数据库表结构
 func (s *SQLite) InitSchema() error {
 	schema := `
@@ -62,7 +212,9 @@ func (s *SQLite) InitSchema() error {
 		updated_at TEXT NOT NULL,
 		started_at TEXT,
 		completed_at TEXT,
-		created_by TEXT
+		created_by TEXT,
+		labels TEXT,
+		secret_params TEXT
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
@@ -83,15 +235,338 @@ func (s *SQLite) InitSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id);
 	CREATE INDEX IF NOT EXISTS idx_task_events_timestamp ON task_events(timestamp);
+
+	CREATE TABLE IF NOT EXISTS tasks_archive (
+		id TEXT PRIMARY KEY,
+		task_type TEXT,
+		status INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		archived_at TEXT NOT NULL,
+		data TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tasks_archive_archived_at ON tasks_archive(archived_at);
+
+	CREATE TABLE IF NOT EXISTS task_outbox (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		change_type TEXT NOT NULL,
+		from_status INTEGER NOT NULL,
+		to_status INTEGER NOT NULL,
+		payload TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		published_at TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_outbox_unpublished ON task_outbox(seq) WHERE published_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_task_outbox_published_at ON task_outbox(published_at);
+
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti TEXT PRIMARY KEY,
+		subject TEXT NOT NULL,
+		expires_at TEXT NOT NULL,
+		revoked_at TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		owner TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL,
+		scopes TEXT NOT NULL DEFAULT '[]',
+		expires_at TEXT,
+		last_used_at TEXT,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_owner ON api_keys(owner);
+
+	CREATE TABLE IF NOT EXISTS namespaces (
+		name TEXT PRIMARY KEY,
+		description TEXT,
+		max_tasks INTEGER NOT NULL DEFAULT 0,
+		max_active_tasks INTEGER NOT NULL DEFAULT 0,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_events (
+		seq INTEGER PRIMARY KEY,
+		timestamp TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		auth_method TEXT NOT NULL,
+		namespace TEXT NOT NULL,
+		transport TEXT NOT NULL,
+		method TEXT NOT NULL,
+		resource TEXT NOT NULL,
+		request_digest TEXT NOT NULL,
+		code TEXT NOT NULL,
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, seq);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_method ON audit_events(method, seq);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource, seq);
+
+	-- 审计日志只允许追加
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+	`
+
+	if _, err := s.writer.Exec(schema); err != nil {
+		return err
+	}
+
+	if err := s.migrate(); err != nil {
+		return err
+	}
+
+	return s.initFTS()
+}
+
+// schemaMigrations 数据迁移，按顺序执行，已完成的版本号记录在 PRAGMA user_version
+var schemaMigrations = []func(tx *sql.Tx) error{
+	migrateNanoTimestamps, // 1: 时间统一为 UTC 纳秒精度定宽格式
+}
+
+// migrate 为旧版本数据库补齐新增列并执行数据迁移
+func (s *SQLite) migrate() error {
+	if err := s.addColumnIfMissing("tasks", "labels", "TEXT"); err != nil {
+		return err
+	}
+	// 命名空间：旧数据归入默认命名空间
+	if err := s.addColumnIfMissing("tasks", "namespace", "TEXT NOT NULL DEFAULT '"+model.DefaultNamespace+"'"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("api_keys", "namespace", "TEXT NOT NULL DEFAULT '"+model.DefaultNamespace+"'"); err != nil {
+		return err
+	}
+	// 机密输入参数：参数名到密文的 JSON
+	if err := s.addColumnIfMissing("tasks", "secret_params", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := s.writer.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_namespace_status ON tasks(namespace, status)`); err != nil {
+		return err
+	}
+	now := formatTime(time.Now())
+	if _, err := s.writer.Exec(`INSERT OR IGNORE INTO namespaces (name, description, created_at, updated_at)
+		VALUES (?, 'default namespace', ?, ?)`, model.DefaultNamespace, now, now); err != nil {
+		return err
+	}
+
+	var version int
+	if err := s.writer.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for v := version; v < len(schemaMigrations); v++ {
+		err := s.ExecTx(func(tx *sql.Tx) error {
+			if err := schemaMigrations[v](tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", v+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", v+1, err)
+		}
+	}
+	return nil
+}
+
+// migrateNanoTimestamps 将旧版秒精度（含本地时区）的时间改写为 formatTime 格式
+func migrateNanoTimestamps(tx *sql.Tx) error {
+	tables := []struct {
+		name    string
+		columns []string
+	}{
+		{"tasks", []string{"created_at", "updated_at", "started_at", "completed_at"}},
+		{"task_events", []string{"timestamp"}},
+		{"tasks_archive", []string{"created_at", "archived_at"}},
+	}
+
+	for _, table := range tables {
+		if err := rewriteTimeColumns(tx, table.name, table.columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteTimeColumns 重写表中的时间列，NULL 与无法解析的值保持不变
+func rewriteTimeColumns(tx *sql.Tx, table string, columns []string) error {
+	type rowUpdate struct {
+		rowid  int64
+		values []interface{}
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT rowid, %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return err
+	}
+
+	var updates []rowUpdate
+	for rows.Next() {
+		var rowid int64
+		raw := make([]sql.NullString, len(columns))
+		dest := []interface{}{&rowid}
+		for i := range raw {
+			dest = append(dest, &raw[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+
+		changed := false
+		values := make([]interface{}, len(columns))
+		for i, v := range raw {
+			if !v.Valid {
+				values[i] = nil
+				continue
+			}
+			values[i] = v.String
+			if t, err := time.Parse(time.RFC3339Nano, v.String); err == nil {
+				if formatted := formatTime(t); formatted != v.String {
+					values[i] = formatted
+					changed = true
+				}
+			}
+		}
+		if changed {
+			updates = append(updates, rowUpdate{rowid: rowid, values: values})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = column + " = ?"
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s WHERE rowid = ?", table, strings.Join(sets, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, u := range updates {
+		if _, err := stmt.Exec(append(u.values, u.rowid)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing 列不存在时执行 ALTER TABLE ADD COLUMN
+func (s *SQLite) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = s.writer.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// ftsSchema 全文索引表结构（外部内容表，通过触发器与 tasks 保持同步）
+const ftsSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(
+		name, description, task_type, input_params, output_result, error_message,
+		content='tasks', content_rowid='rowid', tokenize='unicode61'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_ai AFTER INSERT ON tasks BEGIN
+		INSERT INTO tasks_fts(rowid, name, description, task_type, input_params, output_result, error_message)
+		VALUES (new.rowid, new.name, new.description, new.task_type, new.input_params, new.output_result, new.error_message);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_ad AFTER DELETE ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, name, description, task_type, input_params, output_result, error_message)
+		VALUES ('delete', old.rowid, old.name, old.description, old.task_type, old.input_params, old.output_result, old.error_message);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tasks_fts_au AFTER UPDATE OF name, description, task_type, input_params, output_result, error_message ON tasks BEGIN
+		INSERT INTO tasks_fts(tasks_fts, rowid, name, description, task_type, input_params, output_result, error_message)
+		VALUES ('delete', old.rowid, old.name, old.description, old.task_type, old.input_params, old.output_result, old.error_message);
+		INSERT INTO tasks_fts(rowid, name, description, task_type, input_params, output_result, error_message)
+		VALUES (new.rowid, new.name, new.description, new.task_type, new.input_params, new.output_result, new.error_message);
+	END;
 	`
 
-	_, err := s.db.Exec(schema)
+// initFTS 初始化 FTS5 全文索引
+// 驱动未启用 FTS5（未使用 sqlite_fts5 构建标签）时降级为 LIKE 搜索
+func (s *SQLite) initFTS() error {
+	var exists int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks_fts'`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.writer.Exec(ftsSchema); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			s.ftsEnabled = false
+			return nil
+		}
+		return err
+	}
+	s.ftsEnabled = true
+
+	// 首次创建索引时回填已有数据
+	if exists == 0 {
+		return s.RebuildSearchIndex()
+	}
+	return nil
+}
+
+// FTSEnabled 是否启用了全文索引
+func (s *SQLite) FTSEnabled() bool {
+	return s.ftsEnabled
+}
+
+// RebuildSearchIndex 根据 tasks 表重建全文索引
+// VACUUM 可能改变 tasks 的 rowid，执行后需要重建
+func (s *SQLite) RebuildSearchIndex() error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	_, err := s.writer.Exec(`INSERT INTO tasks_fts(tasks_fts) VALUES ('rebuild')`)
 	return err
 }
 
-// ExecTx 执行事务
+// ExecTx 在写连接上执行事务
 func (s *SQLite) ExecTx(fn func(*sql.Tx) error) error {
-	tx, err := s.db.Begin()
+	tx, err := s.writer.Begin()
 	if err != nil {
 		return err
 	}
@@ -5,67 +5,139 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"taskflow/internal/model"
 )
 
+// taskColumns tasks 表查询列，顺序与 scanTask 一致
+const taskColumns = `id, name, description, status, priority, task_type,
+		input_params, output_result, dependencies, retry_count,
+		max_retries, error_message, created_at, updated_at,
+		started_at, completed_at, created_by, labels, namespace, secret_params`
+
+// 热点语句，通过预编译语句缓存执行
+const (
+	insertTaskQuery = `INSERT INTO tasks (
+		id, name, description, status, priority, task_type,
+		input_params, output_result, dependencies, retry_count,
+		max_retries, error_message, created_at, updated_at,
+		started_at, completed_at, created_by, labels, namespace, secret_params
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	getTaskByIDQuery = `SELECT ` + taskColumns + `
+	FROM tasks WHERE id = ?`
+
+	updateStatusQuery = `UPDATE tasks SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
+
+	insertEventQuery = `INSERT INTO task_events (
+		id, task_id, from_status, to_status, message, timestamp, operator
+	) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	listEventsByTaskIDQuery = `SELECT id, task_id, from_status, to_status, message, timestamp, operator
+	FROM task_events WHERE task_id = ? ORDER BY timestamp ASC`
+)
+
+// ErrStatusMismatch 条件更新状态时任务不存在或当前状态与预期不符
+var ErrStatusMismatch = errors.New("task not found or status mismatch")
+
 // TaskRepository 任务仓储
+// 通过 InNamespace 得到的仓储只读写该命名空间的任务；未限定时作用于全部命名空间，供调度、清理等后台组件使用
 type TaskRepository struct {
-	db *SQLite
+	db        *SQLite
+	outboxCh  chan struct{}
+	namespace string
 }
 
 // NewTaskRepository 创建任务仓储
 func NewTaskRepository(db *SQLite) *TaskRepository {
-	return &TaskRepository{db: db}
+	return &TaskRepository{db: db, outboxCh: make(chan struct{}, 1)}
+}
+
+// InNamespace 返回限定在命名空间内的仓储，与原仓储共享连接与 outbox 通知
+func (r *TaskRepository) InNamespace(namespace string) *TaskRepository {
+	scoped := *r
+	scoped.namespace = namespace
+	return &scoped
+}
+
+// Namespace 仓储限定的命名空间，未限定时为空
+func (r *TaskRepository) Namespace() string {
+	return r.namespace
 }
 
-// Create 创建任务
+// scope 追加命名空间条件，未限定命名空间时原样返回
+func (r *TaskRepository) scope(conditions []string, args []interface{}) ([]string, []interface{}) {
+	if r.namespace == "" {
+		return conditions, args
+	}
+	return append(conditions, "tasks.namespace = ?"), append(args, r.namespace)
+}
+
+// Create 创建任务（同一事务中写入 outbox）
 func (r *TaskRepository) Create(task *model.Task) error {
 	inputParams, _ := json.Marshal(task.InputParams)
 	outputResult, _ := json.Marshal(task.OutputResult)
 	dependencies, _ := json.Marshal(task.Dependencies)
+	labels, _ := json.Marshal(task.Labels)
+	secretParams, _ := json.Marshal(task.SecretParams)
 
-	query := `INSERT INTO tasks (
-		id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	_, err := r.db.DB().Exec(query,
-		task.ID,
-		task.Name,
-		task.Description,
-		task.Status,
-		task.Priority,
-		task.TaskType,
-		string(inputParams),
-		string(outputResult),
-		string(dependencies),
-		task.RetryCount,
-		task.MaxRetries,
-		task.ErrorMessage,
-		task.CreatedAt.Format(time.RFC3339),
-		task.UpdatedAt.Format(time.RFC3339),
-		nullableTime(task.StartedAt),
-		nullableTime(task.CompletedAt),
-		task.CreatedBy,
-	)
+	if r.namespace != "" {
+		task.Namespace = r.namespace
+	} else if task.Namespace == "" {
+		task.Namespace = model.DefaultNamespace
+	}
 
-	return err
+	stmt, err := r.db.writeStmt(insertTaskQuery)
+	if err != nil {
+		return err
+	}
+
+	return r.withOutbox(func(tx *sql.Tx, emit outboxEmitter) error {
+		_, err := tx.Stmt(stmt).Exec(
+			task.ID,
+			task.Name,
+			task.Description,
+			task.Status,
+			task.Priority,
+			task.TaskType,
+			string(inputParams),
+			string(outputResult),
+			string(dependencies),
+			task.RetryCount,
+			task.MaxRetries,
+			task.ErrorMessage,
+			formatTime(task.CreatedAt),
+			formatTime(task.UpdatedAt),
+			nullableTime(task.StartedAt),
+			nullableTime(task.CompletedAt),
+			task.CreatedBy,
+			string(labels),
+			task.Namespace,
+			string(secretParams),
+		)
+		if err != nil {
+			return err
+		}
+
+		return emit(model.ChangeTypeCreated, task, model.TaskStatusUnspecified, task.Status)
+	})
 }
 
 // GetByID 根据 ID 获取任务
 func (r *TaskRepository) GetByID(id string) (*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE id = ?`
+	query, args := getTaskByIDQuery, []interface{}{id}
+	if r.namespace != "" {
+		query, args = getTaskByIDQuery+" AND namespace = ?", append(args, r.namespace)
+	}
+	stmt, err := r.db.readStmt(query)
+	if err != nil {
+		return nil, err
+	}
 
-	task, err := r.scanTask(r.db.DB().QueryRow(query, id))
+	task, err := r.scanTask(stmt.QueryRow(args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -83,63 +155,77 @@ func (r *TaskRepository) GetByID(id string) (*model.Task, error) {
 	return task, nil
 }
 
-// Update 更新任务
+// Update 更新任务（同一事务中写入 outbox）
+// 机密参数创建后不可修改，不在更新范围内
 func (r *TaskRepository) Update(task *model.Task) error {
 	inputParams, _ := json.Marshal(task.InputParams)
 	outputResult, _ := json.Marshal(task.OutputResult)
 	dependencies, _ := json.Marshal(task.Dependencies)
+	labels, _ := json.Marshal(task.Labels)
 
 	query := `UPDATE tasks SET 
 		name = ?, description = ?, status = ?, priority = ?,
 		task_type = ?, input_params = ?, output_result = ?,
 		dependencies = ?, retry_count = ?, max_retries = ?,
 		error_message = ?, updated_at = ?, started_at = ?,
-		completed_at = ?, created_by = ?
+		completed_at = ?, created_by = ?, labels = ?
 	WHERE id = ?`
+	scopeArgs := []interface{}{task.ID}
+	if r.namespace != "" {
+		query += " AND namespace = ?"
+		scopeArgs = append(scopeArgs, r.namespace)
+	}
 
-	_, err := r.db.DB().Exec(query,
-		task.Name,
-		task.Description,
-		task.Status,
-		task.Priority,
-		task.TaskType,
-		string(inputParams),
-		string(outputResult),
-		string(dependencies),
-		task.RetryCount,
-		task.MaxRetries,
-		task.ErrorMessage,
-		task.UpdatedAt.Format(time.RFC3339),
-		nullableTime(task.StartedAt),
-		nullableTime(task.CompletedAt),
-		task.CreatedBy,
-		task.ID,
-	)
+	return r.withOutbox(func(tx *sql.Tx, emit outboxEmitter) error {
+		args := []interface{}{
+			task.Name,
+			task.Description,
+			task.Status,
+			task.Priority,
+			task.TaskType,
+			string(inputParams),
+			string(outputResult),
+			string(dependencies),
+			task.RetryCount,
+			task.MaxRetries,
+			task.ErrorMessage,
+			formatTime(task.UpdatedAt),
+			nullableTime(task.StartedAt),
+			nullableTime(task.CompletedAt),
+			task.CreatedBy,
+			string(labels),
+		}
+		result, err := tx.Exec(query, append(args, scopeArgs...)...)
+		if err != nil {
+			return err
+		}
 
-	return err
+		// 任务不存在时不产生变更记录
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return err
+		}
+		return emit(model.ChangeTypeUpdated, task, task.Status, task.Status)
+	})
 }
 
-// Delete 删除任务
+// Delete 删除任务（连同其事件）
 func (r *TaskRepository) Delete(id string) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-	_, err := r.db.DB().Exec(query, id)
+	_, err := r.DeleteTasks([]string{id})
 	return err
 }
 
 // List 列出任务（分页）
 func (r *TaskRepository) List(limit, offset int, statusFilter *model.TaskStatus) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks`
-
+	var conditions []string
 	var args []interface{}
 	if statusFilter != nil {
-		query += " WHERE status = ?"
+		conditions = append(conditions, "status = ?")
 		args = append(args, *statusFilter)
 	}
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	conditions, args = r.scope(conditions, args)
+
+	query := `SELECT ` + taskColumns + `
+	FROM tasks ` + whereClause(conditions) + " ORDER BY created_at DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := r.db.DB().Query(query, args...)
@@ -167,13 +253,11 @@ func (r *TaskRepository) ListByStatus(status model.TaskStatus, limit int) ([]*mo
 
 // ListByCreator 根据创建者列出任务
 func (r *TaskRepository) ListByCreator(createdBy string, limit, offset int) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE created_by = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	conditions, args := r.scope([]string{"created_by = ?"}, []interface{}{createdBy})
+	query := `SELECT ` + taskColumns + `
+	FROM tasks ` + whereClause(conditions) + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
 
-	rows, err := r.db.DB().Query(query, createdBy, limit, offset)
+	rows, err := r.db.DB().Query(query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
@@ -193,13 +277,11 @@ func (r *TaskRepository) ListByCreator(createdBy string, limit, offset int) ([]*
 
 // ListPending 列出待处理任务（可被调度）
 func (r *TaskRepository) ListPending(limit int) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE status = ? ORDER BY priority DESC, created_at ASC LIMIT ?`
+	conditions, args := r.scope([]string{"status = ?"}, []interface{}{model.TaskStatusPending})
+	query := `SELECT ` + taskColumns + `
+	FROM tasks ` + whereClause(conditions) + ` ORDER BY priority DESC, created_at ASC LIMIT ?`
 
-	rows, err := r.db.DB().Query(query, model.TaskStatusPending, limit)
+	rows, err := r.db.DB().Query(query, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -219,31 +301,33 @@ func (r *TaskRepository) ListPending(limit int) ([]*model.Task, error) {
 
 // Count 统计任务数量
 func (r *TaskRepository) Count(statusFilter *model.TaskStatus) (int, error) {
-	query := "SELECT COUNT(*) FROM tasks"
+	var conditions []string
 	var args []interface{}
 	if statusFilter != nil {
-		query += " WHERE status = ?"
+		conditions = append(conditions, "status = ?")
 		args = append(args, *statusFilter)
 	}
+	conditions, args = r.scope(conditions, args)
 
 	var count int
-	err := r.db.DB().QueryRow(query, args...).Scan(&count)
+	err := r.db.DB().QueryRow("SELECT COUNT(*) FROM tasks "+whereClause(conditions), args...).Scan(&count)
 	return count, err
 }
 
 // AddEvent 添加任务事件
 func (r *TaskRepository) AddEvent(event *model.TaskEvent) error {
-	query := `INSERT INTO task_events (
-		id, task_id, from_status, to_status, message, timestamp, operator
-	) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	stmt, err := r.db.writeStmt(insertEventQuery)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.db.DB().Exec(query,
+	_, err = stmt.Exec(
 		event.ID,
 		event.TaskID,
 		event.FromStatus,
 		event.ToStatus,
 		event.Message,
-		event.Timestamp.Format(time.RFC3339),
+		formatTime(event.Timestamp),
 		event.Operator,
 	)
 
@@ -252,10 +336,12 @@ func (r *TaskRepository) AddEvent(event *model.TaskEvent) error {
 
 // GetEventsByTaskID 获取任务的所有事件
 func (r *TaskRepository) GetEventsByTaskID(taskID string) ([]model.TaskEvent, error) {
-	query := `SELECT id, task_id, from_status, to_status, message, timestamp, operator
-	FROM task_events WHERE task_id = ? ORDER BY timestamp ASC`
+	stmt, err := r.db.readStmt(listEventsByTaskIDQuery)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := r.db.DB().Query(query, taskID)
+	rows, err := stmt.Query(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -277,38 +363,118 @@ func (r *TaskRepository) GetEventsByTaskID(taskID string) ([]model.TaskEvent, er
 		if err != nil {
 			return nil, err
 		}
-		event.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		event.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp)
 		events = append(events, event)
 	}
 
 	return events, rows.Err()
 }
 
-// UpdateStatus 原子更新任务状态
-func (r *TaskRepository) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
-	query := `UPDATE tasks SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
-	result, err := r.db.DB().Exec(query, toStatus, time.Now().Format(time.RFC3339), id, fromStatus)
-	if err != nil {
-		return err
+// ListEventsByTaskID 游标分页获取任务事件（按时间升序）
+func (r *TaskRepository) ListEventsByTaskID(taskID string, pageSize int, pageToken string) ([]model.TaskEvent, string, error) {
+	if pageSize <= 0 {
+		pageSize = 50
 	}
 
-	rows, err := result.RowsAffected()
+	keys := []sortKey{{Column: "timestamp"}}
+	fingerprint := queryFingerprint("events:" + taskID)
+
+	query := `SELECT id, task_id, from_status, to_status, message, timestamp, operator
+	FROM task_events WHERE task_id = ?`
+	args := []interface{}{taskID}
+	if r.namespace != "" {
+		query += " AND task_id IN (SELECT id FROM tasks WHERE namespace = ?)"
+		args = append(args, r.namespace)
+	}
+
+	if pageToken != "" {
+		cursor, err := decodePageToken(pageToken, fingerprint, len(keys))
+		if err != nil {
+			return nil, "", err
+		}
+		cond, condArgs := keysetCondition(keys, "id", false, cursor)
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	query += " ORDER BY " + orderByClause(keys, "id", false) + " LIMIT ?"
+	args = append(args, pageSize+1)
+
+	rows, err := r.db.DB().Query(query, args...)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var events []model.TaskEvent
+	for rows.Next() {
+		var event model.TaskEvent
+		var timestamp string
+		if err := rows.Scan(
+			&event.ID,
+			&event.TaskID,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.Message,
+			&timestamp,
+			&event.Operator,
+		); err != nil {
+			return nil, "", err
+		}
+		event.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp)
+		events = append(events, event)
 	}
-	if rows == 0 {
-		return errors.New("task not found or status mismatch")
+	if err := rows.Err(); err != nil {
+		return nil, "", err
 	}
 
-	return nil
+	var nextPageToken string
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		last := events[len(events)-1]
+		nextPageToken = encodePageToken(pageCursor{
+			Fingerprint: fingerprint,
+			Keys:        []interface{}{formatTime(last.Timestamp)},
+			ID:          last.ID,
+		})
+	}
+
+	return events, nextPageToken, nil
+}
+
+// UpdateStatus 原子更新任务状态（同一事务中写入 outbox）
+func (r *TaskRepository) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
+	return r.updateStatus(id, fromStatus, toStatus, nil)
 }
 
-// UpdateStatusWithEvent 原子更新任务状态并记录事件
+// UpdateStatusWithEvent 原子更新任务状态并记录事件，outbox 记录在同一事务中写入
 func (r *TaskRepository) UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error {
-	return r.db.ExecTx(func(tx *sql.Tx) error {
+	eventStmt, err := r.db.writeStmt(insertEventQuery)
+	if err != nil {
+		return err
+	}
+
+	return r.updateStatus(taskID, fromStatus, toStatus, func(tx *sql.Tx) error {
+		eventID := fmt.Sprintf("%s_%d", taskID, time.Now().UnixNano())
+		_, err := tx.Stmt(eventStmt).Exec(eventID, taskID, fromStatus, toStatus, message, formatTime(time.Now()), operator)
+		return err
+	})
+}
+
+// updateStatus 条件更新状态，执行 extra 后写入 outbox，全部在同一事务中完成
+func (r *TaskRepository) updateStatus(taskID string, fromStatus, toStatus model.TaskStatus, extra func(tx *sql.Tx) error) error {
+	query, scopeArgs := updateStatusQuery, []interface{}(nil)
+	if r.namespace != "" {
+		query, scopeArgs = updateStatusQuery+" AND namespace = ?", []interface{}{r.namespace}
+	}
+	updateStmt, err := r.db.writeStmt(query)
+	if err != nil {
+		return err
+	}
+
+	return r.withOutbox(func(tx *sql.Tx, emit outboxEmitter) error {
 		// 更新状态
-		query := `UPDATE tasks SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
-		result, err := tx.Exec(query, toStatus, time.Now().Format(time.RFC3339), taskID, fromStatus)
+		args := append([]interface{}{toStatus, formatTime(time.Now()), taskID, fromStatus}, scopeArgs...)
+		result, err := tx.Stmt(updateStmt).Exec(args...)
 		if err != nil {
 			return err
 		}
@@ -318,31 +484,43 @@ func (r *TaskRepository) UpdateStatusWithEvent(taskID string, fromStatus, toStat
 			return err
 		}
 		if rows == 0 {
-			return errors.New("task not found or status mismatch")
+			return ErrStatusMismatch
 		}
 
 		// 添加事件
-		eventID := fmt.Sprintf("%s_%d", taskID, time.Now().UnixNano())
-		eventQuery := `INSERT INTO task_events (id, task_id, from_status, to_status, message, timestamp, operator)
-			VALUES (?, ?, ?, ?, ?, ?, ?)`
-		_, err = tx.Exec(eventQuery, eventID, taskID, fromStatus, toStatus, message, time.Now().Format(time.RFC3339), operator)
+		if extra != nil {
+			if err := extra(tx); err != nil {
+				return err
+			}
+		}
 
-		return err
+		task, err := r.getTaskTx(tx, taskID)
+		if err != nil {
+			return err
+		}
+		return emit(model.ChangeTypeStatusChanged, task, fromStatus, toStatus)
 	})
 }
 
 // Search 搜索任务
+// 启用 FTS5 时按相关度排序，支持 "短语" 与 前缀* 查询；否则降级为 LIKE 匹配
 func (r *TaskRepository) Search(keyword string, limit, offset int) ([]*model.Task, error) {
-	searchPattern := "%" + keyword + "%"
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks 
-	WHERE name LIKE ? OR description LIKE ? OR task_type LIKE ?
-	ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	var query string
+	var args []interface{}
+
+	if match := buildFTSQuery(keyword); r.db.FTSEnabled() && match != "" {
+		conditions, scopeArgs := r.scope(nil, nil)
+		query = `SELECT ` + taskColumns + ` FROM tasks` + ftsMatchJoin + ` ` + whereClause(conditions) + `
+		ORDER BY fts.fts_rank, created_at DESC LIMIT ? OFFSET ?`
+		args = append(append([]interface{}{match}, scopeArgs...), limit, offset)
+	} else {
+		conditions, likeArgs := r.scope([]string{likeSearchCondition}, likeSearchArgs(keyword))
+		query = `SELECT ` + taskColumns + ` FROM tasks ` + whereClause(conditions) + `
+		ORDER BY created_at DESC LIMIT ? OFFSET ?`
+		args = append(likeArgs, limit, offset)
+	}
 
-	rows, err := r.db.DB().Query(query, searchPattern, searchPattern, searchPattern, limit, offset)
+	rows, err := r.db.DB().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -365,7 +543,7 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 	var task model.Task
 	var inputParams, outputResult, dependencies string
 	var createdAt, updatedAt string
-	var startedAt, completedAt sql.NullString
+	var startedAt, completedAt, labels, secretParams sql.NullString
 
 	err := row.Scan(
 		&task.ID,
@@ -385,13 +563,16 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 		&startedAt,
 		&completedAt,
 		&task.CreatedBy,
+		&labels,
+		&task.Namespace,
+		&secretParams,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	task.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	task.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	task.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	task.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
 
 	if startedAt.Valid {
 		task.StartedAt, _ = parseTime(startedAt.String)
@@ -403,16 +584,31 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 	json.Unmarshal([]byte(inputParams), &task.InputParams)
 	json.Unmarshal([]byte(outputResult), &task.OutputResult)
 	json.Unmarshal([]byte(dependencies), &task.Dependencies)
+	if labels.Valid {
+		json.Unmarshal([]byte(labels.String), &task.Labels)
+	}
+	if secretParams.Valid {
+		json.Unmarshal([]byte(secretParams.String), &task.SecretParams)
+	}
 
 	return &task, nil
 }
 
+// storedTimeLayout 时间存储格式：UTC、纳秒精度、定宽
+// 定宽保证字符串比较与时间先后一致（RFC3339Nano 会省略末尾的 0，无法直接比较）
+const storedTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime 时间的存储格式
+func formatTime(t time.Time) string {
+	return t.UTC().Format(storedTimeLayout)
+}
+
 // nullableTime 处理可空时间
 func nullableTime(t *time.Time) interface{} {
 	if t == nil {
 		return nil
 	}
-	return t.Format(time.RFC3339)
+	return formatTime(*t)
 }
 
 // parseTime 解析时间
@@ -420,7 +616,7 @@ func parseTime(s string) (*time.Time, error) {
 	if s == "" {
 		return nil, nil
 	}
-	t, err := time.Parse(time.RFC3339, s)
+	t, err := time.Parse(time.RFC3339Nano, s)
 	if err != nil {
 		return nil, err
 	}
@@ -430,50 +626,235 @@ func parseTime(s string) (*time.Time, error) {
 // BuildTaskFilter 构建任务过滤条件
 type TaskFilter struct {
 	Status    *model.TaskStatus
+	Statuses  []model.TaskStatus // 多状态过滤，与 Status 合并
 	Priority  *model.TaskPriority
 	TaskType  string
+	TaskTypes []string // 多类型过滤，与 TaskType 合并
 	CreatedBy string
 	Keyword   string
+	Namespace string // 限定命名空间，通常由 InNamespace 限定，后台组件按命名空间处理时使用
+
+	// 时间范围：After 为闭区间下界，Before 为开区间上界
+	CreatedAfter    *time.Time
+	CreatedBefore   *time.Time
+	UpdatedAfter    *time.Time
+	UpdatedBefore   *time.Time
+	CompletedAfter  *time.Time
+	CompletedBefore *time.Time
+	FinishedBefore  *time.Time // 完成时间（未记录时取更新时间）早于该时间，用于保留策略
+
+	HasError *bool             // true 仅返回有错误信息的任务，false 仅返回无错误信息的任务
+	Labels   map[string]string // 标签需全部匹配
+
+	Expression string // 过滤表达式，见 ParseFilter
+
+	ExcludeTaskTypes []string // 排除的任务类型
+
+	SortBy   string // 排序字段，见 taskSortFields
+	SortDesc bool
+
 	PageSize  int
 	PageIndex int
+	PageToken string // 游标分页令牌，仅 ListByCursor 使用
 }
 
-// ListByFilter 按条件过滤任务
-func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, error) {
-	// 构建 WHERE 子句
+// TaskPage 游标分页结果
+type TaskPage struct {
+	Tasks         []*model.Task
+	Total         int
+	NextPageToken string
+}
+
+// ErrInvalidSortField 不支持的排序字段
+var ErrInvalidSortField = errors.New("invalid sort field")
+
+// taskSortFields 允许排序的字段（白名单），值为实际排序列
+// 非唯一列追加 created_at 作为次级排序
+var taskSortFields = map[string][]string{
+	"created_at":  {"created_at"},
+	"updated_at":  {"updated_at"},
+	"priority":    {"priority", "created_at"},
+	"status":      {"status", "created_at"},
+	"name":        {"name", "created_at"},
+	"task_type":   {"task_type", "created_at"},
+	"retry_count": {"retry_count", "created_at"},
+}
+
+// defaultTaskSort 默认排序：优先级降序、创建时间降序（以 id 兜底）
+var defaultTaskSort = []sortKey{
+	{Column: "priority", Desc: true},
+	{Column: "created_at", Desc: true},
+}
+
+// sortKeys 解析排序字段，未指定时使用默认排序
+func (f TaskFilter) sortKeys() ([]sortKey, error) {
+	if f.SortBy == "" {
+		return defaultTaskSort, nil
+	}
+
+	columns, ok := taskSortFields[f.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSortField, f.SortBy)
+	}
+
+	keys := make([]sortKey, len(columns))
+	for i, column := range columns {
+		keys[i] = sortKey{Column: column, Desc: f.SortDesc}
+	}
+	return keys, nil
+}
+
+// fingerprint 查询条件指纹（不含分页参数）
+func (f TaskFilter) fingerprint() string {
+	f.PageSize, f.PageIndex, f.PageToken = 0, 0, ""
+	return queryFingerprint(f)
+}
+
+// conditions 构建除关键词外的 WHERE 条件
+func (f TaskFilter) conditions() ([]string, []interface{}, error) {
 	conditions := []string{}
 	var args []interface{}
 
-	if filter.Status != nil {
-		conditions = append(conditions, "status = ?")
-		args = append(args, *filter.Status)
+	statuses := f.Statuses
+	if f.Status != nil {
+		statuses = append([]model.TaskStatus{*f.Status}, statuses...)
+	}
+	if len(statuses) > 0 {
+		conditions = append(conditions, "status IN ("+placeholders(len(statuses))+")")
+		for _, status := range statuses {
+			args = append(args, status)
+		}
 	}
-	if filter.Priority != nil {
+	if f.Priority != nil {
 		conditions = append(conditions, "priority = ?")
-		args = append(args, *filter.Priority)
+		args = append(args, *f.Priority)
 	}
-	if filter.TaskType != "" {
-		conditions = append(conditions, "task_type = ?")
-		args = append(args, filter.TaskType)
+
+	taskTypes := f.TaskTypes
+	if f.TaskType != "" {
+		taskTypes = append([]string{f.TaskType}, taskTypes...)
+	}
+	if len(taskTypes) > 0 {
+		conditions = append(conditions, "task_type IN ("+placeholders(len(taskTypes))+")")
+		for _, taskType := range taskTypes {
+			args = append(args, taskType)
+		}
+	}
+	if len(f.ExcludeTaskTypes) > 0 {
+		conditions = append(conditions, "COALESCE(task_type, '') NOT IN ("+placeholders(len(f.ExcludeTaskTypes))+")")
+		for _, taskType := range f.ExcludeTaskTypes {
+			args = append(args, taskType)
+		}
 	}
-	if filter.CreatedBy != "" {
+	if f.CreatedBy != "" {
 		conditions = append(conditions, "created_by = ?")
-		args = append(args, filter.CreatedBy)
+		args = append(args, f.CreatedBy)
 	}
-	if filter.Keyword != "" {
-		searchPattern := "%" + filter.Keyword + "%"
-		conditions = append(conditions, "(name LIKE ? OR description LIKE ?)")
-		args = append(args, searchPattern, searchPattern)
+	if f.Namespace != "" {
+		conditions = append(conditions, "tasks.namespace = ?")
+		args = append(args, f.Namespace)
+	}
+
+	for _, r := range []struct {
+		column        string
+		after, before *time.Time
+	}{
+		{"created_at", f.CreatedAfter, f.CreatedBefore},
+		{"updated_at", f.UpdatedAfter, f.UpdatedBefore},
+		{"completed_at", f.CompletedAfter, f.CompletedBefore},
+	} {
+		if r.after != nil {
+			conditions = append(conditions, r.column+" >= ?")
+			args = append(args, formatTime(*r.after))
+		}
+		if r.before != nil {
+			conditions = append(conditions, r.column+" < ?")
+			args = append(args, formatTime(*r.before))
+		}
+	}
+
+	if f.FinishedBefore != nil {
+		conditions = append(conditions, "COALESCE(completed_at, updated_at) < ?")
+		args = append(args, formatTime(*f.FinishedBefore))
+	}
+
+	if f.HasError != nil {
+		if *f.HasError {
+			conditions = append(conditions, "COALESCE(error_message, '') != ''")
+		} else {
+			conditions = append(conditions, "COALESCE(error_message, '') = ''")
+		}
+	}
+
+	// 标签按键排序，保证生成的 SQL 稳定
+	labelKeys := make([]string, 0, len(f.Labels))
+	for key := range f.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+	for _, key := range labelKeys {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(tasks.labels) WHERE json_each.key = ? AND json_each.value = ?)")
+		args = append(args, key, f.Labels[key])
 	}
 
-	// 构建查询
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	expr, err := ParseFilter(f.Expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if expr != nil {
+		cond, exprArgs := expr.sql(time.Now())
+		conditions = append(conditions, cond)
+		args = append(args, exprArgs...)
+	}
+
+	return conditions, args, nil
+}
+
+// placeholders 生成 n 个逗号分隔的占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// whereClause 拼接 WHERE 子句
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// ListByFilter 按条件过滤任务（偏移分页）
+func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, error) {
+	// 构建 WHERE 子句
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return nil, 0, err
+	}
+	conditions, args = r.scope(conditions, args)
+
+	keys, err := filter.sortKeys()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 关键词搜索：启用 FTS5 时连接全文索引；未指定排序字段时按相关度排序
+	fromClause := "FROM tasks"
+	orderBy := orderByClause(keys, "id", true)
+	if filter.Keyword != "" {
+		if match := buildFTSQuery(filter.Keyword); r.db.FTSEnabled() && match != "" {
+			fromClause += ftsMatchJoin
+			args = append([]interface{}{match}, args...)
+			if filter.SortBy == "" {
+				orderBy = "fts.fts_rank, " + orderBy
+			}
+		} else {
+			conditions = append(conditions, likeSearchCondition)
+			args = append(args, likeSearchArgs(filter.Keyword)...)
+		}
 	}
 
 	// 查询总数
-	countQuery := "SELECT COUNT(*) FROM tasks " + whereClause
+	countQuery := fmt.Sprintf("SELECT COUNT(*) %s %s", fromClause, whereClause(conditions))
 	var total int
 	if err := r.db.DB().QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
@@ -489,28 +870,180 @@ func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, er
 	offset := filter.PageIndex * filter.PageSize
 
 	// 查询列表
-	listQuery := fmt.Sprintf(`SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks %s ORDER BY priority DESC, created_at DESC LIMIT ? OFFSET ?`, whereClause)
+	listQuery := fmt.Sprintf(`SELECT %s %s %s ORDER BY %s LIMIT ? OFFSET ?`,
+		taskColumns, fromClause, whereClause(conditions), orderBy)
 
 	args = append(args, filter.PageSize, offset)
 
-	rows, err := r.db.DB().Query(listQuery, args...)
+	tasks, err := r.queryTasks(listQuery, args...)
 	if err != nil {
 		return nil, 0, err
 	}
+
+	return tasks, total, nil
+}
+
+// ListByCursor 按条件过滤任务（游标分页）
+// 基于 (排序键, id) 的 keyset 分页，翻页期间插入新任务不会导致重复或遗漏；
+// 启用 FTS5 且未指定排序字段时，关键词结果按 (相关度, 默认排序键, id) 分页，与偏移分页的顺序一致
+func (r *TaskRepository) ListByCursor(filter TaskFilter) (*TaskPage, error) {
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return nil, err
+	}
+	conditions, args = r.scope(conditions, args)
+
+	keys, err := filter.sortKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	fromClause := "FROM tasks"
+	ranked := false
+	if filter.Keyword != "" {
+		if match := buildFTSQuery(filter.Keyword); r.db.FTSEnabled() && match != "" {
+			fromClause += ftsMatchJoin
+			args = append([]interface{}{match}, args...)
+			if filter.SortBy == "" {
+				ranked = true
+				keys = append([]sortKey{{Column: "fts.fts_rank"}}, keys...)
+			}
+		} else {
+			conditions = append(conditions, likeSearchCondition)
+			args = append(args, likeSearchArgs(filter.Keyword)...)
+		}
+	}
+
+	// 查询总数（不受游标影响）
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) %s %s", fromClause, whereClause(conditions))
+	if err := r.db.DB().QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	fingerprint := filter.fingerprint()
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken, fingerprint, len(keys))
+		if err != nil {
+			return nil, err
+		}
+		cond, condArgs := keysetCondition(keys, "id", true, cursor)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	// 多取一行用于判断是否还有下一页；按相关度分页时额外读取相关度写入游标
+	columns := taskColumns
+	if ranked {
+		columns += ", fts.fts_rank"
+	}
+	listQuery := fmt.Sprintf(`SELECT %s %s %s ORDER BY %s LIMIT ?`,
+		columns, fromClause, whereClause(conditions), orderByClause(keys, "id", true))
+	args = append(args, filter.PageSize+1)
+
+	tasks, ranks, err := r.queryRankedTasks(ranked, listQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TaskPage{Tasks: tasks, Total: total}
+	if len(tasks) > filter.PageSize {
+		page.Tasks = tasks[:filter.PageSize]
+		last := page.Tasks[len(page.Tasks)-1]
+		cursor := pageCursor{Fingerprint: fingerprint, ID: last.ID}
+		for _, key := range keys {
+			if key.Column == "fts.fts_rank" {
+				cursor.Keys = append(cursor.Keys, ranks[filter.PageSize-1])
+				continue
+			}
+			cursor.Keys = append(cursor.Keys, taskSortValue(last, key.Column))
+		}
+		page.NextPageToken = encodePageToken(cursor)
+	}
+
+	return page, nil
+}
+
+// rankScanner 在任务列之后额外读取相关度
+type rankScanner struct {
+	rows *sql.Rows
+	rank float64
+}
+
+func (s *rankScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, &s.rank)...)
+}
+
+// queryRankedTasks 查询任务列表，ranked 时每行末尾多一列相关度，按行返回
+func (r *TaskRepository) queryRankedTasks(ranked bool, query string, args ...interface{}) ([]*model.Task, []float64, error) {
+	if !ranked {
+		tasks, err := r.queryTasks(query, args...)
+		return tasks, nil, err
+	}
+
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*model.Task
+	var ranks []float64
+	scanner := &rankScanner{rows: rows}
+	for rows.Next() {
+		task, err := r.scanTask(scanner)
+		if err != nil {
+			return nil, nil, err
+		}
+		tasks = append(tasks, task)
+		ranks = append(ranks, scanner.rank)
+	}
+
+	return tasks, ranks, rows.Err()
+}
+
+// taskSortValue 取任务排序键的值，格式需与数据库存储一致
+func taskSortValue(task *model.Task, column string) interface{} {
+	switch column {
+	case "priority":
+		return int32(task.Priority)
+	case "status":
+		return int32(task.Status)
+	case "retry_count":
+		return task.RetryCount
+	case "name":
+		return task.Name
+	case "task_type":
+		return task.TaskType
+	case "created_at":
+		return formatTime(task.CreatedAt)
+	case "updated_at":
+		return formatTime(task.UpdatedAt)
+	default:
+		return nil
+	}
+}
+
+// queryTasks 执行查询并扫描任务列表
+func (r *TaskRepository) queryTasks(query string, args ...interface{}) ([]*model.Task, error) {
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var tasks []*model.Task
 	for rows.Next() {
 		task, err := r.scanTask(rows)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
 
-	return tasks, total, rows.Err()
+	return tasks, rows.Err()
 }
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "taskflow/proto"
+)
+
+// apiKeyRequestBody 创建/修改 API Key 请求体，expires_at 为 RFC3339 时间
+type apiKeyRequestBody struct {
+	Name        *string    `json:"name"`
+	Owner       string     `json:"owner"`
+	Scopes      *[]string  `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	ClearExpiry bool       `json:"clear_expiry"`
+}
+
+// bindAPIKeyRequest 解析 API Key 请求体
+func bindAPIKeyRequest(c *gin.Context) (*apiKeyRequestBody, bool) {
+	var body apiKeyRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return nil, false
+	}
+	return &body, true
+}
+
+// optionalTimestamp 转换可选时间
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// handleCreateAPIKey 创建 API Key，明文密钥只返回一次
+func (s *Server) handleCreateAPIKey(c *gin.Context) {
+	body, ok := bindAPIKeyRequest(c)
+	if !ok {
+		return
+	}
+
+	req := &pb.CreateAPIKeyRequest{Owner: body.Owner, ExpiresAt: optionalTimestamp(body.ExpiresAt)}
+	if body.Name != nil {
+		req.Name = *body.Name
+	}
+	if body.Scopes != nil {
+		req.Scopes = *body.Scopes
+	}
+
+	resp, err := s.apiKeyHandler.CreateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// handleListAPIKeys 列出 API Key
+func (s *Server) handleListAPIKeys(c *gin.Context) {
+	resp, err := s.apiKeyHandler.ListAPIKeys(c.Request.Context(), &pb.ListAPIKeysRequest{Owner: c.Query("owner")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+// handleGetAPIKey 获取 API Key
+func (s *Server) handleGetAPIKey(c *gin.Context) {
+	key, err := s.apiKeyHandler.GetAPIKey(c.Request.Context(), &pb.GetAPIKeyRequest{Id: c.Param("id")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, key)
+}
+
+// handleUpdateAPIKey 修改 API Key，只更新请求体中出现的字段
+func (s *Server) handleUpdateAPIKey(c *gin.Context) {
+	body, ok := bindAPIKeyRequest(c)
+	if !ok {
+		return
+	}
+
+	req := &pb.UpdateAPIKeyRequest{
+		Id:          c.Param("id"),
+		Name:        body.Name,
+		ExpiresAt:   optionalTimestamp(body.ExpiresAt),
+		ClearExpiry: body.ClearExpiry,
+	}
+	if body.Scopes != nil {
+		req.Scopes, req.UpdateScopes = *body.Scopes, true
+	}
+
+	key, err := s.apiKeyHandler.UpdateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, key)
+}
+
+// handleDeleteAPIKey 删除 API Key
+func (s *Server) handleDeleteAPIKey(c *gin.Context) {
+	resp, err := s.apiKeyHandler.DeleteAPIKey(c.Request.Context(), &pb.DeleteAPIKeyRequest{Id: c.Param("id")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
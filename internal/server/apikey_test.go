@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/config"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/middleware"
+	"taskflow/internal/repository"
+)
+
+const testAuthSecret = "0123456789abcdef0123456789abcdef"
+
+func TestServer_AuthAndRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	s := &Server{
+		cfg: &config.Config{Auth: config.AuthConfig{
+			Enabled: true, Secret: testAuthSecret, TokenExpireHours: 1, RefreshExpireHours: 1,
+		}},
+		taskHandler: handler.NewTaskHandler(repository.NewTaskRepository(db)),
+	}
+	if err := s.initAuth(db); err != nil {
+		t.Fatalf("initAuth failed: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Auth(s.authVerifier), middleware.Authorize(s.authDefaultRole(), restMethods))
+	s.registerRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	issuer, _ := grpc_middleware.NewTokenIssuer(&grpc_middleware.AuthConfig{Secret: testAuthSecret})
+	token, _, err := issuer.Issue("ci-admin", "CI", grpc_middleware.TokenUseAccess, []string{"operator"}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	do := func(method, path, body string, header ...string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodGet, "/api/v1/tasks", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/api/v1/admin/api-keys", `{"name": "pipeline", "owner": "someone-else"}`,
+		"Authorization", "Bearer "+token)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 when creating a key for another user, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/api/v1/admin/api-keys", `{"name": "pipeline", "scopes": ["admin"]}`,
+		"Authorization", "Bearer "+token)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 when granting a role above the caller's, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/api/v1/admin/api-keys", `{"name": "pipeline", "scopes": ["viewer"]}`,
+		"Authorization", "Bearer "+token)
+	var created struct {
+		ApiKey struct {
+			Id    string `json:"id"`
+			Owner string `json:"owner"`
+		} `json:"api_key"`
+		Key string `json:"key"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || created.Key == "" || created.ApiKey.Owner != "ci-admin" {
+		t.Fatalf("unexpected create response %d %+v", resp.StatusCode, created)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/tasks", "", grpc_middleware.APIKeyHeader, created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with API key, got %d", resp.StatusCode)
+	}
+
+	// viewer Key 不能创建任务，也不能访问只允许 admin 的接口
+	resp = do(http.MethodPost, "/api/v1/tasks", `{"name": "x"}`, grpc_middleware.APIKeyHeader, created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for viewer creating a task, got %d", resp.StatusCode)
+	}
+	resp = do(http.MethodPost, "/api/v1/admin/backup", "", "Authorization", "Bearer "+token)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for operator backup, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/tasks", "", grpc_middleware.APIKeyHeader, created.Key+"x")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong API key, got %d", resp.StatusCode)
+	}
+
+	// 通过 API Key 认证时调用者即其所有者，可以删除这把 Key
+	resp = do(http.MethodDelete, "/api/v1/admin/api-keys/"+created.ApiKey.Id, "", grpc_middleware.APIKeyHeader, created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", resp.StatusCode)
+	}
+	resp = do(http.MethodGet, "/api/v1/tasks", "", grpc_middleware.APIKeyHeader, created.Key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected deleted key to be rejected, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "taskflow/proto"
+)
+
+// handleListAuditEvents 查询审计日志
+// 支持的查询参数：user_id、method、resource、since/until（RFC3339 或 Unix 秒）、page_size、page_token、verify=true
+func (s *Server) handleListAuditEvents(c *gin.Context) {
+	req := &pb.ListAuditEventsRequest{
+		UserId:    c.Query("user_id"),
+		Method:    c.Query("method"),
+		Resource:  c.Query("resource"),
+		PageSize:  int32(parseInt(c.Query("page_size"), 50)),
+		PageToken: c.Query("page_token"),
+	}
+
+	for param, dst := range map[string]**timestamppb.Timestamp{
+		"since": &req.Since,
+		"until": &req.Until,
+	} {
+		v, err := parseTimeParam(c.Query(param))
+		if err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": fmt.Sprintf("invalid request: %s: %v", param, err)})
+			return
+		}
+		if v != 0 {
+			*dst = timestamppb.New(time.Unix(v, 0))
+		}
+	}
+
+	if v := c.Query("verify"); v != "" {
+		verify, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": "invalid request: verify: " + err.Error()})
+			return
+		}
+		req.Verify = verify
+	}
+
+	resp, err := s.auditHandler.ListAuditEvents(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/config"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/middleware"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+)
+
+func TestServer_AuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	audit := service.NewAuditService(repository.NewAuditRepository(db))
+	s := &Server{
+		cfg: &config.Config{Auth: config.AuthConfig{
+			Enabled: true, Secret: testAuthSecret, TokenExpireHours: 1, RefreshExpireHours: 1,
+		}},
+		taskHandler:  handler.NewTaskHandler(repository.NewTaskRepository(db)),
+		audit:        audit,
+		auditHandler: handler.NewAuditHandler(audit),
+	}
+	if err := s.initAuth(db); err != nil {
+		t.Fatalf("initAuth failed: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Auth(s.authVerifier), middleware.Audit(s.audit, restMethods),
+		middleware.Authorize(s.authDefaultRole(), restMethods))
+	s.registerRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	issuer, _ := grpc_middleware.NewTokenIssuer(&grpc_middleware.AuthConfig{Secret: testAuthSecret})
+	operator, _, err := issuer.Issue("ci-bot", "CI", grpc_middleware.TokenUseAccess, []string{"operator"}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	admin, _, err := issuer.Issue("root", "Root", grpc_middleware.TokenUseAccess, []string{"admin"}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	do := func(method, path, body, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/api/v1/tasks", `{"name": "nightly"}`, operator)
+	var task struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&task)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || task.ID == "" {
+		t.Fatalf("expected task created, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/tasks/"+task.ID, "", operator)
+	resp.Body.Close()
+
+	// 被拒绝的调用同样记录
+	resp = do(http.MethodPost, "/api/v1/admin/backup", "", operator)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for operator backup, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/admin/audit-events", "", operator)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for operator listing audit events, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/v1/admin/audit-events?user_id=ci-bot&verify=true", "", admin)
+	var list struct {
+		Events []struct {
+			UserID    string `json:"user_id"`
+			Transport string `json:"transport"`
+			Method    string `json:"method"`
+			Resource  string `json:"resource"`
+			Code      string `json:"code"`
+		} `json:"events"`
+		ChainValid  bool  `json:"chain_valid"`
+		ChainLength int64 `json:"chain_length"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 listing audit events, got %d", resp.StatusCode)
+	}
+	if len(list.Events) != 2 || !list.ChainValid || list.ChainLength != 2 {
+		t.Fatalf("expected two recorded calls and an intact chain, got %+v", list)
+	}
+	denied, created := list.Events[0], list.Events[1]
+	if denied.Method != "/taskflow.TaskService/BackupDatabase" || denied.Code != "PermissionDenied" {
+		t.Errorf("unexpected denied event %+v", denied)
+	}
+	if created.Method != "/taskflow.TaskService/CreateTask" || created.Resource != task.ID ||
+		created.Code != "OK" || created.UserID != "ci-bot" || created.Transport != "http" {
+		t.Errorf("unexpected create event %+v", created)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+)
+
+// restMethods REST 路由对应的 gRPC 方法，授权时按 grpc_middleware.MethodRoles 检查
+// 未列出的路由只允许 admin 访问
+var restMethods = map[string]string{
+	"GET /api/v1/tasks":                     "/taskflow.TaskService/ListTasks",
+	"POST /api/v1/tasks":                    "/taskflow.TaskService/CreateTask",
+	"GET /api/v1/tasks/:id":                 "/taskflow.TaskService/GetTask",
+	"PUT /api/v1/tasks/:id":                 "/taskflow.TaskService/UpdateTask",
+	"DELETE /api/v1/tasks/:id":              "/taskflow.TaskService/DeleteTask",
+	"POST /api/v1/tasks/:id/cancel":         "/taskflow.TaskService/CancelTask",
+	"POST /api/v1/tasks/:id/retry":          "/taskflow.TaskService/RetryTask",
+	"GET /api/v1/tasks/:id/events":          "/taskflow.TaskService/ListTaskEvents",
+	"GET " + waitTaskPath:                   "/taskflow.TaskService/WaitTask",
+	"GET /api/v1/tasks/stats":               "/taskflow.TaskService/ListTasks",
+	"POST /api/v1/tasks/bulk/cancel":        "/taskflow.TaskService/BulkCancel",
+	"POST /api/v1/tasks/bulk/retry":         "/taskflow.TaskService/BulkRetry",
+	"POST /api/v1/tasks/bulk/priority":      "/taskflow.TaskService/BulkSetPriority",
+	"GET /api/v1/tasks/bulk/:id":            "/taskflow.TaskService/GetBulkOperation",
+	"GET " + watchSSEPath:                   "/taskflow.TaskService/WatchTask",
+	"GET " + watchWSPath:                    "/taskflow.TaskService/WatchTask",
+	"POST /api/v1/admin/backup":             "/taskflow.TaskService/BackupDatabase",
+	"POST /api/v1/admin/api-keys":           "/taskflow.APIKeyService/CreateAPIKey",
+	"GET /api/v1/admin/api-keys":            "/taskflow.APIKeyService/ListAPIKeys",
+	"GET /api/v1/admin/api-keys/:id":        "/taskflow.APIKeyService/GetAPIKey",
+	"PATCH /api/v1/admin/api-keys/:id":      "/taskflow.APIKeyService/UpdateAPIKey",
+	"DELETE /api/v1/admin/api-keys/:id":     "/taskflow.APIKeyService/DeleteAPIKey",
+	"POST /api/v1/admin/namespaces":         "/taskflow.NamespaceService/CreateNamespace",
+	"GET /api/v1/admin/namespaces":          "/taskflow.NamespaceService/ListNamespaces",
+	"GET /api/v1/admin/namespaces/:name":    "/taskflow.NamespaceService/GetNamespace",
+	"PATCH /api/v1/admin/namespaces/:name":  "/taskflow.NamespaceService/UpdateNamespace",
+	"DELETE /api/v1/admin/namespaces/:name": "/taskflow.NamespaceService/DeleteNamespace",
+	"GET /api/v1/admin/audit-events":        "/taskflow.AuditService/ListAuditEvents",
+}
+
+// authConfig 将配置转换为拦截器使用的认证配置
+func (s *Server) authConfig() *grpc_middleware.AuthConfig {
+	return &grpc_middleware.AuthConfig{
+		Secret:           s.cfg.Auth.Secret,
+		JWKSFile:         config.ExpandHome(s.cfg.Auth.JWKSFile),
+		Issuer:           s.cfg.Auth.Issuer,
+		Audience:         s.cfg.Auth.Audience,
+		ClockSkew:        s.cfg.GetAuthClockSkew(),
+		SigningKeyFile:   config.ExpandHome(s.cfg.Auth.SigningKeyFile),
+		SigningKeyID:     s.cfg.Auth.SigningKeyID,
+		TokenExpireHours: s.cfg.Auth.TokenExpireHours,
+	}
+}
+
+// authDefaultRole 未携带角色的调用者使用的角色
+func (s *Server) authDefaultRole() grpc_middleware.Role {
+	role, ok := grpc_middleware.ParseRole(s.cfg.GetAuthDefaultRole())
+	if !ok {
+		return grpc_middleware.RoleViewer
+	}
+	return role
+}
+
+// initAuth 初始化令牌校验、吊销列表与 API Key；配置了签发密钥时启用 AuthService
+// 只配置 JWKS 时令牌由外部签发，不提供登录接口
+func (s *Server) initAuth(db *repository.SQLite) error {
+	cfg := s.authConfig()
+
+	verifier, err := grpc_middleware.NewTokenVerifier(cfg)
+	if err != nil {
+		return err
+	}
+	authRepo := repository.NewAuthRepository(db)
+	revoked, err := service.NewRevocationList(authRepo)
+	if err != nil {
+		return err
+	}
+	verifier.SetRevocationChecker(revoked)
+	s.authVerifier = verifier
+
+	// API Key 与 JWT 共用同一校验入口，gRPC 与 REST 均可使用
+	apiKeys := service.NewAPIKeyService(authRepo)
+	verifier.SetAPIKeyAuthenticator(apiKeys)
+	s.apiKeyHandler = handler.NewAPIKeyHandler(apiKeys)
+
+	if cfg.Secret == "" && cfg.SigningKeyFile == "" {
+		logger.Info("No token signing key configured, AuthService is disabled")
+		return nil
+	}
+
+	issuer, err := grpc_middleware.NewTokenIssuer(cfg)
+	if err != nil {
+		return err
+	}
+
+	var users *service.UserStore
+	if s.cfg.Auth.UsersFile != "" {
+		if users, err = service.LoadUserStore(config.ExpandHome(s.cfg.Auth.UsersFile)); err != nil {
+			return err
+		}
+	}
+
+	auth := service.NewAuthService(users, issuer, verifier, revoked,
+		time.Duration(s.cfg.Auth.TokenExpireHours)*time.Hour,
+		time.Duration(s.cfg.Auth.RefreshExpireHours)*time.Hour)
+	s.authHandler = handler.NewAuthHandler(auth)
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "taskflow/proto"
+)
+
+// bulkRequestBody 批量操作请求体
+// filter 使用 ListTasksRequest 的 JSON 形式，状态可写枚举名，例如
+// {"filter": {"status_filter": ["TASK_STATUS_FAILED"], "task_type": "etl"}, "dry_run": true}
+type bulkRequestBody struct {
+	IDs      []string        `json:"ids"`
+	Filter   json.RawMessage `json:"filter"`
+	DryRun   bool            `json:"dry_run"`
+	Operator string          `json:"operator"`
+	Priority int32           `json:"priority"`
+}
+
+// bindBulkRequest 解析批量操作请求体
+func bindBulkRequest(c *gin.Context) (*bulkRequestBody, *pb.ListTasksRequest, bool) {
+	var body bulkRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return nil, nil, false
+	}
+
+	var filter *pb.ListTasksRequest
+	if len(body.Filter) > 0 && string(body.Filter) != "null" {
+		filter = &pb.ListTasksRequest{}
+		if err := protojson.Unmarshal(body.Filter, filter); err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": "invalid request: filter: " + err.Error()})
+			return nil, nil, false
+		}
+	}
+	return &body, filter, true
+}
+
+// respondBulkOperation 试运行返回 200，已启动的后台操作返回 202
+func respondBulkOperation(c *gin.Context, op *pb.BulkOperation) {
+	code := http.StatusAccepted
+	if op.DryRun {
+		code = http.StatusOK
+	}
+	c.JSON(code, op)
+}
+
+// handleBulkCancel 批量取消任务
+func (s *Server) handleBulkCancel(c *gin.Context) {
+	body, filter, ok := bindBulkRequest(c)
+	if !ok {
+		return
+	}
+
+	op, err := s.taskHandler.BulkCancel(c.Request.Context(), &pb.BulkTaskRequest{
+		Ids: body.IDs, Filter: filter, DryRun: body.DryRun, Operator: body.Operator,
+	})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	respondBulkOperation(c, op)
+}
+
+// handleBulkRetry 批量重试失败的任务
+func (s *Server) handleBulkRetry(c *gin.Context) {
+	body, filter, ok := bindBulkRequest(c)
+	if !ok {
+		return
+	}
+
+	op, err := s.taskHandler.BulkRetry(c.Request.Context(), &pb.BulkTaskRequest{
+		Ids: body.IDs, Filter: filter, DryRun: body.DryRun, Operator: body.Operator,
+	})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	respondBulkOperation(c, op)
+}
+
+// handleBulkSetPriority 批量调整优先级
+func (s *Server) handleBulkSetPriority(c *gin.Context) {
+	body, filter, ok := bindBulkRequest(c)
+	if !ok {
+		return
+	}
+
+	op, err := s.taskHandler.BulkSetPriority(c.Request.Context(), &pb.BulkSetPriorityRequest{
+		Ids: body.IDs, Filter: filter, DryRun: body.DryRun, Operator: body.Operator,
+		Priority: pb.TaskPriority(body.Priority),
+	})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	respondBulkOperation(c, op)
+}
+
+// handleGetBulkOperation 查询批量操作进度
+func (s *Server) handleGetBulkOperation(c *gin.Context) {
+	op, err := s.taskHandler.GetBulkOperation(c.Request.Context(), &pb.GetBulkOperationRequest{Id: c.Param("id")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, op)
+}
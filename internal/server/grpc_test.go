@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"taskflow/internal/config"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// panicProbe 测试用服务，处理器直接 panic
+var panicProbe = grpc.ServiceDesc{
+	ServiceName: "taskflow.Probe",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Panic",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/taskflow.Probe/Panic"}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic("probe")
+			})
+		},
+	}},
+}
+
+func TestServer_GRPCInterceptorChain(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	prev := logger.Logger
+	logger.Logger = zap.New(core).Sugar()
+	t.Cleanup(func() { logger.Logger = prev })
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "grpc.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	audit := service.NewAuditService(repository.NewAuditRepository(db))
+	repo := repository.NewTaskRepository(db)
+	s := &Server{
+		cfg: &config.Config{
+			Auth: config.AuthConfig{Enabled: true, Secret: testAuthSecret, TokenExpireHours: 1, RefreshExpireHours: 1},
+			GRPC: config.GRPCConfig{Recovery: true, Logging: true, RateLimit: 0.001, RateBurst: 3},
+		},
+		taskHandler: handler.NewTaskHandler(repo),
+		audit:       audit,
+	}
+	if err := s.initAuth(db); err != nil {
+		t.Fatalf("initAuth failed: %v", err)
+	}
+
+	grpcServer, err := s.newGRPCServer()
+	if err != nil {
+		t.Fatalf("failed to create gRPC server: %v", err)
+	}
+	grpcServer.RegisterService(&panicProbe, struct{}{})
+	lis := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewTaskServiceClient(conn)
+
+	issuer, _ := grpc_middleware.NewTokenIssuer(&grpc_middleware.AuthConfig{Secret: testAuthSecret})
+	issue := func(userID, role string) string {
+		t.Helper()
+		token, _, err := issuer.Issue(userID, userID, grpc_middleware.TokenUseAccess, []string{role}, "", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		return token
+	}
+	as := func(token string, kv ...string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), append([]string{"authorization", "Bearer " + token}, kv...)...)
+	}
+	admin, viewer := issue("root", "admin"), issue("reader", "viewer")
+
+	// 认证
+	if _, err := client.ListTasks(context.Background(), &pb.ListTasksRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+
+	// 授权
+	if _, err := client.CreateTask(as(viewer), &pb.CreateTaskRequest{Name: "denied"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for viewer, got %v", err)
+	}
+
+	// 命名空间与审计
+	created, err := client.CreateTask(as(admin, grpc_middleware.NamespaceHeader, "team-a"), &pb.CreateTaskRequest{Name: "nightly"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	stored, err := repo.GetByID(created.Id)
+	if err != nil || stored.Namespace != "team-a" {
+		t.Fatalf("expected task in namespace team-a, got %+v: %v", stored, err)
+	}
+	events, _, err := audit.List(context.Background(), repository.AuditFilter{UserID: "root"}, 10, "")
+	if err != nil || len(events) != 1 || events[0].Resource != created.Id {
+		t.Fatalf("expected the create call audited, got %+v: %v", events, err)
+	}
+
+	// panic 恢复后服务仍可用
+	if err := conn.Invoke(as(admin), "/taskflow.Probe/Panic", &emptypb.Empty{}, &emptypb.Empty{}); status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal for a panicking handler, got %v", err)
+	}
+	if _, err := client.GetTask(as(admin, grpc_middleware.NamespaceHeader, "team-a"), &pb.GetTaskRequest{Id: created.Id}); err != nil {
+		t.Fatalf("expected server to survive the panic, got %v", err)
+	}
+
+	// 限流按调用者计算
+	limited := issue("burst", "viewer")
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListTasks(as(limited), &pb.ListTasksRequest{}); err != nil {
+			t.Fatalf("request %d within burst failed: %v", i+1, err)
+		}
+	}
+	if _, err := client.ListTasks(as(limited), &pb.ListTasksRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted after the burst, got %v", err)
+	}
+	if _, err := client.ListTasks(as(viewer), &pb.ListTasksRequest{}); err != nil {
+		t.Fatalf("expected other callers unaffected, got %v", err)
+	}
+
+	// 日志
+	var completed, panicked bool
+	for _, entry := range logs.All() {
+		completed = completed || strings.Contains(entry.Message, "RPC completed")
+		panicked = panicked || (entry.Level == zap.ErrorLevel && strings.Contains(entry.Message, "[PANIC]"))
+		if strings.Contains(entry.Message, admin) {
+			t.Error("bearer token must not be logged")
+		}
+	}
+	if !completed || !panicked {
+		t.Errorf("expected call and panic logs, got %d entries", logs.Len())
+	}
+}
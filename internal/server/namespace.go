@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	pb "taskflow/proto"
+)
+
+// namespaceRequestBody 创建/修改命名空间请求体，未出现的字段在修改时保持不变
+type namespaceRequestBody struct {
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	MaxTasks       *int32  `json:"max_tasks"`
+	MaxActiveTasks *int32  `json:"max_active_tasks"`
+}
+
+// bindNamespaceRequest 解析命名空间请求体
+func bindNamespaceRequest(c *gin.Context) (*namespaceRequestBody, bool) {
+	var body namespaceRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return nil, false
+	}
+	return &body, true
+}
+
+// handleCreateNamespace 创建命名空间
+func (s *Server) handleCreateNamespace(c *gin.Context) {
+	body, ok := bindNamespaceRequest(c)
+	if !ok {
+		return
+	}
+
+	req := &pb.CreateNamespaceRequest{Name: body.Name}
+	if body.Description != nil {
+		req.Description = *body.Description
+	}
+	if body.MaxTasks != nil {
+		req.MaxTasks = *body.MaxTasks
+	}
+	if body.MaxActiveTasks != nil {
+		req.MaxActiveTasks = *body.MaxActiveTasks
+	}
+
+	ns, err := s.namespaceHandler.CreateNamespace(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ns)
+}
+
+// handleListNamespaces 列出命名空间
+func (s *Server) handleListNamespaces(c *gin.Context) {
+	resp, err := s.namespaceHandler.ListNamespaces(c.Request.Context(), &pb.ListNamespacesRequest{})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+// handleGetNamespace 获取命名空间
+func (s *Server) handleGetNamespace(c *gin.Context) {
+	ns, err := s.namespaceHandler.GetNamespace(c.Request.Context(), &pb.GetNamespaceRequest{Name: c.Param("name")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, ns)
+}
+
+// handleUpdateNamespace 修改命名空间，只更新请求体中出现的字段
+func (s *Server) handleUpdateNamespace(c *gin.Context) {
+	body, ok := bindNamespaceRequest(c)
+	if !ok {
+		return
+	}
+
+	ns, err := s.namespaceHandler.UpdateNamespace(c.Request.Context(), &pb.UpdateNamespaceRequest{
+		Name:           c.Param("name"),
+		Description:    body.Description,
+		MaxTasks:       body.MaxTasks,
+		MaxActiveTasks: body.MaxActiveTasks,
+	})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, ns)
+}
+
+// handleDeleteNamespace 删除命名空间
+func (s *Server) handleDeleteNamespace(c *gin.Context) {
+	resp, err := s.namespaceHandler.DeleteNamespace(c.Request.Context(), &pb.DeleteNamespaceRequest{Name: c.Param("name")})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
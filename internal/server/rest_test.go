@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/config"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// setupRESTServer 创建未启用认证的 REST 服务，命名空间取自 x-namespace 请求头
+func setupRESTServer(t *testing.T) (*httptest.Server, *repository.TaskRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "rest.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	repo := repository.NewTaskRepository(db)
+	s := &Server{cfg: &config.Config{}, taskRepo: repo, taskHandler: handler.NewTaskHandler(repo)}
+	router := gin.New()
+	router.Use(middleware.Namespace())
+	s.registerRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return ts, repo
+}
+
+// doJSON 发送请求并解析 JSON 响应
+func doJSON(t *testing.T, method, url, body string, out interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestServer_ListTasksPaging(t *testing.T) {
+	ts, repo := setupRESTServer(t)
+	for i := 0; i < 3; i++ {
+		task := model.NewTask(fmt.Sprintf("page-%d", i), "", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+		task.ID = fmt.Sprintf("page-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	type listResponse struct {
+		Tasks         []struct{ ID string } `json:"tasks"`
+		Total         int32                 `json:"total"`
+		Page          int32                 `json:"page"`
+		NextPageToken string                `json:"next_page_token"`
+	}
+
+	// 未传 page 时默认第 1 页，返回总数与页码
+	var list listResponse
+	if code := doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks?page_size=2", "", &list); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if list.Page != 1 || list.Total != 3 || len(list.Tasks) != 2 || list.NextPageToken != "" {
+		t.Fatalf("expected page 1 of offset paging, got %+v", list)
+	}
+
+	// page=0 开启游标分页，之后传 page_token
+	var first, second listResponse
+	doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks?page=0&page_size=2", "", &first)
+	if first.NextPageToken == "" || len(first.Tasks) != 2 {
+		t.Fatalf("expected a cursor page with next_page_token, got %+v", first)
+	}
+	doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks?page_size=2&page_token="+first.NextPageToken, "", &second)
+	if len(second.Tasks) != 1 || second.NextPageToken != "" {
+		t.Fatalf("expected the last cursor page, got %+v", second)
+	}
+}
+
+func TestServer_ListTaskEventsInvalidToken(t *testing.T) {
+	ts, repo := setupRESTServer(t)
+	task := model.NewTask("events", "", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+	task.ID = "events-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	if code := doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks/events-1/events?page_token=not-a-token", "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed page token, got %d", code)
+	}
+	if code := doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks/events-1/events", "", nil); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+}
+
+func TestServer_ListTasksInvalidParams(t *testing.T) {
+	ts, _ := setupRESTServer(t)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown sort field", "sort_by=secret_params"},
+		{"cursor with unknown sort field", "page=0&sort_by=secret_params"},
+		{"unparsable filter", "filter=" + url.QueryEscape("status in (FAILED")},
+		{"unknown filter field", "filter=" + url.QueryEscape("owner = 'bob'")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := doJSON(t, http.MethodGet, ts.URL+"/api/v1/tasks?"+tt.query, "", nil); code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", code)
+			}
+		})
+	}
+}
+
+func TestServer_CreateTaskSpoofedCreator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "spoof.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	s := &Server{
+		cfg: &config.Config{Auth: config.AuthConfig{
+			Enabled: true, Secret: testAuthSecret, TokenExpireHours: 1, RefreshExpireHours: 1,
+		}},
+		taskHandler: handler.NewTaskHandler(repository.NewTaskRepository(db)),
+	}
+	if err := s.initAuth(db); err != nil {
+		t.Fatalf("initAuth failed: %v", err)
+	}
+	router := gin.New()
+	router.Use(middleware.Auth(s.authVerifier), middleware.Authorize(s.authDefaultRole(), restMethods))
+	s.registerRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	issuer, _ := grpc_middleware.NewTokenIssuer(&grpc_middleware.AuthConfig{Secret: testAuthSecret})
+	token, _, err := issuer.Issue("alice", "Alice", grpc_middleware.TokenUseAccess, []string{"submitter"}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	create := func(body string) (int, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	// 非 admin 代他人填写 created_by 被拒绝
+	if code, _ := create(`{"name": "spoofed", "created_by": "bob"}`); code != http.StatusForbidden {
+		t.Errorf("expected 403 for a spoofed created_by, got %d", code)
+	}
+
+	code, task := create(`{"name": "own"}`)
+	if code != http.StatusCreated || task["created_by"] != "alice" {
+		t.Errorf("expected task created by alice, got %d %v", code, task)
+	}
+}
+
+func TestServer_TaskNamespaceErrors(t *testing.T) {
+	ts, repo := setupRESTServer(t)
+	task := model.NewTask("scoped", "", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+	task.ID = "scoped-1"
+	if err := repo.InNamespace("team-a").Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	do := func(method, path, namespace, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(grpc_middleware.NamespaceHeader, namespace)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do(http.MethodGet, "/api/v1/tasks/scoped-1", "team-a", ""); code != http.StatusOK {
+		t.Fatalf("expected 200 in the task's namespace, got %d", code)
+	}
+	// 其他命名空间的任务与不存在的任务一样返回 404
+	if code := do(http.MethodGet, "/api/v1/tasks/scoped-1", "team-b", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 from another namespace, got %d", code)
+	}
+	if code := do(http.MethodGet, "/api/v1/tasks/missing", "team-a", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing task, got %d", code)
+	}
+	if code := do(http.MethodPut, "/api/v1/tasks/scoped-1", "team-b", `{"status": 2}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 updating from another namespace, got %d", code)
+	}
+}
@@ -3,11 +3,13 @@ package server
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"net"
 	"os"
 	"os/signal"
 	path2 "path"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -15,14 +17,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	"taskflow/internal/config"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
 	"taskflow/internal/handler"
 	"taskflow/internal/logger"
 	"taskflow/internal/middleware"
 	"taskflow/internal/model"
 	"taskflow/internal/repository"
+	"taskflow/internal/service"
 	pb "taskflow/proto"
 )
 
@@ -35,6 +45,21 @@ type Server struct {
 	startMutex sync.Mutex
 	taskHandler *handler.TaskHandler
 	taskRepo    *repository.TaskRepository
+	janitor     *service.Janitor
+	backups     *service.BackupManager
+	outbox      *service.OutboxRelay
+	bulk        *service.BulkManager
+
+	authVerifier  *grpc_middleware.TokenVerifier
+	authHandler   *handler.AuthHandler
+	apiKeyHandler *handler.APIKeyHandler
+
+	namespaceHandler *handler.NamespaceHandler
+
+	audit        *service.AuditService
+	auditHandler *handler.AuditHandler
+
+	certs *certReloader
 }
 
 // NewServer 创建服务实例
@@ -54,15 +79,7 @@ func (s *Server) Start() error {
 	}
 
 	// 获取数据库路径（支持环境变量 TASKFLOW_DB_PATH）
-	dbPath := s.cfg.Server.DBPath
-	// 处理用户主目录
-	if strings.HasPrefix(dbPath, "~") {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			homeDir = "."
-		}
-		dbPath = path2.Join(homeDir, strings.TrimPrefix(dbPath, "~/"))
-	}
+	dbPath := s.cfg.GetDBPath()
 
 	// 确保目录存在
 	dbDir := path2.Dir(dbPath)
@@ -70,7 +87,12 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to create db directory: %w", err)
 	}
 
-	db, err := repository.NewSQLite(dbPath)
+	db, err := repository.NewSQLiteWithOptions(dbPath, repository.SQLiteOptions{
+		JournalMode:  s.cfg.Database.JournalMode,
+		Synchronous:  s.cfg.Database.Synchronous,
+		BusyTimeout:  s.cfg.GetDBBusyTimeout(),
+		MaxReadConns: s.cfg.Database.MaxOpenConns,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to init database: %w", err)
 	}
@@ -80,11 +102,87 @@ func (s *Server) Start() error {
 	if err := db.InitSchema(); err != nil {
 		return fmt.Errorf("failed to init schema: %w", err)
 	}
+	if !db.FTSEnabled() {
+		logger.Warnf("!!! SQLite FTS5 is not available: keyword search falls back to LIKE without relevance ranking. " +
+			"Build with CGO_ENABLED=1 and -tags sqlite_fts5 to enable full-text search !!!")
+	}
 
 	taskRepo := repository.NewTaskRepository(db)
 	s.taskRepo = taskRepo
 	s.taskHandler = handler.NewTaskHandler(taskRepo)
 
+	// 保留策略与归档
+	janitor, err := s.newJanitor(taskRepo)
+	if err != nil {
+		return fmt.Errorf("failed to init retention: %w", err)
+	}
+	s.janitor = janitor
+	s.taskHandler.SetJanitor(janitor)
+	if s.cfg.Retention.Enabled {
+		janitor.Start(context.Background())
+	}
+
+	// 在线备份：手动备份始终可用，定时备份按配置启用
+	s.backups = service.NewBackupManager(db, config.ExpandHome(s.cfg.Backup.Dir), s.cfg.Backup.Keep, s.cfg.GetBackupInterval())
+	s.taskHandler.SetBackupManager(s.backups)
+	if s.cfg.Backup.Enabled {
+		s.backups.Start(context.Background())
+	}
+
+	// 认证：令牌校验与吊销列表
+	if s.cfg.Auth.Enabled {
+		if err := s.initAuth(db); err != nil {
+			return fmt.Errorf("failed to init auth: %w", err)
+		}
+	}
+
+	// 批量取消/重试/调整优先级，在后台执行
+	s.bulk = service.NewBulkManager(taskRepo)
+	s.taskHandler.SetBulkManager(s.bulk)
+
+	// 命名空间：任务按调用者的命名空间隔离，创建任务时检查配额
+	namespaces := service.NewNamespaceService(repository.NewNamespaceRepository(db))
+	s.taskHandler.SetNamespaceService(namespaces)
+	s.namespaceHandler = handler.NewNamespaceHandler(namespaces)
+
+	// 机密参数：配置密钥文件后加密保存；主密钥变更后，启动时将旧密钥加密的数据重新加密
+	if s.cfg.Secrets.KeyFile != "" {
+		secrets, err := service.LoadSecretCipher(config.ExpandHome(s.cfg.Secrets.KeyFile), s.cfg.Secrets.PrimaryKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to load secrets key file: %w", err)
+		}
+		s.taskHandler.SetSecretCipher(secrets)
+		n, err := secrets.Rewrap(context.Background(), taskRepo)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt secret params: %w", err)
+		}
+		if n > 0 {
+			logger.Infof("Re-encrypted secret params of %d tasks with key %s", n, secrets.PrimaryKeyID())
+		}
+	}
+
+	// 审计日志：记录所有修改类调用，只追加
+	s.audit = service.NewAuditService(repository.NewAuditRepository(db))
+	s.auditHandler = handler.NewAuditHandler(s.audit)
+
+	// 变更 outbox 中继：按提交顺序推送给 WatchTask 订阅者，可选推送到 Webhook
+	s.outbox = service.NewOutboxRelay(taskRepo, s.cfg.GetOutboxPollInterval(), s.cfg.GetOutboxRetention())
+	s.outbox.AddSink(s.taskHandler)
+	if s.cfg.Outbox.WebhookURL != "" {
+		s.outbox.AddSink(service.NewWebhookSink(s.cfg.Outbox.WebhookURL, time.Duration(s.cfg.Outbox.WebhookTimeout)*time.Second))
+	}
+	s.outbox.Start(context.Background())
+
+	// TLS：gRPC 与 HTTP 共用证书，按间隔检查证书文件并重新加载
+	if s.cfg.TLSEnabled() {
+		certs, err := newCertReloader(s.cfg.TLS, s.cfg.GetTLSReloadInterval())
+		if err != nil {
+			return fmt.Errorf("failed to init TLS: %w", err)
+		}
+		s.certs = certs
+		s.certs.Start(context.Background())
+	}
+
 	// 启动 gRPC 服务器
 	if err := s.startGRPC(); err != nil {
 		return fmt.Errorf("failed to start gRPC: %w", err)
@@ -103,6 +201,33 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// newJanitor 根据配置创建清理器
+func (s *Server) newJanitor(repo *repository.TaskRepository) (*service.Janitor, error) {
+	cfgRules, err := s.cfg.GetRetentionRules()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]service.RetentionRule, 0, len(cfgRules))
+	for _, r := range cfgRules {
+		rules = append(rules, service.RetentionRule{
+			TaskType: r.TaskType,
+			Status:   model.TaskStatus(pb.TaskStatus_value["TASK_STATUS_"+r.Status]),
+			MaxAge:   r.MaxAge,
+		})
+	}
+
+	var archiver service.TaskArchiver
+	switch s.cfg.Retention.ArchiveMode {
+	case "file":
+		archiver = service.NewFileArchiver(repo, config.ExpandHome(s.cfg.Retention.ArchiveDir))
+	case "table":
+		archiver = service.NewTableArchiver(repo)
+	}
+
+	return service.NewJanitor(repo, archiver, rules, s.cfg.GetRetentionInterval(), s.cfg.Retention.BatchSize), nil
+}
+
 // startGRPC 启动gRPC服务
 func (s *Server) startGRPC() error {
 	lis, err := net.Listen("tcp", s.cfg.GetGRPCAddr())
@@ -110,11 +235,10 @@ func (s *Server) startGRPC() error {
 		return fmt.Errorf("failed to listen on gRPC: %w", err)
 	}
 
-	// 创建 gRPC 服务器
-	s.grpcServer = grpc.NewServer()
-	
-	// 注册 TaskService
-	pb.RegisterTaskServiceServer(s.grpcServer, s.taskHandler)
+	s.grpcServer, err = s.newGRPCServer()
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC server: %w", err)
+	}
 
 	go func() {
 		logger.Infof("gRPC server listening on %s", s.cfg.GetGRPCAddr())
@@ -126,6 +250,76 @@ func (s *Server) startGRPC() error {
 	return nil
 }
 
+// newGRPCServer 创建 gRPC 服务器并注册服务
+func (s *Server) newGRPCServer() (*grpc.Server, error) {
+	opts, err := grpc_middleware.GetUnaryServerOptions(s.grpcInterceptorOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	if s.certs != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.certs.TLSConfig())))
+	}
+	grpcServer := grpc.NewServer(opts...)
+
+	// 注册 TaskService
+	pb.RegisterTaskServiceServer(grpcServer, s.taskHandler)
+	if s.authHandler != nil {
+		pb.RegisterAuthServiceServer(grpcServer, s.authHandler)
+	}
+	if s.apiKeyHandler != nil {
+		pb.RegisterAPIKeyServiceServer(grpcServer, s.apiKeyHandler)
+	}
+	if s.namespaceHandler != nil {
+		pb.RegisterNamespaceServiceServer(grpcServer, s.namespaceHandler)
+	}
+	if s.auditHandler != nil {
+		pb.RegisterAuditServiceServer(grpcServer, s.auditHandler)
+	}
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	return grpcServer, nil
+}
+
+// grpcInterceptorOptions 按配置启用拦截器，链的顺序由 grpc_middleware.GetUnaryServerOptions 决定：
+// panic 恢复、日志、认证（Bearer 令牌、x-api-key 或客户端证书）、按调用者限流、审计、按角色授权、确定命名空间
+func (s *Server) grpcInterceptorOptions() []grpc_middleware.ServerOption {
+	opts := []grpc_middleware.ServerOption{grpc_middleware.WithLogOutput(&grpc_middleware.LoggerConfig{
+		InfoLogger:  zap.NewStdLog(logger.Logger.Desugar()),
+		ErrorLogger: errorStdLog(),
+	})}
+	if s.cfg.GRPC.Logging {
+		opts = append(opts, grpc_middleware.WithLogger(nil))
+	}
+	if s.cfg.GRPC.Recovery {
+		opts = append(opts, grpc_middleware.WithRecovery())
+	}
+	if s.authVerifier != nil {
+		opts = append(opts, grpc_middleware.WithAuthVerifier(s.authVerifier))
+	}
+	if s.cfg.GRPC.RateLimit > 0 {
+		opts = append(opts, grpc_middleware.WithRateLimit(grpc_middleware.NewTokenBucketLimiter(&grpc_middleware.RateLimiterConfig{
+			RequestsPerSecond: s.cfg.GRPC.RateLimit,
+			BurstSize:         s.cfg.GetGRPCRateBurst(),
+		})))
+	}
+	if s.audit != nil {
+		opts = append(opts, grpc_middleware.WithAudit(s.audit))
+	}
+	if s.authVerifier != nil {
+		opts = append(opts, grpc_middleware.WithAuthorization(s.authDefaultRole()))
+	}
+	return append(opts, grpc_middleware.WithNamespaceResolution())
+}
+
+// errorStdLog 以 error 级别写入全局日志的标准库 logger
+func errorStdLog() *log.Logger {
+	l, err := zap.NewStdLogAt(logger.Logger.Desugar(), zap.ErrorLevel)
+	if err != nil {
+		return zap.NewStdLog(logger.Logger.Desugar())
+	}
+	return l
+}
+
 // startHTTP 启动HTTP服务
 func (s *Server) startHTTP() error {
 	if s.cfg.Server.EnableDebug {
@@ -141,7 +335,7 @@ func (s *Server) startHTTP() error {
 		middleware.Logger(),
 		middleware.RequestID(),
 		middleware.CORS(),
-		middleware.Timeout(s.cfg.GetTimeout()),
+		middleware.Timeout(s.cfg.GetTimeout(), watchSSEPath, watchWSPath, waitTaskPath),
 	)
 
 	// 健康检查
@@ -152,6 +346,18 @@ func (s *Server) startHTTP() error {
 	// Prometheus 指标端点
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// 认证、审计与授权：之后注册的 API 路由要求 Bearer 令牌或 X-API-Key，健康检查与指标端点不受影响
+	if s.authVerifier != nil {
+		router.Use(middleware.Auth(s.authVerifier))
+	}
+	if s.audit != nil {
+		router.Use(middleware.Audit(s.audit, restMethods))
+	}
+	if s.authVerifier != nil {
+		router.Use(middleware.Authorize(s.authDefaultRole(), restMethods))
+	}
+	router.Use(middleware.Namespace())
+
 	// 注册 API 路由
 	if s.taskHandler != nil {
 		s.registerRoutes(router)
@@ -165,9 +371,15 @@ func (s *Server) startHTTP() error {
 		MaxHeaderBytes: 1 << 20,
 	}
 
+	serve := s.httpServer.ListenAndServe
+	if s.certs != nil {
+		s.httpServer.TLSConfig = s.certs.TLSConfig()
+		serve = func() error { return s.httpServer.ListenAndServeTLS("", "") }
+	}
+
 	go func() {
 		logger.Infof("HTTP server listening on %s", s.cfg.GetHTTPAddr())
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			logger.Errorf("HTTP server error: %v", err)
 		}
 	}()
@@ -184,9 +396,42 @@ func (s *Server) registerRoutes(router *gin.Engine) {
 	// 单个任务操作
 	router.GET("/api/v1/tasks/:id", s.handleGetTask)
 	router.PUT("/api/v1/tasks/:id", s.handleUpdateTask)
+	router.DELETE("/api/v1/tasks/:id", s.handleDeleteTask)
+	router.POST("/api/v1/tasks/:id/cancel", s.handleCancelTask)
+	router.POST("/api/v1/tasks/:id/retry", s.handleRetryTask)
+	router.GET("/api/v1/tasks/:id/events", s.handleListTaskEvents)
+	router.GET(waitTaskPath, s.handleWaitTask)
 	
 	// 任务统计
 	router.GET("/api/v1/tasks/stats", s.handleTaskStats)
+
+	// 批量操作
+	router.POST("/api/v1/tasks/bulk/cancel", s.handleBulkCancel)
+	router.POST("/api/v1/tasks/bulk/retry", s.handleBulkRetry)
+	router.POST("/api/v1/tasks/bulk/priority", s.handleBulkSetPriority)
+	router.GET("/api/v1/tasks/bulk/:id", s.handleGetBulkOperation)
+
+	// 任务变更订阅（SSE / WebSocket）
+	router.GET(watchSSEPath, s.handleWatchSSE)
+	router.GET(watchWSPath, s.handleWatchWS)
+
+	// 管理接口
+	router.POST("/api/v1/admin/backup", s.handleBackup)
+	if s.apiKeyHandler != nil {
+		router.POST("/api/v1/admin/api-keys", s.handleCreateAPIKey)
+		router.GET("/api/v1/admin/api-keys", s.handleListAPIKeys)
+		router.GET("/api/v1/admin/api-keys/:id", s.handleGetAPIKey)
+		router.PATCH("/api/v1/admin/api-keys/:id", s.handleUpdateAPIKey)
+		router.DELETE("/api/v1/admin/api-keys/:id", s.handleDeleteAPIKey)
+	}
+	router.POST("/api/v1/admin/namespaces", s.handleCreateNamespace)
+	router.GET("/api/v1/admin/namespaces", s.handleListNamespaces)
+	router.GET("/api/v1/admin/namespaces/:name", s.handleGetNamespace)
+	router.PATCH("/api/v1/admin/namespaces/:name", s.handleUpdateNamespace)
+	router.DELETE("/api/v1/admin/namespaces/:name", s.handleDeleteNamespace)
+	if s.auditHandler != nil {
+		router.GET("/api/v1/admin/audit-events", s.handleListAuditEvents)
+	}
 }
 
 // handleCreateTask 创建任务
@@ -200,6 +445,8 @@ func (s *Server) handleCreateTask(c *gin.Context) {
 		Dependencies []string          `json:"dependencies"`
 		MaxRetries   int32             `json:"max_retries"`
 		CreatedBy    string            `json:"created_by"`
+		Labels       map[string]string `json:"labels"`
+		SecretParams map[string]string `json:"secret_params"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -216,11 +463,13 @@ func (s *Server) handleCreateTask(c *gin.Context) {
 		Dependencies: req.Dependencies,
 		MaxRetries:   req.MaxRetries,
 		CreatedBy:    req.CreatedBy,
+		Labels:       req.Labels,
+		SecretParams: req.SecretParams,
 	}
 
 	task, err := s.taskHandler.CreateTask(c.Request.Context(), pbReq)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		respondGRPCError(c, err)
 		return
 	}
 
@@ -228,33 +477,83 @@ func (s *Server) handleCreateTask(c *gin.Context) {
 }
 
 // handleListTasks 列出任务
+// 支持的查询参数：
+//   status=FAILED,TIMEOUT（名称或数值，逗号分隔）、type=etl,report、created_by、priority、keyword
+//   created_after/created_before/updated_after/updated_before/completed_after/completed_before（RFC3339 或 Unix 秒）
+//   has_error=true|false、label=key:value（可重复）、sort_by、sort_desc=true
+//   filter=过滤表达式，例如 status in (FAILED, TIMEOUT) and created_at > now-24h
 func (s *Server) handleListTasks(c *gin.Context) {
+	// 默认 page=1 的页码分页，与旧客户端兼容；page=0（首页）或传 page_token 时使用游标分页
 	page := int32(parseInt(c.Query("page"), 1))
+	if c.Query("page") == "0" || c.Query("page_token") != "" {
+		page = 0
+	}
 	pageSize := int32(parseInt(c.Query("page_size"), 20))
-	keyword := c.Query("keyword")
-	taskType := c.Query("type")
-	statusVal := c.Query("status")
 	priorityStr := c.Query("priority")
 
 	req := &pb.ListTasksRequest{
-		Page:     page,
-		PageSize: pageSize,
-		Keyword:  keyword,
-		TaskType: taskType,
+		Page:      page,
+		PageSize:  pageSize,
+		PageToken: c.Query("page_token"),
+		Keyword:   c.Query("keyword"),
+		TaskTypes: splitList(c.Query("type")),
+		CreatedBy: c.Query("created_by"),
+		SortBy:    c.Query("sort_by"),
+		SortDesc:  c.Query("sort_desc") == "true",
+		Filter:    c.Query("filter"),
 	}
 
-	if statusVal != "" {
-		if v := parseInt(statusVal, -1); v > 0 {
-			req.StatusFilter = []pb.TaskStatus{pb.TaskStatus(v)}
-		}
+	statuses, err := parseStatusList(c.Query("status"))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return
 	}
+	req.StatusFilter = statuses
+
 	if priorityStr != "" {
 		req.Priority = pb.TaskPriority(parseInt(priorityStr, 0))
 	}
 
+	for param, dst := range map[string]*int64{
+		"created_after":    &req.CreatedAfter,
+		"created_before":   &req.CreatedBefore,
+		"updated_after":    &req.UpdatedAfter,
+		"updated_before":   &req.UpdatedBefore,
+		"completed_after":  &req.CompletedAfter,
+		"completed_before": &req.CompletedBefore,
+	} {
+		v, err := parseTimeParam(c.Query(param))
+		if err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": fmt.Sprintf("invalid request: %s: %v", param, err)})
+			return
+		}
+		*dst = v
+	}
+
+	if v := c.Query("has_error"); v != "" {
+		hasError, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": "invalid request: has_error: " + err.Error()})
+			return
+		}
+		req.HasError = &hasError
+	}
+
+	for _, label := range c.QueryArray("label") {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || key == "" {
+			c.JSON(400, gin.H{"code": 1001, "message": "invalid request: label must be key:value"})
+			return
+		}
+		if req.Labels == nil {
+			req.Labels = make(map[string]string)
+		}
+		req.Labels[key] = value
+	}
+
 	resp, err := s.taskHandler.ListTasks(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		respondGRPCError(c, err)
 		return
 	}
 
@@ -273,13 +572,30 @@ func (s *Server) handleGetTask(c *gin.Context) {
 
 	task, err := s.taskHandler.GetTask(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		respondGRPCError(c, err)
 		return
 	}
 
 	c.JSON(200, task)
 }
 
+// handleListTaskEvents 分页获取任务事件
+func (s *Server) handleListTaskEvents(c *gin.Context) {
+	req := &pb.ListTaskEventsRequest{
+		TaskId:    c.Param("id"),
+		PageSize:  int32(parseInt(c.Query("page_size"), 50)),
+		PageToken: c.Query("page_token"),
+	}
+
+	resp, err := s.taskHandler.ListTaskEvents(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
 // handleUpdateTask 更新任务
 func (s *Server) handleUpdateTask(c *gin.Context) {
 	id := c.Param("id")
@@ -306,13 +622,83 @@ func (s *Server) handleUpdateTask(c *gin.Context) {
 
 	task, err := s.taskHandler.UpdateTask(c.Request.Context(), pbReq)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, task)
+}
+
+// handleDeleteTask 删除任务，非终态任务需 force=true
+func (s *Server) handleDeleteTask(c *gin.Context) {
+	req := &pb.DeleteTaskRequest{
+		Id:    c.Param("id"),
+		Force: c.Query("force") == "true",
+	}
+
+	resp, err := s.taskHandler.DeleteTask(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+// lifecycleRequest 取消/重试请求体（可选）
+type lifecycleRequest struct {
+	Operator string `json:"operator"`
+}
+
+// bindLifecycleRequest 解析可选的请求体，空请求体视为未指定操作人
+func bindLifecycleRequest(c *gin.Context) (*lifecycleRequest, bool) {
+	var req lifecycleRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+// handleCancelTask 取消任务
+func (s *Server) handleCancelTask(c *gin.Context) {
+	body, ok := bindLifecycleRequest(c)
+	if !ok {
+		return
+	}
+
+	task, err := s.taskHandler.CancelTask(c.Request.Context(), &pb.CancelTaskRequest{Id: c.Param("id"), Operator: body.Operator})
+	if err != nil {
+		respondGRPCError(c, err)
 		return
 	}
 
 	c.JSON(200, task)
 }
 
+// handleRetryTask 重试失败的任务
+func (s *Server) handleRetryTask(c *gin.Context) {
+	body, ok := bindLifecycleRequest(c)
+	if !ok {
+		return
+	}
+
+	task, err := s.taskHandler.RetryTask(c.Request.Context(), &pb.RetryTaskRequest{Id: c.Param("id"), Operator: body.Operator})
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, task)
+}
+
+// respondGRPCError 按 gRPC 状态码返回对应的 HTTP 错误（如 NotFound 返回 404）
+func respondGRPCError(c *gin.Context, err error) {
+	errorcode.HandleGinError(c, errorcode.FromGRPCStatus(status.Convert(err)))
+}
+
 // handleTaskStats 任务统计
 func (s *Server) handleTaskStats(c *gin.Context) {
 	// 获取各状态的任务数量
@@ -322,11 +708,13 @@ func (s *Server) handleTaskStats(c *gin.Context) {
 	failed := model.TaskStatusFailed
 	cancelled := model.TaskStatusCancelled
 
-	pendingCount, _ := s.taskRepo.Count(&pending)
-	runningCount, _ := s.taskRepo.Count(&running)
-	succeededCount, _ := s.taskRepo.Count(&succeeded)
-	failedCount, _ := s.taskRepo.Count(&failed)
-	cancelledCount, _ := s.taskRepo.Count(&cancelled)
+	// 只统计调用者命名空间内的任务
+	repo := s.taskRepo.InNamespace(grpc_middleware.GetNamespace(c.Request.Context()))
+	pendingCount, _ := repo.Count(&pending)
+	runningCount, _ := repo.Count(&running)
+	succeededCount, _ := repo.Count(&succeeded)
+	failedCount, _ := repo.Count(&failed)
+	cancelledCount, _ := repo.Count(&cancelled)
 
 	total := pendingCount + runningCount + succeededCount + failedCount + cancelledCount
 
@@ -340,6 +728,17 @@ func (s *Server) handleTaskStats(c *gin.Context) {
 	})
 }
 
+// handleBackup 在线备份数据库
+func (s *Server) handleBackup(c *gin.Context) {
+	resp, err := s.taskHandler.BackupDatabase(c.Request.Context(), &pb.BackupDatabaseRequest{})
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
 // waitForShutdown 等待退出信号并优雅关闭
 func (s *Server) waitForShutdown() {
 	stopCh := make(chan os.Signal, 1)
@@ -371,6 +770,31 @@ func (s *Server) waitForShutdown() {
 		}
 	}
 
+	// 停止后台清理
+	if s.janitor != nil {
+		s.janitor.Stop()
+	}
+
+	// 停止定时备份
+	if s.backups != nil {
+		s.backups.Stop()
+	}
+
+	// 中断进行中的批量操作，已处理的任务不回滚
+	if s.bulk != nil {
+		s.bulk.Stop()
+	}
+
+	// 停止 outbox 中继，未发布的记录下次启动后继续投递
+	if s.outbox != nil {
+		s.outbox.Stop()
+	}
+
+	// 停止检查证书文件
+	if s.certs != nil {
+		s.certs.Stop()
+	}
+
 	// 同步日志
 	logger.Sync()
 	logger.Info("Server stopped")
@@ -393,3 +817,52 @@ func parseInt(s string, defaultVal int) int {
 	}
 	return n
 }
+
+// splitList 解析逗号分隔的查询参数
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseStatusList 解析状态列表，支持数值（4）、名称（FAILED）和完整枚举名（TASK_STATUS_FAILED）
+func parseStatusList(s string) ([]pb.TaskStatus, error) {
+	var statuses []pb.TaskStatus
+	for _, item := range splitList(s) {
+		if n, err := strconv.Atoi(item); err == nil {
+			if n > 0 {
+				statuses = append(statuses, pb.TaskStatus(n))
+			}
+			continue
+		}
+		name := strings.ToUpper(item)
+		if !strings.HasPrefix(name, "TASK_STATUS_") {
+			name = "TASK_STATUS_" + name
+		}
+		v, ok := pb.TaskStatus_value[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown status %q", item)
+		}
+		statuses = append(statuses, pb.TaskStatus(v))
+	}
+	return statuses, nil
+}
+
+// parseTimeParam 解析时间参数（RFC3339 或 Unix 秒），返回 Unix 秒，空值返回 0
+func parseTimeParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/logger"
+)
+
+// certReloader 提供磁盘上当前的服务端证书与客户端 CA，文件修改时间变化后重新加载
+// gRPC 与 HTTP 监听共用同一个实例，新连接使用新证书，已建立的连接不受影响
+type certReloader struct {
+	cfg      config.TLSConfig
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newCertReloader 创建并加载证书，加载失败返回错误
+func newCertReloader(cfg config.TLSConfig, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{cfg: cfg, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files 需要监视的文件
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// reload 重新读取证书、私钥与客户端 CA，失败时保留当前证书
+func (r *certReloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// changed 判断是否有文件的修改时间变化
+func (r *certReloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// 证书轮换过程中文件可能暂时不存在，下次再检查
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAuth 双向 TLS 模式
+func (r *certReloader) clientAuth() tls.ClientAuthType {
+	if r.cfg.ClientCAFile == "" {
+		return tls.NoClientCert
+	}
+	if r.cfg.ClientAuth == "optional" {
+		return tls.VerifyClientCertIfGiven
+	}
+	return tls.RequireAndVerifyClientCert
+}
+
+// TLSConfig 生成服务端 TLS 配置，每次握手读取当前证书与客户端 CA
+func (r *certReloader) TLSConfig() *tls.Config {
+	clientAuth := r.clientAuth()
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientCAs:    r.clientCAs,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// Start 按间隔检查证书文件，interval 为 0 时不启动
+func (r *certReloader) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !r.changed() {
+					continue
+				}
+				if err := r.reload(); err != nil {
+					logger.Errorf("TLS certificate reload failed, keeping the current certificate: %v", err)
+					continue
+				}
+				logger.Infof("TLS certificate reloaded from %s", r.cfg.CertFile)
+			}
+		}
+	}()
+}
+
+// Stop 停止检查
+func (r *certReloader) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"taskflow/internal/config"
+	"taskflow/internal/handler"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// testCA 测试用 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "taskflow test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发证书，返回 PEM 格式的证书与私钥
+func (ca *testCA) issue(t *testing.T, serial int64, subject pkix.Name, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to issue certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	tlsCfg := config.TLSConfig{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		ClientAuth:   "require",
+	}
+	serverCert, serverKey := ca.issue(t, 10, pkix.Name{CommonName: "taskflow"}, x509.ExtKeyUsageServerAuth)
+	writeFile(t, tlsCfg.CertFile, serverCert)
+	writeFile(t, tlsCfg.KeyFile, serverKey)
+	writeFile(t, tlsCfg.ClientCAFile, ca.pem)
+
+	db, err := repository.NewSQLite(filepath.Join(dir, "tls.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	s := &Server{
+		cfg: &config.Config{
+			Auth: config.AuthConfig{Enabled: true, Secret: testAuthSecret, TokenExpireHours: 1, RefreshExpireHours: 1},
+			TLS:  tlsCfg,
+		},
+		taskHandler: handler.NewTaskHandler(repository.NewTaskRepository(db)),
+	}
+	if err := s.initAuth(db); err != nil {
+		t.Fatalf("initAuth failed: %v", err)
+	}
+	s.certs, err = newCertReloader(tlsCfg, 0)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer, err := s.newGRPCServer()
+	if err != nil {
+		t.Fatalf("failed to create gRPC server: %v", err)
+	}
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	dial := func(clientCert ...tls.Certificate) pb.TaskServiceClient {
+		t.Helper()
+		creds := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: clientCert})
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewTaskServiceClient(conn)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := dial().CreateTask(ctx, &pb.CreateTaskRequest{Name: "anonymous"}); err == nil {
+		t.Fatal("expected the handshake to fail without a client certificate")
+	}
+
+	certPEM, keyPEM := ca.issue(t, 20, pkix.Name{CommonName: "ci-bot", OrganizationalUnit: []string{"submitter"}}, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load client certificate: %v", err)
+	}
+	task, err := dial(clientCert).CreateTask(ctx, &pb.CreateTaskRequest{Name: "from-ci"})
+	if err != nil {
+		t.Fatalf("CreateTask with client certificate failed: %v", err)
+	}
+	if task.CreatedBy != "ci-bot" {
+		t.Errorf("expected created_by ci-bot from the certificate, got %q", task.CreatedBy)
+	}
+
+	// 轮换服务端证书后，新连接使用新证书
+	rotatedCert, rotatedKey := ca.issue(t, 11, pkix.Name{CommonName: "taskflow"}, x509.ExtKeyUsageServerAuth)
+	writeFile(t, tlsCfg.CertFile, rotatedCert)
+	writeFile(t, tlsCfg.KeyFile, rotatedKey)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(tlsCfg.CertFile, future, future)
+	if !s.certs.changed() {
+		t.Fatal("expected the rotated certificate to be detected")
+	}
+	if err := s.certs.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{
+		RootCAs: roots, Certificates: []tls.Certificate{clientCert}, NextProtos: []string{"h2"},
+	})
+	if err != nil {
+		t.Fatalf("TLS dial failed: %v", err)
+	}
+	defer conn.Close()
+	if serial := conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(); serial != 11 {
+		t.Errorf("expected the rotated certificate (serial 11), got serial %d", serial)
+	}
+
+	// 无效证书不会替换当前证书
+	writeFile(t, tlsCfg.CertFile, []byte("not a certificate"))
+	if err := s.certs.reload(); err == nil {
+		t.Error("expected reload of an invalid certificate to fail")
+	}
+	if _, err := dial(clientCert).GetTask(ctx, &pb.GetTaskRequest{Id: task.Id}); err != nil {
+		t.Errorf("expected the previous certificate to keep serving, got %v", err)
+	}
+}
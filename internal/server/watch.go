@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/handler"
+	"taskflow/internal/logger"
+	pb "taskflow/proto"
+)
+
+const (
+	// watchSSEPath SSE 订阅路由
+	watchSSEPath = "/api/v1/tasks/watch"
+	// watchWSPath WebSocket 订阅路由
+	watchWSPath = "/api/v1/ws"
+	// waitTaskPath 长轮询等待任务完成路由
+	waitTaskPath = "/api/v1/tasks/:id/wait"
+
+	// watchHeartbeatInterval SSE 注释心跳与 WebSocket ping 的间隔，防止代理断开空闲连接
+	watchHeartbeatInterval = 15 * time.Second
+	// wsWriteTimeout WebSocket 单次写超时
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsUpgrader WebSocket 升级器，跨域策略与 CORS 中间件一致
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// watchRequestFromQuery 从查询参数构建订阅请求，参数与 WatchTaskRequest 字段对应
+// task_id、type、created_by 支持逗号分隔或重复传参，label 格式为 key:value
+func watchRequestFromQuery(c *gin.Context) (*pb.WatchTaskRequest, error) {
+	req := &pb.WatchTaskRequest{
+		TaskIds:   queryList(c, "task_id"),
+		TaskTypes: queryList(c, "type"),
+		CreatedBy: queryList(c, "created_by"),
+		Workflow:  c.Query("workflow"),
+		Filter:    c.Query("filter"),
+	}
+
+	statuses, err := parseStatusList(c.Query("status"))
+	if err != nil {
+		return nil, err
+	}
+	req.StatusFilter = statuses
+
+	if v := c.Query("include_initial"); v != "" {
+		if req.IncludeInitial, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("include_initial: %w", err)
+		}
+	}
+
+	// 浏览器 EventSource 断线重连时通过 Last-Event-ID 携带最后收到的序号
+	resume := c.Query("resume_from_sequence")
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		resume = id
+	}
+	if resume != "" {
+		if req.ResumeFromSequence, err = strconv.ParseInt(resume, 10, 64); err != nil {
+			return nil, fmt.Errorf("resume_from_sequence: %w", err)
+		}
+	}
+
+	for _, label := range c.QueryArray("label") {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("label must be key:value")
+		}
+		if req.Labels == nil {
+			req.Labels = make(map[string]string)
+		}
+		req.Labels[key] = value
+	}
+
+	if err := handler.ValidateWatchRequest(req); err != nil {
+		return nil, fmt.Errorf("%s", status.Convert(err).Message())
+	}
+	return req, nil
+}
+
+// queryList 合并重复参数与逗号分隔的值
+func queryList(c *gin.Context, key string) []string {
+	var items []string
+	for _, v := range c.QueryArray(key) {
+		items = append(items, splitList(v)...)
+	}
+	return items
+}
+
+// handleWatchSSE 以 Server-Sent Events 推送任务变更
+// 每条变更的 id 为 sequence，event 为 change_type，data 为 TaskChangeEvent JSON
+func (s *Server) handleWatchSSE(c *gin.Context) {
+	req, err := watchRequestFromQuery(c)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return
+	}
+
+	// 长连接不受 HTTP 写超时限制
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warnf("Failed to clear write deadline for SSE: %v", err)
+	}
+
+	// 先发送响应头，客户端无需等到第一条变更才确认连接成功
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// 推送与心跳在不同 goroutine 中写响应，需要串行化
+	var mu sync.Mutex
+	send := func(event *pb.TaskChangeEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		if event.Sequence > 0 {
+			fmt.Fprintf(&b, "id: %d\n", event.Sequence)
+		}
+		fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event.ChangeType, data)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := c.Writer.WriteString(b.String()); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(watchHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				_, err := c.Writer.WriteString(": ping\n\n")
+				if err == nil {
+					c.Writer.Flush()
+				}
+				mu.Unlock()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = s.taskHandler.Watch(ctx, req, send)
+	if err != nil && ctx.Err() == nil {
+		// 响应头已发送，推送过程中的错误（如回放读库失败）以 error 事件告知客户端
+		taskErr := errorcode.FromGRPCStatus(status.Convert(err))
+		data, _ := json.Marshal(taskErr.ToGinResponse())
+		mu.Lock()
+		fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", data)
+		c.Writer.Flush()
+		mu.Unlock()
+	}
+}
+
+// handleWatchWS 以 WebSocket 推送任务变更，每条消息为一个 TaskChangeEvent JSON
+// 订阅条件通过查询参数指定；overflow/resync 控制事件发送后服务端关闭连接
+func (s *Server) handleWatchWS(c *gin.Context) {
+	req, err := watchRequestFromQuery(c)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 已向客户端返回错误
+		logger.Warnf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// 读循环：处理 pong 与关闭帧，连接断开时结束订阅
+	conn.SetReadDeadline(time.Now().Add(2 * watchHeartbeatInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * watchHeartbeatInterval))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(watchHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	send := func(event *pb.TaskChangeEvent) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(event)
+	}
+
+	err = s.taskHandler.Watch(ctx, req, send)
+	if ctx.Err() != nil {
+		return
+	}
+
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil {
+		code, reason = websocket.CloseInternalServerErr, status.Convert(err).Message()
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+}
+
+// handleWaitTask 长轮询等待任务进入终态或指定状态
+// timeout 支持 Go 时长（30s、2m）或秒数，status 为逗号分隔的目标状态
+func (s *Server) handleWaitTask(c *gin.Context) {
+	req := &pb.WaitTaskRequest{Id: c.Param("id")}
+
+	if v := c.Query("timeout"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			secs, convErr := strconv.Atoi(v)
+			if convErr != nil {
+				c.JSON(400, gin.H{"code": 1001, "message": "invalid request: timeout: " + err.Error()})
+				return
+			}
+			timeout = time.Duration(secs) * time.Second
+		}
+		req.TimeoutMs = timeout.Milliseconds()
+	}
+
+	statuses, err := parseStatusList(c.Query("status"))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+		return
+	}
+	req.TargetStatuses = statuses
+
+	// 等待时间可能超过 HTTP 写超时
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warnf("Failed to clear write deadline for wait: %v", err)
+	}
+
+	resp, err := s.taskHandler.WaitTask(c.Request.Context(), req)
+	if err != nil {
+		respondGRPCError(c, err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
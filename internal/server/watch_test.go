@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"taskflow/internal/handler"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// setupWatchServer 创建只注册 API 路由的测试服务，并写入两个任务（outbox 序号 1、2）
+func setupWatchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "watch.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	repo := repository.NewTaskRepository(db)
+	for i, taskType := range []string{"report", "etl"} {
+		task := model.NewTask(fmt.Sprintf("watch-%d", i), "", model.TaskPriorityNormal, taskType, nil, nil, 0, "test")
+		task.ID = fmt.Sprintf("watch-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	s := &Server{taskHandler: handler.NewTaskHandler(repo)}
+	router := gin.New()
+	s.registerRoutes(router)
+
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestServer_WatchSSE(t *testing.T) {
+	ts := setupWatchServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/v1/tasks/watch?filter=bad%20%3D")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid filter, got %d", resp.StatusCode)
+	}
+
+	// Last-Event-ID 优先于查询参数；按类型过滤后只应回放 etl 任务（序号 2）
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/tasks/watch?type=etl&resume_from_sequence=99", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	lines := make(chan string, 64)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed early, got %v", got)
+			}
+			if line != "" {
+				got = append(got, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+
+	if got[0] != "id: 2" || got[1] != "event: "+model.ChangeTypeCreated {
+		t.Fatalf("unexpected event header %v", got[:2])
+	}
+	var event pb.TaskChangeEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[2], "data: ")), &event); err != nil {
+		t.Fatalf("invalid event data %q: %v", got[2], err)
+	}
+	if event.TaskId != "watch-1" || event.Sequence != 2 {
+		t.Errorf("expected watch-1 at sequence 2, got %s at %d", event.TaskId, event.Sequence)
+	}
+}
+
+func TestServer_WatchWebSocket(t *testing.T) {
+	ts := setupWatchServer(t)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/ws?type=etl&resume_from_sequence=1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event pb.TaskChangeEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if event.TaskId != "watch-1" || event.Sequence != 2 || event.ChangeType != model.ChangeTypeCreated {
+		t.Errorf("unexpected event %s/%s at %d", event.TaskId, event.ChangeType, event.Sequence)
+	}
+
+	// 续传序号超出范围：收到 resync 后服务端正常关闭
+	url = "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/ws?resume_from_sequence=99"
+	conn2, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn2.Close()
+
+	conn2.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn2.ReadJSON(&event); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if event.ChangeType != "resync" || event.Sequence != 2 {
+		t.Errorf("expected resync at sequence 2, got %s at %d", event.ChangeType, event.Sequence)
+	}
+	if _, _, err := conn2.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("expected normal closure, got %v", err)
+	}
+}
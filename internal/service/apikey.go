@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+)
+
+const (
+	// apiKeyPrefix API Key 统一前缀，便于在日志与代码扫描中识别
+	apiKeyPrefix = "tfk_"
+	// apiKeyDisplayLen 保存明文前几位用于识别
+	apiKeyDisplayLen = 12
+	// apiKeyTouchInterval 最近使用时间的最小写入间隔，避免每个请求都写库
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound API Key 不存在
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey API Key 无效或已过期
+	ErrInvalidAPIKey = errors.New("invalid or expired api key")
+	// ErrAPIKeyNameRequired 名称不能为空
+	ErrAPIKeyNameRequired = errors.New("api key name is required")
+	// ErrAPIKeyExpiryInPast 过期时间早于当前时间
+	ErrAPIKeyExpiryInPast = errors.New("api key expiry must be in the future")
+)
+
+// APIKeyUpdate API Key 可修改的字段，nil 表示不修改
+type APIKeyUpdate struct {
+	Name        *string
+	Scopes      []string
+	ScopesSet   bool
+	ExpiresAt   *time.Time
+	ClearExpiry bool
+}
+
+// APIKeyService 管理 API Key 并实现 grpc_middleware.APIKeyAuthenticator
+type APIKeyService struct {
+	repo *repository.AuthRepository
+
+	mu      sync.Mutex
+	touched map[string]time.Time // id -> 最近一次写入 last_used_at 的时间
+}
+
+// NewAPIKeyService 创建 API Key 服务
+func NewAPIKeyService(repo *repository.AuthRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, touched: make(map[string]time.Time)}
+}
+
+// Create 生成新的 API Key，明文只在此时返回一次；使用该 Key 的请求只作用于 namespace
+func (s *APIKeyService) Create(ctx context.Context, name, owner, namespace string, scopes []string, expiresAt *time.Time) (*repository.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", ErrAPIKeyNameRequired
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrAPIKeyExpiryInPast
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &repository.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Owner:     owner,
+		KeyHash:   hashAPIKey(plaintext),
+		Prefix:    plaintext[:apiKeyDisplayLen],
+		Scopes:    scopes,
+		Namespace: namespace,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+
+	logger.Infof("API key %s (%s) created for %s", key.ID, key.Prefix, owner)
+	return key, plaintext, nil
+}
+
+// Get 获取 API Key
+func (s *APIKeyService) Get(ctx context.Context, id string) (*repository.APIKey, error) {
+	key, err := s.repo.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// List 列出 API Key，owner 为空时列出全部
+func (s *APIKeyService) List(ctx context.Context, owner string) ([]*repository.APIKey, error) {
+	return s.repo.ListAPIKeys(owner)
+}
+
+// Update 修改名称、权限范围或过期时间
+func (s *APIKeyService) Update(ctx context.Context, id string, update APIKeyUpdate) (*repository.APIKey, error) {
+	key, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		if strings.TrimSpace(*update.Name) == "" {
+			return nil, ErrAPIKeyNameRequired
+		}
+		key.Name = *update.Name
+	}
+	if update.ScopesSet {
+		key.Scopes = update.Scopes
+	}
+	switch {
+	case update.ClearExpiry:
+		key.ExpiresAt = nil
+	case update.ExpiresAt != nil:
+		if !update.ExpiresAt.After(time.Now()) {
+			return nil, ErrAPIKeyExpiryInPast
+		}
+		key.ExpiresAt = update.ExpiresAt
+	}
+
+	if err := s.repo.UpdateAPIKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Delete 删除 API Key，立即失效
+func (s *APIKeyService) Delete(ctx context.Context, id string) error {
+	found, err := s.repo.DeleteAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAPIKeyNotFound
+	}
+
+	s.mu.Lock()
+	delete(s.touched, id)
+	s.mu.Unlock()
+
+	logger.Infof("API key %s deleted", id)
+	return nil
+}
+
+// AuthenticateAPIKey 校验 API Key，返回以所有者为 subject 的 claims
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, plaintext string) (*grpc_middleware.Claims, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(hashAPIKey(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key == nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	s.touch(key.ID, now)
+
+	claims := &grpc_middleware.Claims{
+		Name:      key.Name,
+		TokenUse:  grpc_middleware.TokenUseAPIKey,
+		Scopes:    key.Scopes,
+		Roles:     key.Scopes, // 权限范围中的角色名即 Key 的角色
+		Namespace: key.Namespace,
+	}
+	claims.ID = key.ID
+	claims.Subject = key.Owner
+	return claims, nil
+}
+
+// touch 按间隔更新最近使用时间，失败只记录日志
+func (s *APIKeyService) touch(id string, now time.Time) {
+	s.mu.Lock()
+	if last, ok := s.touched[id]; ok && now.Sub(last) < apiKeyTouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	if err := s.repo.TouchAPIKey(id, now); err != nil {
+		logger.Warnf("Failed to update last used time of API key %s: %v", id, err)
+	}
+}
+
+// hashAPIKey 密钥为 256 位随机数，直接使用 SHA-256 摘要即可防止泄露库后还原
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/repository"
+)
+
+func setupTestAPIKeyService(t *testing.T) (*APIKeyService, *repository.AuthRepository, func()) {
+	tmpFile, err := os.CreateTemp("", "taskflow_apikey_test_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	db, err := repository.NewSQLite(tmpFile.Name())
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	}
+	if err := db.InitSchema(); err != nil {
+		cleanup()
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	repo := repository.NewAuthRepository(db)
+	return NewAPIKeyService(repo), repo, cleanup
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	keys, repo, cleanup := setupTestAPIKeyService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, _, err := keys.Create(ctx, " ", "ci", "default", nil, nil); !errors.Is(err, ErrAPIKeyNameRequired) {
+		t.Errorf("expected ErrAPIKeyNameRequired, got %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if _, _, err := keys.Create(ctx, "nightly", "ci", "default", nil, &past); !errors.Is(err, ErrAPIKeyExpiryInPast) {
+		t.Errorf("expected ErrAPIKeyExpiryInPast, got %v", err)
+	}
+
+	key, plaintext, err := keys.Create(ctx, "nightly", "ci", "default", []string{"tasks:write"}, nil)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, key.Prefix) || key.KeyHash == plaintext {
+		t.Fatalf("unexpected key %+v", key)
+	}
+
+	claims, err := keys.AuthenticateAPIKey(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("authenticate failed: %v", err)
+	}
+	if claims.Subject != "ci" || claims.Name != "nightly" || claims.TokenUse != grpc_middleware.TokenUseAPIKey ||
+		len(claims.Scopes) != 1 || claims.Scopes[0] != "tasks:write" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if stored, _ := repo.GetAPIKey(key.ID); stored.LastUsedAt == nil {
+		t.Error("expected last_used_at to be recorded")
+	}
+
+	if _, err := keys.AuthenticateAPIKey(ctx, plaintext+"x"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected ErrInvalidAPIKey for wrong key, got %v", err)
+	}
+
+	// 修改过期时间后立即生效
+	soon := time.Now().Add(50 * time.Millisecond)
+	if _, err := keys.Update(ctx, key.ID, APIKeyUpdate{ExpiresAt: &soon}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := keys.AuthenticateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected expired key to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeyService_UpdateAndDelete(t *testing.T) {
+	keys, _, cleanup := setupTestAPIKeyService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	expiry := time.Now().Add(time.Hour)
+	key, plaintext, err := keys.Create(ctx, "cron", "ops", "default", []string{"tasks:read"}, &expiry)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if _, _, err := keys.Create(ctx, "other", "dev", "default", nil, nil); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	name := "cron-hosts"
+	updated, err := keys.Update(ctx, key.ID, APIKeyUpdate{Name: &name, ScopesSet: true, ClearExpiry: true})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if updated.Name != name || len(updated.Scopes) != 0 || updated.ExpiresAt != nil {
+		t.Errorf("unexpected update result %+v", updated)
+	}
+
+	list, err := keys.List(ctx, "ops")
+	if err != nil || len(list) != 1 || list[0].Name != name {
+		t.Fatalf("unexpected list %v: %v", list, err)
+	}
+
+	if err := keys.Delete(ctx, key.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := keys.Delete(ctx, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, err := keys.AuthenticateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected deleted key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// AuditService 审计日志：记录修改类调用并提供查询与哈希链校验
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// RecordAudit 追加审计事件，调用已经执行完毕，写入失败只记录日志
+func (s *AuditService) RecordAudit(ctx context.Context, event *model.AuditEvent) {
+	if err := s.repo.Append(event); err != nil {
+		logger.Errorf("Failed to record audit event for %s by %q: %v", event.Method, event.UserID, err)
+	}
+}
+
+// List 按 seq 倒序分页查询审计事件
+func (s *AuditService) List(ctx context.Context, filter repository.AuditFilter, pageSize int, pageToken string) ([]*model.AuditEvent, string, error) {
+	return s.repo.List(filter, pageSize, pageToken)
+}
+
+// Verify 校验整条哈希链，返回校验过的记录数
+func (s *AuditService) Verify(ctx context.Context) (int64, error) {
+	return s.repo.Verify()
+}
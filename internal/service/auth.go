@@ -0,0 +1,277 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+)
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidToken 令牌无效、已过期、已吊销或用途不符
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenNotOwned 只能吊销自己的令牌
+	ErrTokenNotOwned = errors.New("token belongs to another user")
+)
+
+// dummyHash 用户不存在时仍执行一次 bcrypt 比较，避免通过响应时间枚举用户名
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("taskflow-dummy-password"), bcrypt.DefaultCost)
+
+// User 本地用户
+type User struct {
+	Username     string
+	PasswordHash string
+	Roles        []string
+	Namespace    string // 用户所属命名空间，为空时属于默认命名空间
+}
+
+// UserStore 本地用户表，文件每行一个用户，格式与 htpasswd -B 相同：username:bcrypt_hash
+// 可追加以逗号分隔的角色：username:bcrypt_hash:operator,admin，未指定时使用默认角色
+// 第四列为所属命名空间：username:bcrypt_hash:submitter:team-a，未指定时属于默认命名空间
+// 空行与 # 开头的注释行被忽略
+type UserStore struct {
+	users map[string]*User
+}
+
+// LoadUserStore 从文件加载用户
+func LoadUserStore(path string) (*UserStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open users file: %w", err)
+	}
+	defer f.Close()
+	return ParseUserStore(f)
+}
+
+// ParseUserStore 解析用户表
+func ParseUserStore(r io.Reader) (*UserStore, error) {
+	store := &UserStore{users: make(map[string]*User)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, ":", 4)
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("users file line %d: expected username:hash[:roles[:namespace]]", line)
+		}
+		if _, err := bcrypt.Cost([]byte(fields[1])); err != nil {
+			return nil, fmt.Errorf("users file line %d: %w", line, err)
+		}
+
+		user := &User{Username: fields[0], PasswordHash: fields[1]}
+		if len(fields) >= 3 && fields[2] != "" {
+			for _, role := range strings.Split(fields[2], ",") {
+				role = strings.TrimSpace(role)
+				if _, ok := grpc_middleware.ParseRole(role); !ok {
+					return nil, fmt.Errorf("users file line %d: unknown role %q", line, role)
+				}
+				user.Roles = append(user.Roles, role)
+			}
+		}
+		if len(fields) == 4 && fields[3] != "" {
+			if !ValidNamespaceName(fields[3]) {
+				return nil, fmt.Errorf("users file line %d: invalid namespace %q", line, fields[3])
+			}
+			user.Namespace = fields[3]
+		}
+		store.users[user.Username] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Authenticate 校验用户名与密码
+func (s *UserStore) Authenticate(username, password string) (*User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Lookup 按用户名查找用户，不存在返回 nil
+func (s *UserStore) Lookup(username string) *User {
+	return s.users[username]
+}
+
+// RevocationList 令牌吊销列表，持久化到 SQLite 并在内存中缓存，供认证拦截器逐请求检查
+type RevocationList struct {
+	repo *repository.AuthRepository
+
+	mu        sync.RWMutex
+	revoked   map[string]time.Time // jti -> 令牌过期时间
+	lastPrune time.Time
+}
+
+// NewRevocationList 创建吊销列表并加载未过期的记录
+func NewRevocationList(repo *repository.AuthRepository) (*RevocationList, error) {
+	tokens, err := repo.ListRevokedTokens(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	l := &RevocationList{repo: repo, revoked: make(map[string]time.Time, len(tokens)), lastPrune: time.Now()}
+	for _, token := range tokens {
+		l.revoked[token.JTI] = token.ExpiresAt
+	}
+	return l, nil
+}
+
+// IsRevoked 实现 grpc_middleware.RevocationChecker
+func (l *RevocationList) IsRevoked(jti string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.revoked[jti]
+	return ok
+}
+
+// Revoke 吊销令牌，记录保留到令牌过期
+func (l *RevocationList) Revoke(jti, subject string, expiresAt time.Time) error {
+	now := time.Now()
+	if err := l.repo.RevokeToken(&repository.RevokedToken{
+		JTI: jti, Subject: subject, ExpiresAt: expiresAt, RevokedAt: now,
+	}); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked[jti] = expiresAt
+
+	// 过期令牌本身已无法通过校验，定期清理其吊销记录
+	if now.Sub(l.lastPrune) >= time.Hour {
+		l.lastPrune = now
+		for id, exp := range l.revoked {
+			if !exp.After(now) {
+				delete(l.revoked, id)
+			}
+		}
+		if _, err := l.repo.PruneRevokedTokens(now); err != nil {
+			logger.Warnf("Failed to prune revoked tokens: %v", err)
+		}
+	}
+	return nil
+}
+
+// TokenPair 登录或刷新返回的令牌
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
+
+// AuthService 认证服务：登录、刷新与吊销令牌
+type AuthService struct {
+	users      *UserStore
+	issuer     *grpc_middleware.TokenIssuer
+	verifier   *grpc_middleware.TokenVerifier
+	revoked    *RevocationList
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewAuthService 创建认证服务，verifier 需已设置 revoked 作为吊销检查
+func NewAuthService(users *UserStore, issuer *grpc_middleware.TokenIssuer, verifier *grpc_middleware.TokenVerifier, revoked *RevocationList, accessTTL, refreshTTL time.Duration) *AuthService {
+	if users == nil {
+		users = &UserStore{users: make(map[string]*User)}
+	}
+	return &AuthService{
+		users:      users,
+		issuer:     issuer,
+		verifier:   verifier,
+		revoked:    revoked,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Login 校验密码并签发令牌
+func (s *AuthService) Login(ctx context.Context, username, password string) (*TokenPair, error) {
+	user, err := s.users.Authenticate(username, password)
+	if err != nil {
+		logger.Warnf("Login failed for user %q", username)
+		return nil, err
+	}
+	return s.issue(user)
+}
+
+// Refresh 用刷新令牌换取新的令牌对，旧刷新令牌随即吊销（轮换）
+// 角色按用户表重新读取，用户被删除后无法再刷新
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.verifier.Verify(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.TokenUse != grpc_middleware.TokenUseRefresh || claims.ID == "" {
+		return nil, fmt.Errorf("%w: not a refresh token", ErrInvalidToken)
+	}
+	user := s.users.Lookup(claims.Subject)
+	if user == nil {
+		return nil, fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+
+	if err := s.revoked.Revoke(claims.ID, claims.Subject, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+	return s.issue(user)
+}
+
+// Revoke 吊销调用者自己的令牌（访问令牌或刷新令牌）
+func (s *AuthService) Revoke(ctx context.Context, token, callerID string) error {
+	claims, err := s.verifier.Verify(token)
+	if errors.Is(err, grpc_middleware.ErrTokenRevoked) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject != callerID {
+		return ErrTokenNotOwned
+	}
+	if claims.ID == "" {
+		return fmt.Errorf("%w: token has no id", ErrInvalidToken)
+	}
+	return s.revoked.Revoke(claims.ID, claims.Subject, claims.ExpiresAt.Time)
+}
+
+// issue 签发访问令牌与刷新令牌
+func (s *AuthService) issue(user *User) (*TokenPair, error) {
+	access, accessClaims, err := s.issuer.Issue(user.Username, user.Username, grpc_middleware.TokenUseAccess, user.Roles, user.Namespace, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshClaims, err := s.issuer.Issue(user.Username, user.Username, grpc_middleware.TokenUseRefresh, nil, "", s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresAt:  accessClaims.ExpiresAt.Time,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/repository"
+)
+
+const testAuthSecret = "0123456789abcdef0123456789abcdef"
+
+// setupTestAuthService 创建基于临时数据库的认证服务，用户 alice 的密码为 secret
+func setupTestAuthService(t *testing.T) (*AuthService, *grpc_middleware.TokenVerifier, func()) {
+	tmpFile, err := os.CreateTemp("", "taskflow_auth_test_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	db, err := repository.NewSQLite(tmpFile.Name())
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	}
+	if err := db.InitSchema(); err != nil {
+		cleanup()
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	users, err := ParseUserStore(strings.NewReader("# users\nalice:" + string(hash) + ":submitter\n"))
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to parse users: %v", err)
+	}
+
+	cfg := &grpc_middleware.AuthConfig{Secret: testAuthSecret, Issuer: "taskflow"}
+	verifier, err := grpc_middleware.NewTokenVerifier(cfg)
+	if err != nil {
+		cleanup()
+		t.Fatalf("NewTokenVerifier failed: %v", err)
+	}
+	issuer, err := grpc_middleware.NewTokenIssuer(cfg)
+	if err != nil {
+		cleanup()
+		t.Fatalf("NewTokenIssuer failed: %v", err)
+	}
+	revoked, err := NewRevocationList(repository.NewAuthRepository(db))
+	if err != nil {
+		cleanup()
+		t.Fatalf("NewRevocationList failed: %v", err)
+	}
+	verifier.SetRevocationChecker(revoked)
+
+	return NewAuthService(users, issuer, verifier, revoked, time.Hour, 24*time.Hour), verifier, cleanup
+}
+
+func TestParseUserStore(t *testing.T) {
+	if _, err := ParseUserStore(strings.NewReader("alice\n")); err == nil {
+		t.Error("expected error for line without hash")
+	}
+	if _, err := ParseUserStore(strings.NewReader("alice:plaintext\n")); err == nil {
+		t.Error("expected error for non-bcrypt hash")
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := ParseUserStore(strings.NewReader("alice:" + string(hash) + ":root\n")); err == nil {
+		t.Error("expected error for unknown role")
+	}
+	store, err := ParseUserStore(strings.NewReader("alice:" + string(hash) + ":submitter, operator\nbob:" + string(hash) + "\n"))
+	if err != nil {
+		t.Fatalf("failed to parse users: %v", err)
+	}
+	if roles := store.Lookup("alice").Roles; len(roles) != 2 || roles[1] != "operator" {
+		t.Errorf("unexpected roles %v", roles)
+	}
+	if roles := store.Lookup("bob").Roles; len(roles) != 0 {
+		t.Errorf("expected no roles for bob, got %v", roles)
+	}
+}
+
+func TestAuthService_LoginAndRefresh(t *testing.T) {
+	auth, verifier, cleanup := setupTestAuthService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := auth.Login(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := auth.Login(ctx, "bob", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+
+	pair, err := auth.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	claims, err := verifier.Verify(pair.AccessToken)
+	if err != nil || claims.Subject != "alice" || claims.TokenUse != grpc_middleware.TokenUseAccess ||
+		len(claims.Roles) != 1 || claims.Roles[0] != "submitter" {
+		t.Fatalf("unexpected access token claims %+v: %v", claims, err)
+	}
+
+	// 访问令牌不能用于刷新
+	if _, err := auth.Refresh(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for access token, got %v", err)
+	}
+
+	refreshed, err := auth.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if refreshed.RefreshToken == pair.RefreshToken {
+		t.Error("expected a new refresh token")
+	}
+	// 旧刷新令牌已轮换，不能再次使用
+	if _, err := auth.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected reused refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthService_Revoke(t *testing.T) {
+	auth, verifier, cleanup := setupTestAuthService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	pair, err := auth.Login(ctx, "alice", "secret")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	if err := auth.Revoke(ctx, pair.AccessToken, "bob"); !errors.Is(err, ErrTokenNotOwned) {
+		t.Errorf("expected ErrTokenNotOwned, got %v", err)
+	}
+	if err := auth.Revoke(ctx, pair.AccessToken, "alice"); err != nil {
+		t.Fatalf("revoke failed: %v", err)
+	}
+	if _, err := verifier.Verify(pair.AccessToken); !errors.Is(err, grpc_middleware.ErrTokenRevoked) {
+		t.Errorf("expected ErrTokenRevoked, got %v", err)
+	}
+	// 重复吊销视为成功
+	if err := auth.Revoke(ctx, pair.AccessToken, "alice"); err != nil {
+		t.Errorf("expected repeated revoke to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/repository"
+)
+
+// backupFilePrefix 备份文件名前缀，文件名中的时间戳可按字典序排序
+const backupFilePrefix = "taskflow-"
+
+// BackupInfo 备份结果
+type BackupInfo struct {
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// BackupManager 数据库在线备份：支持手动触发与定时备份，按数量轮转
+type BackupManager struct {
+	db       *repository.SQLite
+	dir      string
+	keep     int
+	interval time.Duration
+
+	runMu sync.Mutex // 保证同一时间只有一次备份
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewBackupManager 创建备份管理器
+func NewBackupManager(db *repository.SQLite, dir string, keep int, interval time.Duration) *BackupManager {
+	if keep <= 0 {
+		keep = 7
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &BackupManager{
+		db:       db,
+		dir:      dir,
+		keep:     keep,
+		interval: interval,
+	}
+}
+
+// Start 启动定时备份
+func (m *BackupManager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	m.running = true
+
+	go m.loop(ctx)
+
+	logger.Infof("Backup scheduler started (dir=%s, interval=%s, keep=%d)", m.dir, m.interval, m.keep)
+}
+
+// Stop 停止定时备份并等待进行中的备份完成
+func (m *BackupManager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.cancel()
+	m.running = false
+	done := m.done
+	m.mu.Unlock()
+
+	<-done
+	logger.Infof("Backup scheduler stopped")
+}
+
+// loop 定时备份循环
+func (m *BackupManager) loop(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Backup(ctx); err != nil && ctx.Err() == nil {
+				logger.Errorf("Scheduled backup failed: %v", err)
+			}
+		}
+	}
+}
+
+// Backup 生成一份快照并轮转旧备份
+func (m *BackupManager) Backup(ctx context.Context) (*BackupInfo, error) {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	now := time.Now().UTC()
+	path := filepath.Join(m.dir, fmt.Sprintf("%s%s.db", backupFilePrefix, now.Format("20060102-150405.000000")))
+
+	if err := m.db.Backup(ctx, path); err != nil {
+		metrics.RecordBackup(false, 0)
+		return nil, fmt.Errorf("failed to backup database: %w", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		metrics.RecordBackup(false, 0)
+		return nil, err
+	}
+	metrics.RecordBackup(true, stat.Size())
+	logger.Infof("Database backup written to %s (%d bytes)", path, stat.Size())
+
+	if err := m.rotate(); err != nil {
+		logger.Warnf("Failed to rotate backups: %v", err)
+	}
+
+	return &BackupInfo{Path: path, Size: stat.Size(), CreatedAt: now}, nil
+}
+
+// List 按时间从新到旧列出现有备份
+func (m *BackupManager) List() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(m.dir, backupFilePrefix+"*.db"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// rotate 只保留最新的 keep 份备份
+func (m *BackupManager) rotate() error {
+	files, err := m.List()
+	if err != nil {
+		return err
+	}
+	if len(files) <= m.keep {
+		return nil
+	}
+
+	for _, f := range files[m.keep:] {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+		logger.Infof("Removed old backup %s", f)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"taskflow/internal/repository"
+)
+
+func TestBackupManager_BackupAndRotate(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "taskflow_backup_test_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	db, err := repository.NewSQLite(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	defer db.Close()
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	dir := t.TempDir()
+	manager := NewBackupManager(db, dir, 2, 0)
+
+	var last *BackupInfo
+	for i := 0; i < 4; i++ {
+		info, err := manager.Backup(context.Background())
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		if info.Size == 0 {
+			t.Error("expected non-empty backup")
+		}
+		last = info
+	}
+
+	files, err := manager.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 backups after rotation, got %v", files)
+	}
+	if files[0] != last.Path {
+		t.Errorf("expected newest backup first, got %s", files[0])
+	}
+	if _, err := repository.ValidateSnapshot(files[1]); err != nil {
+		t.Errorf("expected valid snapshot: %v", err)
+	}
+}
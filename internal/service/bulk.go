@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// BulkAction 批量操作类型
+type BulkAction string
+
+const (
+	BulkActionCancel      BulkAction = "cancel"
+	BulkActionRetry       BulkAction = "retry"
+	BulkActionSetPriority BulkAction = "set_priority"
+)
+
+// BulkState 批量操作状态
+type BulkState string
+
+const (
+	BulkStateRunning   BulkState = "running"
+	BulkStateCompleted BulkState = "completed"
+	// BulkStateAborted 服务关闭时中断，未处理的任务保持原样
+	BulkStateAborted BulkState = "aborted"
+)
+
+const (
+	// maxBulkTargets 单次批量操作最多涉及的任务数
+	maxBulkTargets = 10000
+	// maxBulkErrors 每个操作最多保留的单项错误数，超出部分只计入 Failed
+	maxBulkErrors = 1000
+	// maxFinishedBulkOps 保留的已结束操作数量，超出时淘汰最早结束的
+	maxFinishedBulkOps = 100
+	// bulkPageSize 按过滤条件解析目标时的分页大小
+	bulkPageSize = 500
+)
+
+var (
+	// ErrBulkNoSelector 未指定任务 ID 或过滤条件，或同时指定
+	ErrBulkNoSelector = errors.New("exactly one of ids or filter is required")
+	// ErrBulkTooLarge 匹配的任务数超过单次上限
+	ErrBulkTooLarge = fmt.Errorf("bulk operation matches more than %d tasks", maxBulkTargets)
+	// ErrBulkInvalidPriority 目标优先级无效
+	ErrBulkInvalidPriority = errors.New("invalid priority")
+)
+
+// BulkRequest 批量操作请求，IDs 与 Filter 二选一
+type BulkRequest struct {
+	Action   BulkAction
+	IDs      []string
+	Filter   *repository.TaskFilter
+	Priority model.TaskPriority // 仅 BulkActionSetPriority
+	Operator string
+	DryRun   bool
+	// Namespace 只选择该命名空间的任务，为空时不限制
+	Namespace string
+}
+
+// BulkItemError 单个任务的处理错误
+type BulkItemError struct {
+	TaskID string `json:"task_id"`
+	Error  string `json:"error"`
+}
+
+// BulkOperation 批量操作进度
+// Total 为选中的任务数（ID 列表长度或过滤匹配数）；Affected 为其中当前状态适用该操作的任务数，
+// 不适用的计入 Skipped，不存在的 ID 计入 Failed；Processed = Succeeded + Failed + Skipped
+type BulkOperation struct {
+	ID         string          `json:"id"`
+	Action     BulkAction      `json:"action"`
+	State      BulkState       `json:"state"`
+	DryRun     bool            `json:"dry_run"`
+	Operator   string          `json:"operator"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Total      int             `json:"total"`
+	Affected   int             `json:"affected"`
+	Processed  int             `json:"processed"`
+	Succeeded  int             `json:"succeeded"`
+	Failed     int             `json:"failed"`
+	Skipped    int             `json:"skipped"`
+	Errors     []BulkItemError `json:"errors"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+}
+
+// addError 记录单项错误
+func (op *BulkOperation) addError(taskID string, err error) {
+	op.Failed++
+	op.Processed++
+	if len(op.Errors) < maxBulkErrors {
+		op.Errors = append(op.Errors, BulkItemError{TaskID: taskID, Error: err.Error()})
+	}
+}
+
+// snapshot 返回操作的副本，调用方需持有锁
+func (op *BulkOperation) snapshot() *BulkOperation {
+	cp := *op
+	cp.Errors = append([]BulkItemError(nil), op.Errors...)
+	if op.FinishedAt != nil {
+		finished := *op.FinishedAt
+		cp.FinishedAt = &finished
+	}
+	return &cp
+}
+
+// BulkManager 在后台执行批量取消、重试与调整优先级，并跟踪进度
+// 操作记录仅保存在内存中，服务重启后丢失
+type BulkManager struct {
+	repo  *repository.TaskRepository
+	tasks *TaskService
+
+	mu       sync.Mutex
+	ops      map[string]*BulkOperation
+	finished []string // 已结束操作 ID，按结束顺序
+	cancel   context.CancelFunc
+	ctx      context.Context
+	wg       sync.WaitGroup
+}
+
+// NewBulkManager 创建批量操作管理器
+func NewBulkManager(repo *repository.TaskRepository) *BulkManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BulkManager{
+		repo:   repo,
+		tasks:  NewTaskService(repo),
+		ops:    make(map[string]*BulkOperation),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 解析目标任务并启动批量操作，返回初始进度
+// DryRun 时只统计受影响的任务数，不创建后台操作
+func (m *BulkManager) Start(ctx context.Context, req BulkRequest) (*BulkOperation, error) {
+	if (len(req.IDs) == 0) == (req.Filter == nil) {
+		return nil, ErrBulkNoSelector
+	}
+	switch req.Action {
+	case BulkActionCancel, BulkActionRetry:
+	case BulkActionSetPriority:
+		if req.Priority < model.TaskPriorityLow || req.Priority > model.TaskPriorityUrgent {
+			return nil, fmt.Errorf("%w: %d", ErrBulkInvalidPriority, req.Priority)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bulk action %q", req.Action)
+	}
+
+	tasks, missing, err := m.resolve(req)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &BulkOperation{
+		Action:    req.Action,
+		State:     BulkStateRunning,
+		DryRun:    req.DryRun,
+		Operator:  req.Operator,
+		Namespace: req.Namespace,
+		Total:     len(tasks) + len(missing),
+		CreatedAt: time.Now(),
+	}
+
+	// 先按当前状态筛出适用的任务，执行时服务层会再次校验
+	targets := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if bulkApplies(req, task) {
+			targets = append(targets, task.ID)
+		} else {
+			op.Skipped++
+			op.Processed++
+		}
+	}
+	op.Affected = len(targets)
+
+	if req.DryRun {
+		now := time.Now()
+		op.State = BulkStateCompleted
+		op.FinishedAt = &now
+		return op, nil
+	}
+
+	for _, id := range missing {
+		op.addError(id, fmt.Errorf("%w: %s", ErrTaskNotFound, id))
+	}
+
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return nil, errors.New("bulk manager is stopped")
+	}
+	op.ID = uuid.New().String()
+	m.ops[op.ID] = op
+	m.wg.Add(1)
+	snapshot := op.snapshot()
+	m.mu.Unlock()
+
+	go m.run(op, req, targets)
+
+	logger.Infof("Bulk %s %s started: %d selected, %d affected", req.Action, op.ID, op.Total, op.Affected)
+	return snapshot, nil
+}
+
+// Get 返回操作进度，不存在返回 nil
+func (m *BulkManager) Get(id string) *BulkOperation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, ok := m.ops[id]
+	if !ok {
+		return nil
+	}
+	return op.snapshot()
+}
+
+// Stop 中断所有进行中的操作并等待退出
+func (m *BulkManager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// resolve 解析目标任务，返回存在的任务与不存在的 ID（其他命名空间的任务视为不存在）
+func (m *BulkManager) resolve(req BulkRequest) ([]*model.Task, []string, error) {
+	repo := m.repo
+	if req.Namespace != "" {
+		repo = repo.InNamespace(req.Namespace)
+	}
+
+	if len(req.IDs) > 0 {
+		if len(req.IDs) > maxBulkTargets {
+			return nil, nil, ErrBulkTooLarge
+		}
+
+		seen := make(map[string]bool, len(req.IDs))
+		var tasks []*model.Task
+		var missing []string
+		for _, id := range req.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			task, err := repo.GetByID(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			if task == nil {
+				missing = append(missing, id)
+				continue
+			}
+			tasks = append(tasks, task)
+		}
+		return tasks, missing, nil
+	}
+
+	// 用游标分页一次性取完匹配集合，避免处理过程中状态变化导致翻页遗漏
+	filter := *req.Filter
+	filter.PageSize = bulkPageSize
+	filter.PageIndex = 0
+	filter.PageToken = ""
+
+	var tasks []*model.Task
+	for {
+		page, err := repo.ListByCursor(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		if page.Total > maxBulkTargets {
+			return nil, nil, ErrBulkTooLarge
+		}
+		tasks = append(tasks, page.Tasks...)
+		if page.NextPageToken == "" {
+			return tasks, nil, nil
+		}
+		filter.PageToken = page.NextPageToken
+	}
+}
+
+// bulkApplies 判断任务当前状态是否适用该操作
+func bulkApplies(req BulkRequest, task *model.Task) bool {
+	switch req.Action {
+	case BulkActionCancel:
+		return !task.IsTerminal()
+	case BulkActionRetry:
+		return task.CanRetry()
+	case BulkActionSetPriority:
+		return !task.IsTerminal() && task.Priority != req.Priority
+	}
+	return false
+}
+
+// run 逐个处理目标任务并更新进度
+func (m *BulkManager) run(op *BulkOperation, req BulkRequest, targets []string) {
+	defer m.wg.Done()
+
+	state := BulkStateCompleted
+	for _, id := range targets {
+		if m.ctx.Err() != nil {
+			state = BulkStateAborted
+			break
+		}
+
+		var err error
+		switch req.Action {
+		case BulkActionCancel:
+			err = m.tasks.CancelTask(m.ctx, id, req.Operator)
+		case BulkActionRetry:
+			err = m.tasks.RetryTask(m.ctx, id, req.Operator)
+		case BulkActionSetPriority:
+			err = m.tasks.SetPriority(m.ctx, id, req.Priority)
+		}
+
+		m.mu.Lock()
+		if err != nil {
+			op.addError(id, err)
+		} else {
+			op.Succeeded++
+			op.Processed++
+		}
+		m.mu.Unlock()
+	}
+
+	m.finish(op, state)
+	logger.Infof("Bulk %s %s %s: %d succeeded, %d failed, %d skipped",
+		op.Action, op.ID, state, op.Succeeded, op.Failed, op.Skipped)
+}
+
+// finish 标记操作结束，并淘汰超出保留数量的旧操作
+func (m *BulkManager) finish(op *BulkOperation, state BulkState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	op.State = state
+	op.FinishedAt = &now
+
+	m.finished = append(m.finished, op.ID)
+	for len(m.finished) > maxFinishedBulkOps {
+		delete(m.ops, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// waitBulk 轮询直到操作结束
+func waitBulk(t *testing.T, m *BulkManager, id string) *BulkOperation {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		op := m.Get(id)
+		if op == nil {
+			t.Fatalf("bulk operation %s not found", id)
+		}
+		if op.State != BulkStateRunning {
+			return op
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("bulk operation %s did not finish", id)
+	return nil
+}
+
+func TestBulkManager_RetryByFilter(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// 3 个可重试、1 个重试次数用完、1 个已成功
+	for i := 0; i < 5; i++ {
+		task := model.NewTask(fmt.Sprintf("bulk-%d", i), "", model.TaskPriorityNormal, "etl", nil, nil, 2, "test")
+		task.ID = fmt.Sprintf("bulk-%d", i)
+		task.Status = model.TaskStatusFailed
+		if i == 3 {
+			task.RetryCount = 2
+		}
+		if i == 4 {
+			task.Status = model.TaskStatusSucceeded
+		}
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	m := NewBulkManager(repo)
+	defer m.Stop()
+
+	filter := &repository.TaskFilter{Statuses: []model.TaskStatus{model.TaskStatusFailed}}
+	dry, err := m.Start(context.Background(), BulkRequest{Action: BulkActionRetry, Filter: filter, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if dry.ID != "" || dry.Total != 4 || dry.Affected != 3 {
+		t.Fatalf("unexpected dry run result %+v", dry)
+	}
+	if task, _ := repo.GetByID("bulk-0"); task.Status != model.TaskStatusFailed {
+		t.Fatalf("dry run must not modify tasks, got %s", task.Status)
+	}
+
+	started, err := m.Start(context.Background(), BulkRequest{Action: BulkActionRetry, Filter: filter, Operator: "ops"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	op := waitBulk(t, m, started.ID)
+	if op.State != BulkStateCompleted || op.Succeeded != 3 || op.Skipped != 1 || op.Failed != 0 || op.Processed != op.Total {
+		t.Fatalf("unexpected result %+v", op)
+	}
+
+	for _, id := range []string{"bulk-0", "bulk-1", "bulk-2"} {
+		task, _ := repo.GetByID(id)
+		if task.Status != model.TaskStatusPending {
+			t.Errorf("expected %s PENDING, got %s", id, task.Status)
+		}
+	}
+}
+
+func TestBulkManager_CancelAndPriorityByIDs(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		task := model.NewTask(fmt.Sprintf("bulk-%d", i), "", model.TaskPriorityLow, "etl", nil, nil, 0, "test")
+		task.ID = fmt.Sprintf("bulk-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	m := NewBulkManager(repo)
+	defer m.Stop()
+
+	if _, err := m.Start(context.Background(), BulkRequest{Action: BulkActionCancel}); !errors.Is(err, ErrBulkNoSelector) {
+		t.Errorf("expected ErrBulkNoSelector, got %v", err)
+	}
+	if _, err := m.Start(context.Background(), BulkRequest{Action: BulkActionSetPriority, IDs: []string{"bulk-0"}}); !errors.Is(err, ErrBulkInvalidPriority) {
+		t.Errorf("expected ErrBulkInvalidPriority, got %v", err)
+	}
+
+	started, err := m.Start(context.Background(), BulkRequest{
+		Action: BulkActionSetPriority, IDs: []string{"bulk-0", "bulk-1"}, Priority: model.TaskPriorityUrgent,
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if op := waitBulk(t, m, started.ID); op.Succeeded != 2 {
+		t.Fatalf("unexpected priority result %+v", op)
+	}
+	if task, _ := repo.GetByID("bulk-1"); task.Priority != model.TaskPriorityUrgent {
+		t.Errorf("expected URGENT, got %s", task.Priority)
+	}
+
+	started, err = m.Start(context.Background(), BulkRequest{
+		Action: BulkActionCancel, IDs: []string{"bulk-0", "missing", "bulk-0"}, Operator: "ops",
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	op := waitBulk(t, m, started.ID)
+	if op.Total != 2 || op.Succeeded != 1 || op.Failed != 1 {
+		t.Fatalf("unexpected cancel result %+v", op)
+	}
+	if len(op.Errors) != 1 || op.Errors[0].TaskID != "missing" {
+		t.Errorf("expected item error for missing task, got %v", op.Errors)
+	}
+	if task, _ := repo.GetByID("bulk-0"); task.Status != model.TaskStatusCancelled {
+		t.Errorf("expected CANCELLED, got %s", task.Status)
+	}
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// ErrNonTerminalPurge 只允许清理终态任务
+var ErrNonTerminalPurge = errors.New("only terminal tasks can be purged")
+
+// terminalStatuses 可被清理的终态
+var terminalStatuses = []model.TaskStatus{
+	model.TaskStatusSucceeded,
+	model.TaskStatusFailed,
+	model.TaskStatusCancelled,
+	model.TaskStatusTimeout,
+}
+
+// TaskArchiver 任务归档器：在任务从主表删除前保存其完整数据
+type TaskArchiver interface {
+	// ArchiveAndDelete 归档任务并从主表删除，返回删除的任务数
+	ArchiveAndDelete(tasks []*model.Task) (int, error)
+}
+
+// FileArchiver 归档到按天滚动的 gzip 压缩 JSONL 文件
+// 每批写入一个独立的 gzip 成员并落盘后再删除数据库记录，文件可直接用 zcat 读取
+type FileArchiver struct {
+	repo *repository.TaskRepository
+	dir  string
+	mu   sync.Mutex
+}
+
+// NewFileArchiver 创建文件归档器
+func NewFileArchiver(repo *repository.TaskRepository, dir string) *FileArchiver {
+	return &FileArchiver{repo: repo, dir: dir}
+}
+
+// ArchiveAndDelete 写入归档文件后删除任务
+func (a *FileArchiver) ArchiveAndDelete(tasks []*model.Task) (int, error) {
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	if err := a.write(tasks); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return a.repo.DeleteTasks(ids)
+}
+
+// write 追加一个 gzip 成员到当天的归档文件
+func (a *FileArchiver) write(tasks []*model.Task) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(a.dir, fmt.Sprintf("tasks-%s.jsonl.gz", time.Now().Format("20060102")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, task := range tasks {
+		if err := enc.Encode(task); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// TableArchiver 归档到数据库 tasks_archive 表（与删除在同一事务中）
+type TableArchiver struct {
+	repo *repository.TaskRepository
+}
+
+// NewTableArchiver 创建归档表归档器
+func NewTableArchiver(repo *repository.TaskRepository) *TableArchiver {
+	return &TableArchiver{repo: repo}
+}
+
+// ArchiveAndDelete 写入归档表并删除任务
+func (a *TableArchiver) ArchiveAndDelete(tasks []*model.Task) (int, error) {
+	return a.repo.ArchiveTasks(tasks)
+}
+
+// RetentionRule 保留规则：指定状态（可限定任务类型）的任务完成后保留 MaxAge
+type RetentionRule struct {
+	TaskType string // 为空表示所有类型；同状态下类型规则优先于通用规则
+	Status   model.TaskStatus
+	MaxAge   time.Duration
+}
+
+// PurgeResult 清理结果
+type PurgeResult struct {
+	Matched int  // 匹配的任务数
+	Deleted int  // 实际删除的任务数
+	DryRun  bool // 仅统计，未删除
+}
+
+// Janitor 后台清理器：按保留策略归档并删除过期任务
+type Janitor struct {
+	repo      *repository.TaskRepository
+	archiver  TaskArchiver // 为 nil 时直接删除
+	rules     []RetentionRule
+	interval  time.Duration
+	batchSize int
+
+	runMu sync.Mutex // 保证同一时间只有一次清理
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewJanitor 创建清理器
+func NewJanitor(repo *repository.TaskRepository, archiver TaskArchiver, rules []RetentionRule, interval time.Duration, batchSize int) *Janitor {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &Janitor{
+		repo:      repo,
+		archiver:  archiver,
+		rules:     rules,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Start 启动后台清理循环
+func (j *Janitor) Start(ctx context.Context) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running {
+		return
+	}
+
+	ctx, j.cancel = context.WithCancel(ctx)
+	j.done = make(chan struct{})
+	j.running = true
+
+	go j.loop(ctx)
+
+	logger.Infof("Retention janitor started (interval=%s, rules=%d)", j.interval, len(j.rules))
+}
+
+// Stop 停止后台清理循环并等待当前批次完成
+func (j *Janitor) Stop() {
+	j.mu.Lock()
+	if !j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.cancel()
+	j.running = false
+	done := j.done
+	j.mu.Unlock()
+
+	<-done
+	logger.Infof("Retention janitor stopped")
+}
+
+// loop 清理循环
+func (j *Janitor) loop(ctx context.Context) {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logger.Errorf("Retention janitor run failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 按保留规则执行一次清理，返回删除的任务数
+func (j *Janitor) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	total := 0
+
+	for _, filter := range j.ruleFilters(now) {
+		result, err := j.Purge(ctx, filter, false, 0)
+		if result != nil {
+			total += result.Deleted
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if total > 0 {
+		metrics.RecordTasksPurged("retention", total)
+		logger.Infof("Retention janitor removed %d expired tasks", total)
+	}
+	return total, nil
+}
+
+// ruleFilters 将保留规则转换为查询条件
+// 通用规则排除同状态下已有类型规则的任务类型，避免规则重叠
+func (j *Janitor) ruleFilters(now time.Time) []repository.TaskFilter {
+	typed := make(map[model.TaskStatus][]string)
+	for _, rule := range j.rules {
+		if rule.TaskType != "" {
+			typed[rule.Status] = append(typed[rule.Status], rule.TaskType)
+		}
+	}
+
+	filters := make([]repository.TaskFilter, 0, len(j.rules))
+	for _, rule := range j.rules {
+		before := now.Add(-rule.MaxAge)
+		filter := repository.TaskFilter{
+			Statuses:       []model.TaskStatus{rule.Status},
+			FinishedBefore: &before,
+		}
+		if rule.TaskType != "" {
+			filter.TaskType = rule.TaskType
+		} else {
+			filter.ExcludeTaskTypes = typed[rule.Status]
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// Purge 归档并删除符合条件的终态任务
+// 未指定状态时默认所有终态；limit <= 0 表示不限制数量；dryRun 仅返回匹配数量
+func (j *Janitor) Purge(ctx context.Context, filter repository.TaskFilter, dryRun bool, limit int) (*PurgeResult, error) {
+	if filter.Status != nil {
+		filter.Statuses = append(filter.Statuses, *filter.Status)
+		filter.Status = nil
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = terminalStatuses
+	}
+	for _, status := range filter.Statuses {
+		if !isTerminalStatus(status) {
+			return nil, fmt.Errorf("%w: %s", ErrNonTerminalPurge, status)
+		}
+	}
+
+	// 按创建时间升序分批处理，先清理最旧的任务
+	filter.SortBy = "created_at"
+	filter.SortDesc = false
+	filter.PageIndex = 0
+	filter.PageToken = ""
+
+	result := &PurgeResult{DryRun: dryRun}
+	if dryRun {
+		filter.PageSize = 1
+		_, total, err := j.repo.ListByFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Matched = total
+		if limit > 0 && result.Matched > limit {
+			result.Matched = limit
+		}
+		return result, nil
+	}
+
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	for limit <= 0 || result.Deleted < limit {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		filter.PageSize = j.batchSize
+		if limit > 0 && limit-result.Deleted < filter.PageSize {
+			filter.PageSize = limit - result.Deleted
+		}
+
+		tasks, _, err := j.repo.ListByFilter(filter)
+		if err != nil {
+			return result, err
+		}
+		if len(tasks) == 0 {
+			break
+		}
+		result.Matched += len(tasks)
+
+		deleted, err := j.deleteBatch(tasks)
+		result.Deleted += deleted
+		if err != nil {
+			return result, err
+		}
+		if deleted == 0 || len(tasks) < filter.PageSize {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteTask 归档并删除单个任务
+func (j *Janitor) DeleteTask(task *model.Task) error {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	_, err := j.deleteBatch([]*model.Task{task})
+	return err
+}
+
+// deleteBatch 加载事件后归档并删除一批任务
+func (j *Janitor) deleteBatch(tasks []*model.Task) (int, error) {
+	if j.archiver == nil {
+		ids := make([]string, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
+		}
+		return j.repo.DeleteTasks(ids)
+	}
+
+	for _, task := range tasks {
+		if task.Events != nil {
+			continue
+		}
+		events, err := j.repo.GetEventsByTaskID(task.ID)
+		if err != nil {
+			return 0, err
+		}
+		task.Events = events
+	}
+
+	return j.archiver.ArchiveAndDelete(tasks)
+}
+
+// isTerminalStatus 是否为终态
+func isTerminalStatus(status model.TaskStatus) bool {
+	for _, s := range terminalStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// createAgedTask 创建指定状态、类型并在 age 之前完成的任务
+func createAgedTask(t *testing.T, repo *repository.TaskRepository, id string, status model.TaskStatus, taskType string, age time.Duration) {
+	t.Helper()
+
+	finished := time.Now().Add(-age)
+	task := model.NewTask(id, "janitor test", model.TaskPriorityNormal, taskType, nil, nil, 0, "test")
+	task.ID = id
+	task.Status = status
+	task.CreatedAt = finished.Add(-time.Minute)
+	task.UpdatedAt = finished
+	if status != model.TaskStatusPending && status != model.TaskStatusRunning {
+		task.CompletedAt = &finished
+	}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	event := &model.TaskEvent{
+		ID:        id + "-event",
+		TaskID:    id,
+		ToStatus:  status,
+		Timestamp: finished,
+		Operator:  "test",
+	}
+	if err := repo.AddEvent(event); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+}
+
+func TestJanitor_RunOnceWithRules(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	createAgedTask(t, repo, "succeeded-old", model.TaskStatusSucceeded, "report", 8*day)
+	createAgedTask(t, repo, "succeeded-new", model.TaskStatusSucceeded, "report", 1*day)
+	createAgedTask(t, repo, "failed-old", model.TaskStatusFailed, "report", 8*day)
+	createAgedTask(t, repo, "failed-ancient", model.TaskStatusFailed, "report", 31*day)
+	createAgedTask(t, repo, "etl-succeeded", model.TaskStatusSucceeded, "etl", 2*day)
+	createAgedTask(t, repo, "pending-old", model.TaskStatusPending, "report", 60*day)
+
+	rules := []RetentionRule{
+		{Status: model.TaskStatusSucceeded, MaxAge: 7 * day},
+		{Status: model.TaskStatusFailed, MaxAge: 30 * day},
+		{TaskType: "etl", Status: model.TaskStatusSucceeded, MaxAge: 1 * day},
+	}
+	archiver := NewTableArchiver(repo)
+	janitor := NewJanitor(repo, archiver, rules, time.Hour, 2)
+
+	deleted, err := janitor.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("expected 3 deleted tasks, got %d", deleted)
+	}
+
+	for id, kept := range map[string]bool{
+		"succeeded-old":  false,
+		"succeeded-new":  true,
+		"failed-old":     true,
+		"failed-ancient": false,
+		"etl-succeeded":  false,
+		"pending-old":    true,
+	} {
+		task, err := repo.GetByID(id)
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if (task != nil) != kept {
+			t.Errorf("task %s: expected kept=%v", id, kept)
+		}
+
+		archived, err := repo.GetArchivedTask(id)
+		if err != nil {
+			t.Fatalf("failed to get archived task: %v", err)
+		}
+		if (archived != nil) == kept {
+			t.Errorf("task %s: expected archived=%v", id, !kept)
+		}
+		if archived != nil && len(archived.Events) != 1 {
+			t.Errorf("task %s: expected archived events, got %d", id, len(archived.Events))
+		}
+	}
+
+	events, err := repo.GetEventsByTaskID("succeeded-old")
+	if err != nil {
+		t.Fatalf("failed to get events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected events of deleted task to be removed, got %d", len(events))
+	}
+}
+
+func TestJanitor_PurgeToFile(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		createAgedTask(t, repo, fmt.Sprintf("purge-%d", i), model.TaskStatusFailed, "etl", time.Duration(i+1)*time.Hour)
+	}
+	createAgedTask(t, repo, "purge-running", model.TaskStatusRunning, "etl", 10*time.Hour)
+
+	dir := t.TempDir()
+	janitor := NewJanitor(repo, NewFileArchiver(repo, dir), nil, time.Hour, 2)
+	ctx := context.Background()
+
+	// 非终态不允许清理
+	running := model.TaskStatusRunning
+	if _, err := janitor.Purge(ctx, repository.TaskFilter{Status: &running}, true, 0); !errors.Is(err, ErrNonTerminalPurge) {
+		t.Errorf("expected ErrNonTerminalPurge, got %v", err)
+	}
+
+	before := time.Now().Add(-90 * time.Minute)
+	filter := repository.TaskFilter{FinishedBefore: &before}
+
+	result, err := janitor.Purge(ctx, filter, true, 0)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if result.Matched != 4 || result.Deleted != 0 {
+		t.Errorf("dry run: expected 4 matched and 0 deleted, got %+v", result)
+	}
+
+	result, err = janitor.Purge(ctx, filter, false, 3)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if result.Deleted != 3 {
+		t.Errorf("expected 3 deleted with limit, got %+v", result)
+	}
+
+	result, err = janitor.Purge(ctx, filter, false, 0)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if result.Deleted != 1 {
+		t.Errorf("expected 1 remaining deleted, got %+v", result)
+	}
+
+	// 多个 gzip 成员可连续读取
+	files, _ := filepath.Glob(filepath.Join(dir, "tasks-*.jsonl.gz"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 archive file, got %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	archived := map[string]bool{}
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var task model.Task
+		if err := json.Unmarshal(scanner.Bytes(), &task); err != nil {
+			t.Fatalf("invalid archive line: %v", err)
+		}
+		archived[task.ID] = true
+	}
+	if len(archived) != 4 || archived["purge-0"] {
+		t.Errorf("unexpected archived tasks: %v", archived)
+	}
+
+	if task, _ := repo.GetByID("purge-0"); task == nil {
+		t.Error("expected recent task to be kept")
+	}
+	if task, _ := repo.GetByID("purge-running"); task == nil {
+		t.Error("expected running task to be kept")
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+var (
+	// ErrNamespaceNotFound 命名空间不存在
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	// ErrInvalidNamespace 命名空间名称不合法
+	ErrInvalidNamespace = errors.New("namespace name must be 1-63 lowercase letters, digits or '-', starting and ending with a letter or digit")
+	// ErrInvalidQuota 配额为负数
+	ErrInvalidQuota = errors.New("namespace quotas must not be negative")
+	// ErrDefaultNamespace 默认命名空间不能删除
+	ErrDefaultNamespace = errors.New("the default namespace cannot be deleted")
+	// ErrQuotaExceeded 命名空间配额已用完
+	ErrQuotaExceeded = errors.New("namespace quota exceeded")
+)
+
+// namespacePattern 命名空间名称格式，与 DNS 标签一致
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidNamespaceName 判断命名空间名称是否合法
+func ValidNamespaceName(name string) bool {
+	return namespacePattern.MatchString(name)
+}
+
+// NamespaceUpdate 命名空间可修改的字段，nil 表示不修改
+type NamespaceUpdate struct {
+	Description    *string
+	MaxTasks       *int
+	MaxActiveTasks *int
+}
+
+// NamespaceService 命名空间管理与配额检查
+type NamespaceService struct {
+	repo *repository.NamespaceRepository
+}
+
+// NewNamespaceService 创建命名空间服务
+func NewNamespaceService(repo *repository.NamespaceRepository) *NamespaceService {
+	return &NamespaceService{repo: repo}
+}
+
+// Create 创建命名空间
+func (s *NamespaceService) Create(ctx context.Context, name, description string, maxTasks, maxActiveTasks int) (*repository.Namespace, error) {
+	if !ValidNamespaceName(name) {
+		return nil, ErrInvalidNamespace
+	}
+	if maxTasks < 0 || maxActiveTasks < 0 {
+		return nil, ErrInvalidQuota
+	}
+
+	now := time.Now()
+	ns := &repository.Namespace{
+		Name:           name,
+		Description:    description,
+		MaxTasks:       maxTasks,
+		MaxActiveTasks: maxActiveTasks,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.repo.Create(ns); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("Namespace %s created", name)
+	return ns, nil
+}
+
+// Get 获取命名空间
+func (s *NamespaceService) Get(ctx context.Context, name string) (*repository.Namespace, error) {
+	ns, err := s.repo.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil {
+		return nil, ErrNamespaceNotFound
+	}
+	return ns, nil
+}
+
+// List 列出全部命名空间
+func (s *NamespaceService) List(ctx context.Context) ([]*repository.Namespace, error) {
+	return s.repo.List()
+}
+
+// Usage 统计命名空间当前用量
+func (s *NamespaceService) Usage(ctx context.Context, name string) (repository.NamespaceUsage, error) {
+	return s.repo.Usage(name)
+}
+
+// Update 修改描述或配额，调低配额不影响已有任务
+func (s *NamespaceService) Update(ctx context.Context, name string, update NamespaceUpdate) (*repository.Namespace, error) {
+	ns, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Description != nil {
+		ns.Description = *update.Description
+	}
+	if update.MaxTasks != nil {
+		ns.MaxTasks = *update.MaxTasks
+	}
+	if update.MaxActiveTasks != nil {
+		ns.MaxActiveTasks = *update.MaxActiveTasks
+	}
+	if ns.MaxTasks < 0 || ns.MaxActiveTasks < 0 {
+		return nil, ErrInvalidQuota
+	}
+	ns.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ns); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// Delete 删除命名空间，命名空间中仍有任务时失败
+func (s *NamespaceService) Delete(ctx context.Context, name string) error {
+	if name == model.DefaultNamespace {
+		return ErrDefaultNamespace
+	}
+
+	found, err := s.repo.Delete(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNamespaceNotFound
+	}
+
+	logger.Infof("Namespace %s deleted", name)
+	return nil
+}
+
+// CheckQuota 检查命名空间存在且还能再创建 n 个任务
+// 检查与创建不在同一事务中，并发创建时可能短暂超出配额
+func (s *NamespaceService) CheckQuota(ctx context.Context, name string, n int) error {
+	ns, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if ns.MaxTasks == 0 && ns.MaxActiveTasks == 0 {
+		return nil
+	}
+
+	usage, err := s.repo.Usage(name)
+	if err != nil {
+		return err
+	}
+	if ns.MaxTasks > 0 && usage.Tasks+n > ns.MaxTasks {
+		return fmt.Errorf("%w: namespace %s allows %d tasks, has %d", ErrQuotaExceeded, name, ns.MaxTasks, usage.Tasks)
+	}
+	if ns.MaxActiveTasks > 0 && usage.ActiveTasks+n > ns.MaxActiveTasks {
+		return fmt.Errorf("%w: namespace %s allows %d active tasks, has %d", ErrQuotaExceeded, name, ns.MaxActiveTasks, usage.ActiveTasks)
+	}
+	return nil
+}
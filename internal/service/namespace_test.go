@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestNamespaceService(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "taskflow_namespace_test_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	db, err := repository.NewSQLite(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	defer db.Close()
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	ctx := context.Background()
+	namespaces := NewNamespaceService(repository.NewNamespaceRepository(db))
+
+	if _, err := namespaces.Create(ctx, "Team_A", "", 0, 0); !errors.Is(err, ErrInvalidNamespace) {
+		t.Errorf("expected ErrInvalidNamespace, got %v", err)
+	}
+	if _, err := namespaces.Create(ctx, "team-a", "", -1, 0); !errors.Is(err, ErrInvalidQuota) {
+		t.Errorf("expected ErrInvalidQuota, got %v", err)
+	}
+	if _, err := namespaces.Create(ctx, "team-a", "team A", 0, 1); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	if err := namespaces.CheckQuota(ctx, "missing", 1); !errors.Is(err, ErrNamespaceNotFound) {
+		t.Errorf("expected ErrNamespaceNotFound, got %v", err)
+	}
+
+	repo := repository.NewTaskRepository(db).InNamespace("team-a")
+	task := model.NewTask("t", "", model.TaskPriorityNormal, "test", nil, nil, 0, "alice")
+	task.ID = "t-1"
+	if err := namespaces.CheckQuota(ctx, "team-a", 1); err != nil {
+		t.Fatalf("unexpected quota error: %v", err)
+	}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := namespaces.CheckQuota(ctx, "team-a", 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for active tasks, got %v", err)
+	}
+
+	// 任务结束后活跃配额释放
+	if err := repo.UpdateStatus("t-1", model.TaskStatusPending, model.TaskStatusCancelled); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+	if err := namespaces.CheckQuota(ctx, "team-a", 1); err != nil {
+		t.Errorf("expected quota available after task finished, got %v", err)
+	}
+
+	maxTasks := 1
+	if _, err := namespaces.Update(ctx, "team-a", NamespaceUpdate{MaxTasks: &maxTasks}); err != nil {
+		t.Fatalf("failed to update namespace: %v", err)
+	}
+	if err := namespaces.CheckQuota(ctx, "team-a", 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for total tasks, got %v", err)
+	}
+
+	if err := namespaces.Delete(ctx, model.DefaultNamespace); !errors.Is(err, ErrDefaultNamespace) {
+		t.Errorf("expected ErrDefaultNamespace, got %v", err)
+	}
+	if err := namespaces.Delete(ctx, "team-a"); !errors.Is(err, repository.ErrNamespaceNotEmpty) {
+		t.Errorf("expected ErrNamespaceNotEmpty, got %v", err)
+	}
+}
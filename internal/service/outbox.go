@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// OutboxSink 任务变更的发布目标
+// Publish 返回错误时该 sink 暂停并在下一轮重试同一条记录，保证对每个 sink 严格按提交顺序、至少一次投递
+type OutboxSink interface {
+	Publish(entry *model.OutboxEntry) error
+}
+
+// OutboxRelay 读取 outbox 表并按 seq 顺序发布到各个 sink
+// 每个 sink 有独立的投递游标，一个 sink 失败不影响其他 sink；所有 sink 都投递后才标记为已发布
+type OutboxRelay struct {
+	repo      *repository.TaskRepository
+	sinks     []*outboxCursor
+	interval  time.Duration // 轮询间隔（写入时另有即时通知）
+	retention time.Duration // 已发布记录的保留时长
+	batchSize int
+
+	runMu     sync.Mutex
+	lastPrune time.Time
+	loaded    bool // 游标是否已从已发布位置初始化
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// outboxCursor sink 及其已投递的最大 seq
+// 游标只保存在内存中，重启后从已发布位置继续，期间其他 sink 已收到的记录会再投递一次
+type outboxCursor struct {
+	sink OutboxSink
+	seq  int64
+}
+
+// NewOutboxRelay 创建 outbox 中继
+func NewOutboxRelay(repo *repository.TaskRepository, interval, retention time.Duration) *OutboxRelay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	return &OutboxRelay{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+		batchSize: 100,
+	}
+}
+
+// AddSink 添加发布目标，需在 Start 之前调用
+func (r *OutboxRelay) AddSink(sink OutboxSink) {
+	r.sinks = append(r.sinks, &outboxCursor{sink: sink})
+}
+
+// Start 启动中继
+func (r *OutboxRelay) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	r.running = true
+
+	go r.loop(ctx)
+
+	logger.Infof("Outbox relay started (sinks=%d, interval=%s)", len(r.sinks), r.interval)
+}
+
+// Stop 停止中继并等待当前批次完成
+func (r *OutboxRelay) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.cancel()
+	r.running = false
+	done := r.done
+	r.mu.Unlock()
+
+	<-done
+	logger.Infof("Outbox relay stopped")
+}
+
+// loop 中继循环：收到写入通知或轮询到期时发布
+func (r *OutboxRelay) loop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RunOnce(); err != nil && ctx.Err() == nil {
+			logger.Warnf("Outbox relay publish failed, will retry: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.repo.OutboxNotify():
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 将未发布的记录依次投递到各个 sink，返回本轮新标记为已发布的记录数
+// sink 失败时只停止该 sink，失败的记录下次重试；所有 sink 都已投递的记录标记为已发布
+func (r *OutboxRelay) RunOnce() (int, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	published, err := r.repo.LastPublishedOutboxSeq()
+	if err != nil {
+		return 0, err
+	}
+	if !r.loaded {
+		for _, c := range r.sinks {
+			c.seq = published
+		}
+		r.loaded = true
+	}
+
+	var errs []error
+	upto := int64(-1)
+	for _, c := range r.sinks {
+		if err := r.deliver(c); err != nil {
+			errs = append(errs, err)
+		}
+		if upto < 0 || c.seq < upto {
+			upto = c.seq
+		}
+	}
+
+	n := 0
+	if upto > published {
+		if n, err = r.repo.MarkOutboxPublished(upto); err != nil {
+			return 0, err
+		}
+	}
+
+	r.prune()
+	return n, errors.Join(errs...)
+}
+
+// deliver 从 sink 的游标开始投递，直到没有新记录或 sink 失败
+func (r *OutboxRelay) deliver(c *outboxCursor) error {
+	for {
+		entries, err := r.repo.ListOutboxSince(c.seq, r.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := c.sink.Publish(entry); err != nil {
+				return fmt.Errorf("%T seq %d: %w", c.sink, entry.Seq, err)
+			}
+			c.seq = entry.Seq
+		}
+		if len(entries) < r.batchSize {
+			return nil
+		}
+	}
+}
+
+// prune 定期清理超过保留时长的已发布记录
+func (r *OutboxRelay) prune() {
+	if time.Since(r.lastPrune) < time.Minute {
+		return
+	}
+	r.lastPrune = time.Now()
+
+	n, err := r.repo.PruneOutbox(time.Now().Add(-r.retention))
+	if err != nil {
+		logger.Warnf("Failed to prune outbox: %v", err)
+		return
+	}
+	if n > 0 {
+		logger.Debugf("Pruned %d published outbox entries", n)
+	}
+}
+
+// WebhookSink 以 JSON POST 将变更推送到外部 HTTP 端点，非 2xx 视为失败
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink 创建 Webhook 发布目标
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Publish 发送一条变更
+func (s *WebhookSink) Publish(entry *model.OutboxEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Taskflow-Seq", fmt.Sprintf("%d", entry.Seq))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
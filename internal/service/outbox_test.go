@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// recordingSink 记录收到的变更，failAt 指定的 seq 发布失败一次，down 时全部失败
+type recordingSink struct {
+	entries []*model.OutboxEntry
+	failAt  int64
+	down    bool
+}
+
+func (s *recordingSink) Publish(entry *model.OutboxEntry) error {
+	if s.down || entry.Seq == s.failAt {
+		s.failAt = 0
+		return errors.New("sink unavailable")
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestOutboxRelay_OrderAndRetry(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		task := model.NewTask(fmt.Sprintf("relay-%d", i), "", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+		task.ID = fmt.Sprintf("relay-%d", i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	pending, err := repo.ListUnpublishedOutbox(10)
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("expected 3 outbox entries, got %d (%v)", len(pending), err)
+	}
+
+	sink := &recordingSink{failAt: pending[1].Seq}
+	relay := NewOutboxRelay(repo, time.Second, time.Hour)
+	relay.AddSink(sink)
+
+	// 第二条失败：只发布第一条，其余保留
+	n, err := relay.RunOnce()
+	if err == nil {
+		t.Fatal("expected publish error")
+	}
+	if n != 1 {
+		t.Errorf("expected 1 published, got %d", n)
+	}
+
+	n, err = relay.RunOnce()
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 published on retry, got %d", n)
+	}
+
+	if len(sink.entries) != 3 {
+		t.Fatalf("expected 3 delivered entries, got %d", len(sink.entries))
+	}
+	for i, entry := range sink.entries {
+		if entry.TaskID != fmt.Sprintf("relay-%d", i) {
+			t.Errorf("entry %d: expected relay-%d, got %s", i, i, entry.TaskID)
+		}
+	}
+
+	remaining, _ := repo.ListUnpublishedOutbox(10)
+	if len(remaining) != 0 {
+		t.Errorf("expected no unpublished entries, got %d", len(remaining))
+	}
+}
+
+func TestOutboxRelay_IndependentSinks(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	create := func(id string) {
+		t.Helper()
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "report", nil, nil, 0, "test")
+		task.ID = id
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	create("relay-0")
+	create("relay-1")
+
+	local, webhook := &recordingSink{}, &recordingSink{down: true}
+	relay := NewOutboxRelay(repo, time.Second, time.Hour)
+	relay.AddSink(local)
+	relay.AddSink(webhook)
+
+	// Webhook 不可用时本地 sink 照常收到变更，但记录不标记为已发布
+	if n, err := relay.RunOnce(); err == nil || n != 0 {
+		t.Fatalf("expected webhook error and nothing published, got %d (%v)", n, err)
+	}
+	create("relay-2")
+	if _, err := relay.RunOnce(); err == nil {
+		t.Fatal("expected webhook error")
+	}
+	if len(local.entries) != 3 {
+		t.Fatalf("expected local sink to receive 3 entries once, got %d", len(local.entries))
+	}
+	if pending, _ := repo.ListUnpublishedOutbox(10); len(pending) != 3 {
+		t.Fatalf("expected 3 unpublished entries, got %d", len(pending))
+	}
+
+	// Webhook 恢复后补发全部记录，本地 sink 不重复收到
+	webhook.down = false
+	n, err := relay.RunOnce()
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 published after recovery, got %d (%v)", n, err)
+	}
+	if len(local.entries) != 3 || len(webhook.entries) != 3 {
+		t.Errorf("expected each sink to get 3 entries, got local=%d webhook=%d", len(local.entries), len(webhook.entries))
+	}
+	for i, entry := range webhook.entries {
+		if entry.TaskID != fmt.Sprintf("relay-%d", i) {
+			t.Errorf("webhook entry %d: expected relay-%d, got %s", i, i, entry.TaskID)
+		}
+	}
+}
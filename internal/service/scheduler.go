@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -20,6 +21,7 @@ type Scheduler struct {
 	workerPool      *WorkerPool
 	pollingInterval time.Duration
 	maxPending      int
+	secrets         *SecretCipher // 未设置时带机密参数的任务无法执行
 
 	mu      sync.RWMutex
 	running bool
@@ -291,8 +293,17 @@ func (s *Scheduler) executeTask(taskID string) {
 		return
 	}
 
+	// 机密参数只在交给执行器时解密，明文不写回任务也不记录日志
+	params, err := s.executionParams(task)
+	if err != nil {
+		logger.Errorf("Failed to prepare params for task %s: %v", taskID, err)
+		s.handleTaskFailure(taskID, "failed to decrypt secret params")
+		metrics.RecordTaskError(task.TaskType, "secret_error")
+		return
+	}
+
 	// 执行业务逻辑（这里应该是可扩展的 handler）
-	result, err := s.executeTaskHandler(task)
+	result, err := s.executeTaskHandler(task, params)
 	duration := time.Since(startTime).Seconds()
 
 	if err != nil {
@@ -308,8 +319,31 @@ func (s *Scheduler) executeTask(taskID string) {
 	metrics.RecordTaskDuration(task.TaskType, "succeeded", duration)
 }
 
-// executeTaskHandler 实际执行任务逻辑
-func (s *Scheduler) executeTaskHandler(task *model.Task) (map[string]string, error) {
+// executionParams 执行器看到的输入参数：普通参数加上解密后的机密参数
+func (s *Scheduler) executionParams(task *model.Task) (map[string]string, error) {
+	if len(task.SecretParams) == 0 {
+		return task.InputParams, nil
+	}
+	if s.secrets == nil {
+		return nil, errors.New("secret params present but no secrets key file is configured")
+	}
+	secrets, err := s.secrets.Open(task.ID, task.SecretParams)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string, len(task.InputParams)+len(secrets))
+	for k, v := range task.InputParams {
+		params[k] = v
+	}
+	for k, v := range secrets {
+		params[k] = v
+	}
+	return params, nil
+}
+
+// executeTaskHandler 实际执行任务逻辑，params 为 executionParams 准备的输入参数
+func (s *Scheduler) executeTaskHandler(task *model.Task, params map[string]string) (map[string]string, error) {
 	// TODO: 实现具体的任务执行逻辑
 	// 这里可以扩展为根据 task.TaskType 调用不同的处理器
 
@@ -397,6 +431,11 @@ func (s *Scheduler) SetWorkerCount(count int) {
 	}
 }
 
+// SetSecretCipher 设置解密机密参数使用的密钥环，须在 Start 之前调用
+func (s *Scheduler) SetSecretCipher(secrets *SecretCipher) {
+	s.secrets = secrets
+}
+
 // SetPollingInterval 设置轮询间隔
 func (s *Scheduler) SetPollingInterval(interval time.Duration) {
 	s.mu.Lock()
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+)
+
+// sealedPrefix 密文格式版本：v1:<key_id>:<base64(nonce || ciphertext)>
+const sealedPrefix = "v1"
+
+var (
+	// ErrSecretKeyNotFound 密文使用的密钥不在密钥文件中
+	ErrSecretKeyNotFound = errors.New("secret key not found")
+	// ErrSecretCorrupted 密文格式错误或校验失败（被篡改、或被挪到其他任务/参数名下）
+	ErrSecretCorrupted = errors.New("secret ciphertext is corrupted")
+)
+
+// SecretCipher 使用 AES-GCM 加密任务的机密参数
+// 密钥环中可以有多个密钥：新数据总是用主密钥加密，旧密钥只用于解密，轮换后由 Rewrap 重新加密
+type SecretCipher struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadSecretCipher 从密钥文件加载密钥环，primary 为空时使用文件中的第一个密钥
+func LoadSecretCipher(path, primary string) (*SecretCipher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secrets key file: %w", err)
+	}
+	defer f.Close()
+	return ParseSecretCipher(f, primary)
+}
+
+// ParseSecretCipher 解析密钥文件，每行 key_id:base64(密钥)，密钥长度 16、24 或 32 字节
+func ParseSecretCipher(r io.Reader, primary string) (*SecretCipher, error) {
+	c := &SecretCipher{keys: make(map[string]cipher.AEAD)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(text, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("secrets key file line %d: expected key_id:base64_key", line)
+		}
+		if _, exists := c.keys[id]; exists {
+			return nil, fmt.Errorf("secrets key file line %d: duplicate key id %q", line, id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("secrets key file line %d: %w", line, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("secrets key file line %d: %w", line, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("secrets key file line %d: %w", line, err)
+		}
+		c.keys[id] = aead
+		if c.primary == "" && primary == "" {
+			c.primary = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if primary != "" {
+		c.primary = primary
+	}
+	if len(c.keys) == 0 {
+		return nil, errors.New("secrets key file contains no keys")
+	}
+	if _, ok := c.keys[c.primary]; !ok {
+		return nil, fmt.Errorf("primary secret key %q not found in key file", c.primary)
+	}
+	return c, nil
+}
+
+// PrimaryKeyID 当前用于加密的密钥 ID
+func (c *SecretCipher) PrimaryKeyID() string {
+	return c.primary
+}
+
+// Seal 用主密钥逐个加密任务的机密参数
+// 附加数据绑定任务 ID 与参数名，密文被挪到其他任务或参数名下时无法解密
+func (c *SecretCipher) Seal(taskID string, params map[string]string) (map[string]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	aead := c.keys[c.primary]
+	sealed := make(map[string]string, len(params))
+	for name, value := range params {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		data := aead.Seal(nonce, nonce, []byte(value), secretAAD(taskID, name))
+		sealed[name] = sealedPrefix + ":" + c.primary + ":" + base64.StdEncoding.EncodeToString(data)
+	}
+	return sealed, nil
+}
+
+// Open 解密任务的机密参数，只应在把任务交给执行器时调用
+func (c *SecretCipher) Open(taskID string, sealed map[string]string) (map[string]string, error) {
+	if len(sealed) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]string, len(sealed))
+	for name, value := range sealed {
+		plaintext, err := c.open(taskID, name, value)
+		if err != nil {
+			return nil, fmt.Errorf("secret param %q: %w", name, err)
+		}
+		params[name] = plaintext
+	}
+	return params, nil
+}
+
+// open 解密单个参数
+func (c *SecretCipher) open(taskID, name, value string) (string, error) {
+	version, rest, ok := strings.Cut(value, ":")
+	if !ok || version != sealedPrefix {
+		return "", ErrSecretCorrupted
+	}
+	keyID, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", ErrSecretCorrupted
+	}
+	aead, ok := c.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretKeyNotFound, keyID)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", ErrSecretCorrupted
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], secretAAD(taskID, name))
+	if err != nil {
+		return "", ErrSecretCorrupted
+	}
+	return string(plaintext), nil
+}
+
+// needsRewrap 是否有参数不是用主密钥加密的
+func (c *SecretCipher) needsRewrap(sealed map[string]string) bool {
+	for _, value := range sealed {
+		if !strings.HasPrefix(value, sealedPrefix+":"+c.primary+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Rewrap 将仍由旧密钥加密的机密参数用主密钥重新加密，返回重新加密的任务数
+// 无法解密的任务（密钥已移除或密文损坏）记录错误后跳过，旧密钥应在全部重新加密后再从密钥文件中删除
+func (c *SecretCipher) Rewrap(ctx context.Context, repo *repository.TaskRepository) (int, error) {
+	all, err := repo.ListSealedSecrets()
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped := 0
+	for id, sealed := range all {
+		if err := ctx.Err(); err != nil {
+			return rewrapped, err
+		}
+		if !c.needsRewrap(sealed) {
+			continue
+		}
+		params, err := c.Open(id, sealed)
+		if err != nil {
+			logger.Errorf("Failed to rewrap secret params of task %s: %v", id, err)
+			continue
+		}
+		resealed, err := c.Seal(id, params)
+		if err != nil {
+			return rewrapped, err
+		}
+		replaced, err := repo.ReplaceSealedSecrets(id, sealed, resealed)
+		if err != nil {
+			return rewrapped, err
+		}
+		if replaced {
+			rewrapped++
+		}
+	}
+	return rewrapped, nil
+}
+
+// secretAAD 附加认证数据：任务 ID 与参数名
+func secretAAD(taskID, name string) []byte {
+	return []byte(taskID + "\x00" + name)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"taskflow/internal/model"
+)
+
+// testKeyLine 生成密钥文件中的一行
+func testKeyLine(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestParseSecretCipher(t *testing.T) {
+	c, err := ParseSecretCipher(strings.NewReader("# keys\n"+testKeyLine("k1", 'a')+"\n"+testKeyLine("k2", 'b')+"\n"), "")
+	if err != nil || c.PrimaryKeyID() != "k1" {
+		t.Fatalf("expected first key as primary, got %v: %v", c, err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		primary string
+	}{
+		{"empty", "# nothing\n", ""},
+		{"missing separator", "k1\n", ""},
+		{"bad base64", "k1:***\n", ""},
+		{"bad key length", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")) + "\n", ""},
+		{"duplicate id", testKeyLine("k1", 'a') + "\n" + testKeyLine("k1", 'b') + "\n", ""},
+		{"unknown primary", testKeyLine("k1", 'a') + "\n", "k9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSecretCipher(strings.NewReader(tt.file), tt.primary); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSecretCipher_SealAndOpen(t *testing.T) {
+	c, err := ParseSecretCipher(strings.NewReader(testKeyLine("k1", 'a')), "")
+	if err != nil {
+		t.Fatalf("failed to parse key file: %v", err)
+	}
+
+	sealed, err := c.Seal("t-1", map[string]string{"password": "hunter2"})
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if strings.Contains(sealed["password"], "hunter2") || !strings.HasPrefix(sealed["password"], "v1:k1:") {
+		t.Fatalf("unexpected ciphertext %q", sealed["password"])
+	}
+
+	params, err := c.Open("t-1", sealed)
+	if err != nil || params["password"] != "hunter2" {
+		t.Fatalf("expected plaintext back, got %v: %v", params, err)
+	}
+
+	// 密文与任务 ID、参数名绑定
+	if _, err := c.Open("t-2", sealed); !errors.Is(err, ErrSecretCorrupted) {
+		t.Errorf("expected ErrSecretCorrupted for another task, got %v", err)
+	}
+	if _, err := c.Open("t-1", map[string]string{"token": sealed["password"]}); !errors.Is(err, ErrSecretCorrupted) {
+		t.Errorf("expected ErrSecretCorrupted for another param, got %v", err)
+	}
+	if _, err := c.Open("t-1", map[string]string{"password": "v1:k9:AAAA"}); !errors.Is(err, ErrSecretKeyNotFound) {
+		t.Errorf("expected ErrSecretKeyNotFound, got %v", err)
+	}
+}
+
+func TestSecretCipher_Rewrap(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	old, _ := ParseSecretCipher(strings.NewReader(testKeyLine("k1", 'a')), "")
+	task := model.NewTask("deploy", "", model.TaskPriorityNormal, "deploy", map[string]string{"env": "prod"}, nil, 0, "alice")
+	task.ID = "t-1"
+	task.SecretParams, _ = old.Seal(task.ID, map[string]string{"password": "hunter2"})
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 新主密钥 k2，旧密钥 k1 仍可解密
+	rotated, _ := ParseSecretCipher(strings.NewReader(testKeyLine("k1", 'a')+"\n"+testKeyLine("k2", 'b')), "k2")
+	n, err := rotated.Rewrap(context.Background(), repo)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 task re-encrypted, got %d: %v", n, err)
+	}
+	if n, _ := rotated.Rewrap(context.Background(), repo); n != 0 {
+		t.Errorf("expected nothing left to re-encrypt, got %d", n)
+	}
+
+	// 移除旧密钥后仍能解密
+	current, _ := ParseSecretCipher(strings.NewReader(testKeyLine("k2", 'b')), "")
+	stored, err := repo.GetByID("t-1")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	params, err := current.Open(stored.ID, stored.SecretParams)
+	if err != nil || params["password"] != "hunter2" {
+		t.Fatalf("expected plaintext with the new key, got %v: %v", params, err)
+	}
+
+	// 执行器看到合并后的明文参数
+	s := NewScheduler(repo)
+	defer s.workerPool.Stop()
+	if _, err := s.executionParams(stored); err == nil {
+		t.Error("expected error without a secret cipher")
+	}
+	s.SetSecretCipher(current)
+	params, err = s.executionParams(stored)
+	if err != nil || params["env"] != "prod" || params["password"] != "hunter2" {
+		t.Errorf("unexpected execution params %v: %v", params, err)
+	}
+	if _, ok := stored.InputParams["password"]; ok {
+		t.Error("decrypted secret must not be written back to the task")
+	}
+}
+
+func TestSecretCipher_ArchiveKeepsCiphertext(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	c, _ := ParseSecretCipher(strings.NewReader(testKeyLine("k1", 'a')), "")
+	newTask := func(id string) *model.Task {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "deploy", nil, nil, 0, "alice")
+		task.ID = id
+		task.Status = model.TaskStatusSucceeded
+		task.SecretParams, _ = c.Seal(id, map[string]string{"password": "hunter2"})
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task
+	}
+
+	// 归档保存密文，保留旧密钥即可解密
+	opened := func(task *model.Task) {
+		t.Helper()
+		if task == nil {
+			t.Fatal("expected archived task")
+		}
+		if params, err := c.Open(task.ID, task.SecretParams); err != nil || params["password"] != "hunter2" {
+			t.Errorf("expected archived secret to decrypt, got %v: %v", params, err)
+		}
+	}
+
+	table := newTask("archive-table")
+	if _, err := NewTableArchiver(repo).ArchiveAndDelete([]*model.Task{table}); err != nil {
+		t.Fatalf("failed to archive to table: %v", err)
+	}
+	archived, err := repo.GetArchivedTask(table.ID)
+	if err != nil {
+		t.Fatalf("failed to read archived task: %v", err)
+	}
+	opened(archived)
+
+	dir := t.TempDir()
+	file := newTask("archive-file")
+	if _, err := NewFileArchiver(repo, dir).ArchiveAndDelete([]*model.Task{file}); err != nil {
+		t.Fatalf("failed to archive to file: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "tasks-*.jsonl.gz"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 archive file, got %v", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	var line model.Task
+	if err := json.NewDecoder(zr).Decode(&line); err != nil {
+		t.Fatalf("invalid archive line: %v", err)
+	}
+	opened(&line)
+
+	// 变更推送的快照只带参数名
+	entries, err := repo.ListOutboxSince(0, 10)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("expected outbox entries: %v", err)
+	}
+	for _, entry := range entries {
+		if v := entry.Task.SecretParams["password"]; v != model.RedactedValue {
+			t.Errorf("expected redacted outbox snapshot, got %q", v)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"taskflow/internal/model"
 	"time"
 )
 
+// ErrInvalidTransition 状态机不允许的状态转换
+var ErrInvalidTransition = errors.New("invalid state transition")
+
 // StateMachine 任务状态机
 type StateMachine struct {
 	// transitions 定义有效状态转换
@@ -75,7 +79,7 @@ func (sm *StateMachine) Transition(task *model.Task, toStatus model.TaskStatus,
 
 	// 验证转换
 	if !sm.CanTransition(fromStatus, toStatus) {
-		return fmt.Errorf("invalid state transition from %s to %s", fromStatus, toStatus)
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, fromStatus, toStatus)
 	}
 
 	// 执行转换前的钩子
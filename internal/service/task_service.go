@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,15 @@ import (
 	"taskflow/internal/repository"
 )
 
+var (
+	// ErrTaskNotFound 任务不存在
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskTerminated 任务已处于终态
+	ErrTaskTerminated = errors.New("task already terminated")
+	// ErrTaskNotRetryable 任务不是失败状态或重试次数已用完
+	ErrTaskNotRetryable = errors.New("task cannot be retried")
+)
+
 // TaskService 任务服务
 type TaskService struct {
 	repo      *repository.TaskRepository
@@ -68,7 +78,7 @@ func (s *TaskService) UpdateTask(ctx context.Context, id string, updates map[str
 		return nil, err
 	}
 	if task == nil {
-		return nil, fmt.Errorf("task not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
 	}
 
 	// 应用更新
@@ -108,11 +118,11 @@ func (s *TaskService) CancelTask(ctx context.Context, id, operator string) error
 		return err
 	}
 	if task == nil {
-		return fmt.Errorf("task not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
 	}
 
 	if task.IsTerminal() {
-		return fmt.Errorf("cannot cancel terminal task")
+		return fmt.Errorf("cannot cancel task: %w", ErrTaskTerminated)
 	}
 
 	fromStatus := task.Status
@@ -131,11 +141,11 @@ func (s *TaskService) RetryTask(ctx context.Context, id, operator string) error
 		return err
 	}
 	if task == nil {
-		return fmt.Errorf("task not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
 	}
 
 	if !task.CanRetry() {
-		return fmt.Errorf("task cannot be retried")
+		return ErrTaskNotRetryable
 	}
 
 	// 重置为 Pending 状态
@@ -149,6 +159,33 @@ func (s *TaskService) RetryTask(ctx context.Context, id, operator string) error
 	return s.repo.UpdateStatusWithEvent(id, fromStatus, model.TaskStatusPending, operator, retryMsg)
 }
 
+// SetPriority 调整未完成任务的优先级
+func (s *TaskService) SetPriority(ctx context.Context, id string, priority model.TaskPriority) error {
+	task, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+
+	if task.IsTerminal() {
+		return fmt.Errorf("cannot change priority: %w", ErrTaskTerminated)
+	}
+	if task.Priority == priority {
+		return nil
+	}
+
+	task.Priority = priority
+	task.UpdatedAt = time.Now()
+	return s.repo.Update(task)
+}
+
+// SetSecretCipher 设置调度器解密机密参数使用的密钥环
+func (s *TaskService) SetSecretCipher(secrets *SecretCipher) {
+	s.scheduler.SetSecretCipher(secrets)
+}
+
 // StartScheduler 启动调度器
 func (s *TaskService) StartScheduler(ctx context.Context) {
 	s.scheduler.Start(ctx)
@@ -12,6 +12,11 @@ func main() {
 	// 加载配置
 	cfg := config.LoadConfig()
 
+	// 子命令（如 restore）无需启动服务
+	if runCommand(cfg, os.Args[1:]) {
+		return
+	}
+
 	// 初始化日志
 	if err := logger.Init(cfg.Server.EnableDebug); err != nil {
 		os.Stderr.WriteString("Failed to initialize logger: " + err.Error())
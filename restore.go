@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"taskflow/internal/config"
+	"taskflow/internal/repository"
+)
+
+// runRestore 执行 restore 子命令：校验快照后替换数据库文件
+// 用法：taskflow restore [-db path] [-check] <snapshot>
+func runRestore(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dbPath := fs.String("db", cfg.GetDBPath(), "database file to replace (stop the server first)")
+	checkOnly := fs.Bool("check", false, "only validate the snapshot")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: taskflow restore [-db path] [-check] <snapshot>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("snapshot path is required")
+	}
+	snapshot := fs.Arg(0)
+
+	info, err := repository.ValidateSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot %s is valid: %d tasks, %d events, %d bytes\n", info.Path, info.Tasks, info.Events, info.Size)
+	if *checkOnly {
+		return nil
+	}
+
+	previous, err := repository.RestoreSnapshot(snapshot, config.ExpandHome(*dbPath))
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		fmt.Printf("Previous database moved to %s\n", previous)
+	}
+	fmt.Printf("Database %s restored from %s\n", *dbPath, snapshot)
+	return nil
+}
+
+// runCommand 处理子命令，返回 false 表示没有匹配的子命令
+func runCommand(cfg *config.Config, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "restore":
+		if err := runRestore(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "restore failed: %v\n", err)
+			os.Exit(1)
+		}
+		return true
+	}
+	return false
+}